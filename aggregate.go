@@ -0,0 +1,151 @@
+package tripper
+
+import (
+	"strings"
+	"time"
+)
+
+// AggregateData combines the Data of a group of related circuits, e.g. all
+// shards of one service, into a single CircuitData for a dashboard. The group
+// is open if any of the circuits is open; see AggregateDataWithOperator.
+func AggregateData(circuits ...Circuit) CircuitData {
+	return AggregateDataWithOperator(OperatorOr, circuits...)
+}
+
+// AggregateDataWithOperator combines the Data of the circuits like
+// AggregateData. With OperatorOr the group is open if any circuit is open,
+// with OperatorAnd only if all of them are open.
+//
+// The counts are summed and FailureRate and ShedRatio are computed from the
+// sums. The streaks, the EWMA failure rate, the backoff level and the latency
+// percentiles are the highest of the circuits, since they cannot be combined
+// exactly. OpenedAt is the earliest time a circuit of the group opened and
+// LastStateChangedTime the latest state change. The options echoed in Data
+// are left zero. A group that is not open is half-open if any circuit is.
+func AggregateDataWithOperator(operator string, circuits ...Circuit) CircuitData {
+	var aggregate CircuitData
+	openCount, halfOpen := 0, false
+	for _, circuit := range circuits {
+		data := circuit.Data()
+		aggregate.SuccessCount += data.SuccessCount
+		aggregate.FailureCount += data.FailureCount
+		aggregate.SlowCallCount += data.SlowCallCount
+		aggregate.BadCallCount += data.BadCallCount
+		aggregate.TotalCount += data.TotalCount
+		aggregate.TripCount += data.TripCount
+		aggregate.WeightedSuccessCount += data.WeightedSuccessCount
+		aggregate.WeightedFailureCount += data.WeightedFailureCount
+		aggregate.InFlight += data.InFlight
+		aggregate.ConcurrencyRejected += data.ConcurrencyRejected
+		aggregate.RejectedThisEpisode += data.RejectedThisEpisode
+		aggregate.SkippedCount += data.SkippedCount
+		aggregate.AllowedCount += data.AllowedCount
+		aggregate.RejectedCount += data.RejectedCount
+		if data.EWMAFailureRate > aggregate.EWMAFailureRate {
+			aggregate.EWMAFailureRate = data.EWMAFailureRate
+		}
+		if data.ConsecutiveCounter > aggregate.ConsecutiveCounter {
+			aggregate.ConsecutiveCounter = data.ConsecutiveCounter
+		}
+		if data.BackoffLevel > aggregate.BackoffLevel {
+			aggregate.BackoffLevel = data.BackoffLevel
+		}
+		if data.IsCircuitOpen {
+			openCount++
+			if aggregate.CircuitOpenedSince == 0 || data.CircuitOpenedSince < aggregate.CircuitOpenedSince {
+				aggregate.CircuitOpenedSince = data.CircuitOpenedSince
+			}
+			if aggregate.OpenedAt.IsZero() || data.OpenedAt.Before(aggregate.OpenedAt) {
+				aggregate.OpenedAt = data.OpenedAt
+			}
+		}
+		if data.State == StateHalfOpen {
+			halfOpen = true
+		}
+		if data.LastStateChangedAt > aggregate.LastStateChangedAt {
+			aggregate.LastStateChangedAt = data.LastStateChangedAt
+		}
+		if data.LastStateChangedTime.After(aggregate.LastStateChangedTime) {
+			aggregate.LastStateChangedTime = data.LastStateChangedTime
+		}
+		for class, count := range data.FailuresByClass {
+			if aggregate.FailuresByClass == nil {
+				aggregate.FailuresByClass = make(map[string]int64)
+			}
+			aggregate.FailuresByClass[class] += count
+		}
+		aggregate.Latency = mergeLatency(aggregate.Latency, data.Latency)
+	}
+	if aggregate.TotalCount > 0 {
+		aggregate.FailureRate = float64(aggregate.FailureCount) / float64(aggregate.TotalCount)
+	}
+	aggregate.ShedRatio = shedRatio(aggregate.AllowedCount, aggregate.RejectedCount)
+	if operator == OperatorAnd {
+		aggregate.IsCircuitOpen = len(circuits) > 0 && openCount == len(circuits)
+	} else {
+		aggregate.IsCircuitOpen = openCount > 0
+	}
+	switch {
+	case aggregate.IsCircuitOpen:
+		aggregate.State = StateOpen
+	case halfOpen:
+		aggregate.State = StateHalfOpen
+	default:
+		aggregate.State = StateClosed
+		aggregate.CircuitOpenedSince = 0
+		aggregate.OpenedAt = time.Time{}
+	}
+	return aggregate
+}
+
+// mergeLatency combines two latency summaries. The percentiles are the higher
+// of the two, an upper bound of the percentiles of all the latencies.
+func mergeLatency(a, b LatencySummary) LatencySummary {
+	if a.Count == 0 {
+		return b
+	}
+	if b.Count == 0 {
+		return a
+	}
+	merged := LatencySummary{
+		Count: a.Count + b.Count,
+		Min:   a.Min,
+		Max:   a.Max,
+		P50:   a.P50,
+		P95:   a.P95,
+		P99:   a.P99,
+	}
+	if b.Min < merged.Min {
+		merged.Min = b.Min
+	}
+	if b.Max > merged.Max {
+		merged.Max = b.Max
+	}
+	if b.P50 > merged.P50 {
+		merged.P50 = b.P50
+	}
+	if b.P95 > merged.P95 {
+		merged.P95 = b.P95
+	}
+	if b.P99 > merged.P99 {
+		merged.P99 = b.P99
+	}
+	return merged
+}
+
+// AggregateData combines the Data of the circuits whose name starts with
+// prefix, e.g. "payments-" for every shard of the payments service, like the
+// package-level AggregateData. The Data of the circuits is read without
+// holding the lock of the Tripper.
+func (t *TripperImplementation) AggregateData(prefix string) CircuitData {
+	t.Mutex.RLock()
+	var circuits []Circuit
+	for name, circuit := range t.Monitors {
+		if strings.HasPrefix(name, prefix) {
+			circuits = append(circuits, circuit)
+		}
+	}
+	t.Mutex.RUnlock()
+
+	return AggregateData(circuits...)
+}
@@ -0,0 +1,71 @@
+package tripper
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAggregateData(t *testing.T) {
+	clock := NewFakeClock(time.Unix(1700000000, 0))
+	registry := Configure(TripperOptions{})
+	defer registry.StopAll()
+	var shards []Circuit
+	for _, name := range []string{"payments-1", "payments-2", "payments-3", "search"} {
+		circuit, err := registry.AddMonitor(CircuitOptions{
+			Name:              name,
+			Threshold:         2,
+			ThresholdType:     ThresholdConsecutive,
+			IntervalInSeconds: 60,
+			Clock:             clock,
+		})
+		assert.NoError(t, err)
+		shards = append(shards, circuit)
+	}
+	payments, search := shards[:3], shards[3]
+	payments[0].UpdateStatus(true)
+	payments[0].UpdateStatus(true)
+	payments[1].UpdateStatus(false)
+	payments[1].UpdateStatus(true)
+	payments[2].UpdateStatus(false)
+	clock.Advance(5 * time.Second)
+	payments[2].UpdateStatus(false)
+	search.UpdateStatus(false)
+	search.UpdateStatus(false)
+
+	// Test case 1: Aggregate a mix of open and closed circuits
+	// Expected output: The counts are summed and the group is open as one circuit is
+	data := AggregateData(payments...)
+	assert.Equal(t, int64(3), data.SuccessCount)
+	assert.Equal(t, int64(3), data.FailureCount)
+	assert.Equal(t, int64(6), data.TotalCount)
+	assert.Equal(t, 0.5, data.FailureRate)
+	assert.Equal(t, int64(2), data.ConsecutiveCounter)
+	assert.Equal(t, int64(1), data.TripCount)
+	assert.True(t, data.IsCircuitOpen)
+	assert.Equal(t, StateOpen, data.State)
+	assert.Equal(t, payments[2].Data().CircuitOpenedSince, data.CircuitOpenedSince)
+
+	// Test case 2: Aggregate the same circuits with OperatorAnd
+	// Expected output: The group is closed as not all circuits are open
+	data = AggregateDataWithOperator(OperatorAnd, payments...)
+	assert.Equal(t, int64(6), data.TotalCount)
+	assert.False(t, data.IsCircuitOpen)
+	assert.Equal(t, StateClosed, data.State)
+	assert.Zero(t, data.CircuitOpenedSince)
+	data = AggregateDataWithOperator(OperatorAnd, payments[2], search)
+	assert.True(t, data.IsCircuitOpen)
+	assert.Equal(t, int64(2), data.TripCount)
+
+	// Test case 3: Aggregate the circuits of a Tripper by name prefix
+	// Expected output: Only the circuits with the prefix are included
+	assert.Equal(t, AggregateData(payments...), registry.AggregateData("payments-"))
+	assert.Equal(t, int64(8), registry.AggregateData("").TotalCount)
+
+	// Test case 4: Aggregate no circuits
+	// Expected output: Empty closed data, with either operator
+	assert.Equal(t, CircuitData{State: StateClosed}, AggregateData())
+	assert.Equal(t, CircuitData{State: StateClosed}, AggregateDataWithOperator(OperatorAnd))
+	assert.Equal(t, CircuitData{State: StateClosed}, registry.AggregateData("unknown"))
+}
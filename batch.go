@@ -0,0 +1,152 @@
+package tripper
+
+// UpdateStatusBatch records successes and failures at once, e.g. when
+// flushing results collected by an aggregation loop. The counts are applied
+// under a single lock acquisition and the state is evaluated once, which is
+// cheaper than calling UpdateStatus in a loop. Since the order of the events
+// in a batch is unknown, its failures are treated as the most recent ones:
+// any failure ends the streak of consecutive successes and the failures are
+// counted as consecutive, after the successes if there are any. Negative
+// counts are treated as 0.
+func (m *CircuitImplementation) UpdateStatusBatch(successes, failures int64) {
+	if m == nil {
+		return
+	}
+	if successes < 0 {
+		successes = 0
+	}
+	if failures < 0 {
+		failures = 0
+	}
+	if successes == 0 && failures == 0 {
+		return
+	}
+	m.startTicker()
+
+	m.Mutex.Lock()
+	if m.shutdown {
+		m.Mutex.Unlock()
+		return
+	}
+	if m.Options.InvertPolarity {
+		successes, failures = failures, successes
+	}
+	events := m.recordBatch(successes, failures)
+	m.Mutex.Unlock()
+
+	m.dispatch(events...)
+}
+
+// RecordAttempts records the attempts of an operation retried internally, e.g.
+// 2 failed attempts followed by a successful one, as a single outcome. All the
+// attempts are counted in the counts and the failure rate, but the streaks of
+// consecutive failures and successes only see finalSuccess, so a retried
+// operation that eventually succeeded does not trip a ThresholdConsecutive
+// circuit. The final attempt is part of successes or failures and is added to
+// them if missing. A half-open circuit takes finalSuccess as the outcome of
+// the probe. Negative counts are treated as 0.
+func (m *CircuitImplementation) RecordAttempts(successes, failures int64, finalSuccess bool) {
+	if m == nil {
+		return
+	}
+	if successes < 0 {
+		successes = 0
+	}
+	if failures < 0 {
+		failures = 0
+	}
+	if finalSuccess && successes == 0 {
+		successes = 1
+	}
+	if !finalSuccess && failures == 0 {
+		failures = 1
+	}
+	m.startTicker()
+
+	m.Mutex.Lock()
+	if m.shutdown {
+		m.Mutex.Unlock()
+		return
+	}
+	if m.Options.InvertPolarity {
+		successes, failures = failures, successes
+		finalSuccess = !finalSuccess
+	}
+	events := m.recordAttempts(successes, failures, finalSuccess)
+	m.Mutex.Unlock()
+
+	m.dispatch(events...)
+}
+
+// recordAttempts records the attempts of a retried operation and evaluates
+// the state once, with the streaks following its final outcome. It returns
+// the callback events for any state change. The caller must hold m.Mutex.
+func (m *CircuitImplementation) recordAttempts(successes, failures int64, finalSuccess bool) []CallbackEvent {
+	events := m.addCounts(successes, failures)
+	if finalSuccess {
+		m.ConsecutiveSuccessCounter++
+		if m.ConsecutiveSuccessCounter > m.Options.AllowedInterleavedSuccesses {
+			m.ConsecutiveCounter = 0
+		}
+	} else {
+		m.ConsecutiveCounter++
+		m.ConsecutiveSuccessCounter = 0
+	}
+	return append(events, m.evaluateBatch(finalSuccess)...)
+}
+
+// addCounts adds successes and failures recorded at once to the counts, after
+// expiring the data and the open duration. It returns the callback events for
+// any state change. The caller must hold m.Mutex.
+func (m *CircuitImplementation) addCounts(successes, failures int64) []CallbackEvent {
+	now := m.now()
+	events := m.expireStaleData(now)
+	m.LastCapturedAt = now
+	events = append(events, m.expireMaxOpen(now)...)
+	events = append(events, m.expireOpenDuration(now)...)
+	m.SuccessCount += successes
+	m.FailureCount += failures
+	addWeight(&m.weightedSuccesses, countUnits(successes))
+	addWeight(&m.weightedFailures, countUnits(failures))
+	m.recordEWMA(float64(successes), float64(failures), timeOf(m.LastCapturedAt))
+	return events
+}
+
+// evaluateBatch evaluates the state once after events were recorded at once.
+// A half-open circuit takes success as the outcome of the probe, and a success
+// while closed resets the backoff level. It returns the callback events for
+// any state change. The caller must hold m.Mutex.
+func (m *CircuitImplementation) evaluateBatch(success bool) []CallbackEvent {
+	if m.HalfOpen {
+		return m.recordProbe(success)
+	}
+	if success && !m.CircuitOpen {
+		// the dependency recovered after the circuit closed
+		m.BackoffLevel = 0
+	}
+	return m.evaluateStatus()
+}
+
+// recordBatch records a batch of events and evaluates the state once. It
+// returns the callback events for any state change. The caller must hold
+// m.Mutex.
+func (m *CircuitImplementation) recordBatch(successes, failures int64) []CallbackEvent {
+	events := m.addCounts(successes, failures)
+	if failures > 0 {
+		if successes > 0 && m.ConsecutiveSuccessCounter+successes > m.Options.AllowedInterleavedSuccesses {
+			m.ConsecutiveCounter = 0
+		}
+		m.ConsecutiveCounter += failures
+		m.ConsecutiveSuccessCounter = 0
+	} else {
+		m.ConsecutiveSuccessCounter += successes
+		if m.ConsecutiveSuccessCounter > m.Options.AllowedInterleavedSuccesses {
+			m.ConsecutiveCounter = 0
+		}
+	}
+	if m.HalfOpen {
+		// a batch is a successful probe only if it has no failures
+		return append(events, m.recordProbe(failures == 0)...)
+	}
+	return append(events, m.evaluateBatch(successes > 0)...)
+}
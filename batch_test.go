@@ -0,0 +1,174 @@
+package tripper
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newBatchCircuit(t *testing.T, thresholdType string, threshold float32) Circuit {
+	m, err := ConfigureCircuit(CircuitOptions{
+		Name:              "TEST_Batch",
+		Threshold:         threshold,
+		ThresholdType:     thresholdType,
+		MinimumCount:      20,
+		IntervalInSeconds: 60,
+		Clock:             NewFakeClock(time.Unix(1700000000, 0)),
+	})
+	assert.NoError(t, err)
+	return m
+}
+
+func TestUpdateStatusBatchCount(t *testing.T) {
+	m := newBatchCircuit(t, ThresholdCount, 10)
+	defer m.Close()
+
+	// Test case 1: Record a batch below MinimumCount
+	// Expected output: The counts are added and the circuit stays closed
+	m.UpdateStatusBatch(5, 9)
+	data := m.Data()
+	assert.Equal(t, int64(5), data.SuccessCount)
+	assert.Equal(t, int64(9), data.FailureCount)
+	assert.Equal(t, float64(9), data.WeightedFailureCount)
+	assert.False(t, m.IsCircuitOpen())
+
+	// Test case 2: Record a batch reaching MinimumCount and the threshold
+	// Expected output: The circuit opens
+	m.UpdateStatusBatch(5, 1)
+	assert.Equal(t, int64(20), m.Data().TotalCount)
+	assert.True(t, m.IsCircuitOpen())
+
+	// Test case 3: Record an empty or negative batch
+	// Expected output: Nothing changes
+	m.UpdateStatusBatch(0, 0)
+	m.UpdateStatusBatch(-5, -5)
+	assert.Equal(t, int64(20), m.Data().TotalCount)
+}
+
+func TestUpdateStatusBatchPercentage(t *testing.T) {
+	m := newBatchCircuit(t, ThresholdPercentage, 50)
+	defer m.Close()
+
+	// Test case 1: Record a batch with a 40% failure rate
+	// Expected output: The circuit stays closed
+	m.UpdateStatusBatch(30, 20)
+	assert.Equal(t, 0.4, m.Data().FailureRate)
+	assert.False(t, m.IsCircuitOpen())
+
+	// Test case 2: Record a batch raising the failure rate to 50%
+	// Expected output: The circuit opens
+	m.UpdateStatusBatch(0, 10)
+	assert.Equal(t, 0.5, m.Data().FailureRate)
+	assert.True(t, m.IsCircuitOpen())
+}
+
+func TestUpdateStatusBatchConsecutive(t *testing.T) {
+	m, err := ConfigureCircuit(CircuitOptions{
+		Name:                  "TEST_BatchConsecutive",
+		Threshold:             3,
+		ThresholdType:         ThresholdConsecutive,
+		CloseConsecutiveCount: 2,
+		IntervalInSeconds:     60,
+		Clock:                 NewFakeClock(time.Unix(1700000000, 0)),
+	})
+	assert.NoError(t, err)
+	defer m.Close()
+
+	// Test case 1: Record a batch with 2 failures after a failure
+	// Expected output: The successes end the streak, so only 2 failures are consecutive
+	m.UpdateStatus(false)
+	m.UpdateStatusBatch(1, 2)
+	assert.Equal(t, int64(2), m.Data().ConsecutiveCounter)
+	assert.False(t, m.IsCircuitOpen())
+
+	// Test case 2: Record a batch of failures only
+	// Expected output: They extend the streak and open the circuit
+	m.UpdateStatusBatch(0, 1)
+	assert.True(t, m.IsCircuitOpen())
+
+	// Test case 3: Record a batch with successes and a failure on the open circuit
+	// Expected output: The failure breaks the streak of successes and the circuit stays open
+	m.UpdateStatusBatch(5, 1)
+	assert.True(t, m.IsCircuitOpen())
+
+	// Test case 4: Record batches of successes only
+	// Expected output: The circuit closes once 2 consecutive successes were recorded
+	m.UpdateStatusBatch(1, 0)
+	assert.True(t, m.IsCircuitOpen())
+	m.UpdateStatusBatch(1, 0)
+	assert.False(t, m.IsCircuitOpen())
+}
+
+func TestRecordAttempts(t *testing.T) {
+	m, err := ConfigureCircuit(CircuitOptions{
+		Name:              "TEST_RecordAttempts",
+		Threshold:         3,
+		ThresholdType:     ThresholdConsecutive,
+		IntervalInSeconds: 60,
+		Clock:             NewFakeClock(time.Unix(1700000000, 0)),
+	})
+	assert.NoError(t, err)
+	defer m.Close()
+
+	// Test case 1: Record operations that succeeded after 2 failed attempts
+	// Expected output: Every attempt is counted but the failures do not add to the streak
+	for i := 0; i < 3; i++ {
+		m.RecordAttempts(1, 2, true)
+	}
+	data := m.Data()
+	assert.Equal(t, int64(3), data.SuccessCount)
+	assert.Equal(t, int64(6), data.FailureCount)
+	assert.Equal(t, int64(0), data.ConsecutiveCounter)
+	assert.False(t, m.IsCircuitOpen())
+
+	// Test case 2: Record operations that failed after 3 attempts
+	// Expected output: Each one adds a single failure to the streak and the third opens the circuit
+	m.RecordAttempts(0, 3, false)
+	m.RecordAttempts(0, 3, false)
+	assert.Equal(t, int64(2), m.Data().ConsecutiveCounter)
+	assert.False(t, m.IsCircuitOpen())
+	m.RecordAttempts(0, 3, false)
+	assert.Equal(t, int64(15), m.Data().FailureCount)
+	assert.True(t, m.IsCircuitOpen())
+
+	// Test case 3: Record an operation whose final attempt is missing from the counts
+	// Expected output: The final attempt is added and the success ends the streak
+	m.RecordAttempts(0, 1, true)
+	data = m.Data()
+	assert.Equal(t, int64(4), data.SuccessCount)
+	assert.Equal(t, int64(16), data.FailureCount)
+	assert.Equal(t, int64(0), data.ConsecutiveCounter)
+	assert.False(t, m.IsCircuitOpen())
+}
+
+func TestRecordAttemptsHalfOpen(t *testing.T) {
+	clock := NewFakeClock(time.Unix(1700000000, 0))
+	m, err := ConfigureCircuit(CircuitOptions{
+		Name:                  "TEST_RecordAttemptsHalfOpen",
+		Threshold:             2,
+		ThresholdType:         ThresholdConsecutive,
+		IntervalInSeconds:     60,
+		OpenDurationInSeconds: 30,
+		HalfOpenMaxProbes:     1,
+		Clock:                 clock,
+	})
+	assert.NoError(t, err)
+	defer m.Close()
+	m.UpdateStatus(false)
+	m.UpdateStatus(false)
+
+	// Test case 1: Record a probe that failed on every retry
+	// Expected output: The probe failed and the circuit opens again
+	clock.Advance(30 * time.Second)
+	assert.True(t, m.AllowRequest())
+	m.RecordAttempts(0, 3, false)
+	assert.Equal(t, StateOpen, m.State())
+
+	// Test case 2: Record a probe that succeeded after a failed attempt
+	// Expected output: The probe succeeded and the circuit closes
+	clock.Advance(30 * time.Second)
+	assert.True(t, m.AllowRequest())
+	m.RecordAttempts(1, 1, true)
+	assert.Equal(t, StateClosed, m.State())
+}
@@ -0,0 +1,440 @@
+package tripper
+
+import (
+	"context"
+	"math/rand"
+	"time"
+)
+
+// Option configures a circuit created by NewCircuit.
+type Option func(o *CircuitOptions)
+
+// NewCircuit creates a circuit with the given name and options. The options
+// are validated the same way ConfigureCircuit validates CircuitOptions.
+//
+//	circuit, err := tripper.NewCircuit("orders",
+//		tripper.WithPercentageThreshold(50),
+//		tripper.WithMinimumCount(20),
+//		tripper.WithInterval(120),
+//	)
+func NewCircuit(name string, opts ...Option) (Circuit, error) {
+	monitorOptions := CircuitOptions{Name: name}
+	for _, opt := range opts {
+		opt(&monitorOptions)
+	}
+	return ConfigureCircuit(monitorOptions)
+}
+
+// Clone creates a new circuit named name with the options of m, changed by
+// opts. The callbacks and the Clock are shared with m, while Rand is replaced
+// by a new random source unless WithRand is given. Thresholds,
+// FailureStatusCodes and ThresholdStrategies are copied, so that changing them
+// on one circuit does not change the other. The counts and state of m are not
+// copied.
+//
+//	search, err := orders.Clone("search", tripper.WithMinimumCount(50))
+func (m *CircuitImplementation) Clone(name string, opts ...Option) (Circuit, error) {
+	if m == nil {
+		return noopCircuit{}.Clone(name, opts...)
+	}
+
+	monitorOptions := m.GetOptions()
+	monitorOptions.Name = name
+	monitorOptions.Rand = nil
+	for _, opt := range opts {
+		opt(&monitorOptions)
+	}
+	return ConfigureCircuit(monitorOptions)
+}
+
+// WithPercentageThreshold opens the circuit when the percentage of failures reaches threshold.
+func WithPercentageThreshold(threshold float32) Option {
+	return WithThreshold(ThresholdPercentage, threshold)
+}
+
+// WithCountThreshold opens the circuit when the number of failures reaches threshold.
+func WithCountThreshold(threshold int64) Option {
+	return WithThreshold(ThresholdCount, float32(threshold))
+}
+
+// WithConsecutiveThreshold opens the circuit after threshold failures in a row.
+func WithConsecutiveThreshold(threshold int64) Option {
+	return WithThreshold(ThresholdConsecutive, float32(threshold))
+}
+
+// WithEWMAThreshold opens the circuit when the exponentially weighted failure
+// percentage, in which an event counts half as much after halfLifeSeconds,
+// reaches threshold.
+func WithEWMAThreshold(threshold float32, halfLifeSeconds float64) Option {
+	return func(o *CircuitOptions) {
+		o.ThresholdType = ThresholdEWMA
+		o.Threshold = threshold
+		o.HalfLifeSeconds = halfLifeSeconds
+	}
+}
+
+// WithThreshold sets ThresholdType and Threshold.
+func WithThreshold(thresholdType string, threshold float32) Option {
+	return func(o *CircuitOptions) {
+		o.ThresholdType = thresholdType
+		o.Threshold = threshold
+	}
+}
+
+// WithCloseThreshold sets CloseThreshold, below which an open circuit closes.
+func WithCloseThreshold(threshold float32) Option {
+	return func(o *CircuitOptions) {
+		o.CloseThreshold = threshold
+	}
+}
+
+// WithThresholds sets multiple threshold rules combined with operator
+// (OperatorAnd or OperatorOr).
+func WithThresholds(operator string, rules ...ThresholdRule) Option {
+	return func(o *CircuitOptions) {
+		o.ThresholdsOperator = operator
+		o.Thresholds = rules
+	}
+}
+
+// WithThresholdStrategy adds a custom threshold type that can then be used as
+// ThresholdType or in Thresholds.
+func WithThresholdStrategy(thresholdType string, strategy ThresholdStrategy) Option {
+	return func(o *CircuitOptions) {
+		strategies := make(map[string]ThresholdStrategy, len(o.ThresholdStrategies)+1)
+		for name, s := range o.ThresholdStrategies {
+			strategies[name] = s
+		}
+		strategies[thresholdType] = strategy
+		o.ThresholdStrategies = strategies
+	}
+}
+
+// WithPercentageRounding sets how the failure percentage is rounded before it
+// is compared to a PERCENTAGE threshold.
+func WithPercentageRounding(rounding string) Option {
+	return func(o *CircuitOptions) {
+		o.PercentageRounding = rounding
+	}
+}
+
+// WithMinimumCount sets the minimum number of events required before the thresholds are evaluated.
+func WithMinimumCount(count int64) Option {
+	return func(o *CircuitOptions) {
+		o.MinimumCount = count
+	}
+}
+
+// WithMinimumFailures sets the minimum number of failures required before a
+// PERCENTAGE threshold trips the circuit.
+func WithMinimumFailures(count int64) Option {
+	return func(o *CircuitOptions) {
+		o.MinimumFailures = count
+	}
+}
+
+// WithMinRequestsPerSecond sets the minimum average request rate since the
+// interval started required before the thresholds are evaluated.
+func WithMinRequestsPerSecond(rate float64) Option {
+	return func(o *CircuitOptions) {
+		o.MinRequestsPerSecond = rate
+	}
+}
+
+// WithMinimumCountPerBucket evaluates MinimumCount against a sliding window
+// that includes part of the previous interval.
+func WithMinimumCountPerBucket() Option {
+	return func(o *CircuitOptions) {
+		o.RequireMinimumCountPerBucket = true
+	}
+}
+
+// WithInterval sets IntervalInSeconds.
+func WithInterval(seconds int) Option {
+	return func(o *CircuitOptions) {
+		o.IntervalInSeconds = seconds
+	}
+}
+
+// WithOpenDuration sets OpenDurationInSeconds.
+func WithOpenDuration(seconds int) Option {
+	return func(o *CircuitOptions) {
+		o.OpenDurationInSeconds = seconds
+	}
+}
+
+// WithStickyOpen keeps an open circuit open across interval resets until the
+// counts recorded since show recovery.
+func WithStickyOpen() Option {
+	return func(o *CircuitOptions) {
+		o.StickyOpen = true
+	}
+}
+
+// WithWarmup sets a grace period after the circuit is configured during which
+// it records events but cannot open.
+func WithWarmup(seconds int) Option {
+	return func(o *CircuitOptions) {
+		o.WarmupSeconds = seconds
+	}
+}
+
+// WithRequireFullWindow keeps the circuit from opening until a full interval
+// has elapsed since it was configured, reset or last closed.
+func WithRequireFullWindow() Option {
+	return func(o *CircuitOptions) {
+		o.RequireFullWindow = true
+	}
+}
+
+// WithStaleAfter clears the counts and closes the circuit once no event was
+// recorded for seconds.
+func WithStaleAfter(seconds int) Option {
+	return func(o *CircuitOptions) {
+		o.StaleAfterSeconds = seconds
+	}
+}
+
+// WithMaxOpen forces the circuit closed once it has not been closed for
+// seconds.
+func WithMaxOpen(seconds int) Option {
+	return func(o *CircuitOptions) {
+		o.MaxOpenSeconds = seconds
+	}
+}
+
+// WithRecordHistory keeps the last n state transitions for Transitions.
+func WithRecordHistory(n int) Option {
+	return func(o *CircuitOptions) {
+		o.RecordHistory = n
+	}
+}
+
+// WithBackoff multiplies the open duration by multiplier on every trip until
+// the circuit recovers, up to maxSeconds (unbounded when zero).
+func WithBackoff(multiplier float64, maxSeconds int) Option {
+	return func(o *CircuitOptions) {
+		o.BackoffMultiplier = multiplier
+		o.MaxOpenDurationInSeconds = maxSeconds
+	}
+}
+
+// WithCooldownJitter randomly lengthens or shortens each open duration by up
+// to the given fraction.
+func WithCooldownJitter(jitter float64) Option {
+	return func(o *CircuitOptions) {
+		o.CooldownJitter = jitter
+	}
+}
+
+// WithRand sets the random source used for CooldownJitter.
+func WithRand(r *rand.Rand) Option {
+	return func(o *CircuitOptions) {
+		o.Rand = r
+	}
+}
+
+// WithHalfOpenMaxProbes makes the circuit half-open once its open duration
+// elapses, admitting up to probes calls through Execute. It closes once all of
+// them succeeded.
+func WithHalfOpenMaxProbes(probes int64) Option {
+	return func(o *CircuitOptions) {
+		o.HalfOpenMaxProbes = probes
+	}
+}
+
+// WithSlowCalls counts calls slower than threshold as slow and opens the
+// circuit when the percentage of slow calls reaches rate.
+func WithSlowCalls(threshold time.Duration, rate float32) Option {
+	return func(o *CircuitOptions) {
+		o.SlowCallThreshold = threshold
+		o.SlowCallRateThreshold = rate
+	}
+}
+
+// WithBadCalls counts calls slower than threshold as slow and opens the
+// circuit when the percentage of calls that failed or were slow reaches rate.
+func WithBadCalls(threshold time.Duration, rate float32) Option {
+	return func(o *CircuitOptions) {
+		o.SlowCallThreshold = threshold
+		o.BadCallRateThreshold = rate
+	}
+}
+
+// WithIsFailure sets the classifier deciding which errors returned to Execute count as failures.
+func WithIsFailure(isFailure func(err error) bool) Option {
+	return func(o *CircuitOptions) {
+		o.IsFailure = isFailure
+	}
+}
+
+// WithErrorClassifier counts the failures recorded by Execute and
+// UpdateStatusErr per class returned by fn in Data().FailuresByClass.
+func WithErrorClassifier(fn func(err error) string) Option {
+	return func(o *CircuitOptions) {
+		o.ErrorClassifier = fn
+	}
+}
+
+// WithFailureStatusCodes sets the status codes recorded as failures by
+// UpdateFromHTTPStatus, replacing the default 500-599.
+func WithFailureStatusCodes(codes ...int) Option {
+	return func(o *CircuitOptions) {
+		o.FailureStatusCodes = codes
+	}
+}
+
+// WithContextErrorsAsFailure records context cancellation and deadline errors as failures in ExecuteContext.
+func WithContextErrorsAsFailure() Option {
+	return func(o *CircuitOptions) {
+		o.CountContextErrorsAsFailure = true
+	}
+}
+
+// WithRecoverPanics returns a panic in the function passed to Execute as an
+// error wrapping ErrPanic instead of raising it again.
+func WithRecoverPanics() Option {
+	return func(o *CircuitOptions) {
+		o.RecoverPanics = true
+	}
+}
+
+// WithProbeOnceKeepsOpen only records the outcome of a successful ProbeOnce
+// instead of closing the circuit.
+func WithProbeOnceKeepsOpen() Option {
+	return func(o *CircuitOptions) {
+		o.ProbeOnceKeepsOpen = true
+	}
+}
+
+// WithContext closes the circuit once ctx is done.
+func WithContext(ctx context.Context) Option {
+	return func(o *CircuitOptions) {
+		o.Context = ctx
+	}
+}
+
+// WithInvertPolarity records every success as a failure and every failure as a
+// success, so the circuit opens on a spike of successes.
+func WithInvertPolarity() Option {
+	return func(o *CircuitOptions) {
+		o.InvertPolarity = true
+	}
+}
+
+// WithMaxConcurrent limits the calls in flight at once through Execute and
+// CircuitTransport to max.
+func WithMaxConcurrent(max int64) Option {
+	return func(o *CircuitOptions) {
+		o.MaxConcurrent = max
+	}
+}
+
+// WithCloseConsecutiveCount sets the consecutive successes required to close an open circuit.
+func WithCloseConsecutiveCount(count int64) Option {
+	return func(o *CircuitOptions) {
+		o.CloseConsecutiveCount = count
+	}
+}
+
+// WithAllowedInterleavedSuccesses lets up to count successes in a row occur
+// within a streak of consecutive failures without ending it.
+func WithAllowedInterleavedSuccesses(count int64) Option {
+	return func(o *CircuitOptions) {
+		o.AllowedInterleavedSuccesses = count
+	}
+}
+
+// OnOpen sets the callback called when the circuit opens.
+func OnOpen(fn func(t CallbackEvent)) Option {
+	return func(o *CircuitOptions) {
+		o.OnCircuitOpen = fn
+	}
+}
+
+// OnClose sets the callback called when the circuit closes.
+func OnClose(fn func(t CallbackEvent)) Option {
+	return func(o *CircuitOptions) {
+		o.OnCircuitClosed = fn
+	}
+}
+
+// OnStateChange sets the callback called on every state change.
+func OnStateChange(fn func(from, to CircuitState, t CallbackEvent)) Option {
+	return func(o *CircuitOptions) {
+		o.OnStateChange = fn
+	}
+}
+
+// OnRejected sets the callback called for every call rejected because the circuit is open.
+func OnRejected(fn func(t CallbackEvent)) Option {
+	return func(o *CircuitOptions) {
+		o.OnRejected = fn
+	}
+}
+
+// OnUpdate sets the callback called after every single event recorded.
+func OnUpdate(fn func(success bool, data CircuitData)) Option {
+	return func(o *CircuitOptions) {
+		o.OnUpdate = fn
+	}
+}
+
+// OnCallbackPanic sets the callback called with the recovered value when a callback panics.
+func OnCallbackPanic(fn func(t CallbackEvent, recovered interface{})) Option {
+	return func(o *CircuitOptions) {
+		o.OnCallbackPanic = fn
+	}
+}
+
+// WithMinCallbackInterval suppresses OnOpen and OnClose callbacks when the
+// same one was called less than interval ago.
+func WithMinCallbackInterval(interval time.Duration) Option {
+	return func(o *CircuitOptions) {
+		o.MinCallbackInterval = interval
+	}
+}
+
+// WithManualReset does not start a ticker, so the counts are only reset by
+// ResetWindow.
+func WithManualReset() Option {
+	return func(o *CircuitOptions) {
+		o.ManualReset = true
+	}
+}
+
+// WithAlignToWallClock aligns the intervals to minute boundaries.
+func WithAlignToWallClock() Option {
+	return func(o *CircuitOptions) {
+		o.AlignToWallClock = true
+	}
+}
+
+// WithAsyncCallbacks delivers callbacks from a dedicated goroutine.
+func WithAsyncCallbacks() Option {
+	return func(o *CircuitOptions) {
+		o.AsyncCallbacks = true
+	}
+}
+
+// WithBlockingSubscribers makes the circuit wait for a subscriber whose buffer
+// is full instead of dropping the event.
+func WithBlockingSubscribers() Option {
+	return func(o *CircuitOptions) {
+		o.BlockSlowSubscribers = true
+	}
+}
+
+// WithClock sets the time source of the circuit.
+func WithClock(clock Clock) Option {
+	return func(o *CircuitOptions) {
+		o.Clock = clock
+	}
+}
+
+// WithLogger sets the logger that receives a line for every state transition.
+func WithLogger(logger Logger) Option {
+	return func(o *CircuitOptions) {
+		o.Logger = logger
+	}
+}
@@ -0,0 +1,242 @@
+package tripper
+
+import (
+	"errors"
+	"math/rand"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// optionsOf applies opts the way NewCircuit does and returns the resulting options.
+func optionsOf(opts ...Option) CircuitOptions {
+	monitorOptions := CircuitOptions{Name: "test"}
+	for _, opt := range opts {
+		opt(&monitorOptions)
+	}
+	return monitorOptions
+}
+
+func TestNewCircuit(t *testing.T) {
+	// Test case 1: Create a circuit with valid options
+	// Expected output: No error and a circuit configured with the options
+	clock := NewFakeClock(time.Unix(1700000000, 0))
+	circuit, err := NewCircuit("test",
+		WithPercentageThreshold(50),
+		WithMinimumCount(20),
+		WithInterval(120),
+		WithClock(clock),
+	)
+	assert.NoError(t, err)
+	assert.NotNil(t, circuit)
+	monitor := circuit.(*CircuitImplementation)
+	assert.Equal(t, "test", monitor.Options.Name)
+	assert.Equal(t, ThresholdPercentage, monitor.Options.ThresholdType)
+	assert.Equal(t, float32(50), monitor.Options.Threshold)
+	assert.Equal(t, int64(20), monitor.Options.MinimumCount)
+	assert.Equal(t, 120, monitor.Options.IntervalInSeconds)
+	circuit.Close()
+
+	// Test case 2: Create a circuit without a threshold
+	// Expected output: The same validation error as ConfigureCircuit
+	_, err = NewCircuit("test", WithMinimumCount(20), WithInterval(120))
+	assert.EqualError(t, err, "invalid threshold type ")
+
+	// Test case 3: Create a circuit with an invalid interval
+	// Expected output: The same validation error as ConfigureCircuit
+	_, err = NewCircuit("test", WithCountThreshold(5), WithMinimumCount(20), WithInterval(90))
+	assert.EqualError(t, err, "invalid interval 90, should be a multiple of 60")
+
+	// Test case 4: The builder opens the circuit like ConfigureCircuit
+	// Expected output: The circuit opens once the threshold is reached
+	circuit, err = NewCircuit("test",
+		WithConsecutiveThreshold(2),
+		WithMinimumCount(1),
+		WithInterval(60),
+		WithClock(clock),
+	)
+	assert.NoError(t, err)
+	circuit.UpdateStatus(false)
+	circuit.UpdateStatus(false)
+	assert.True(t, circuit.IsCircuitOpen())
+	circuit.Close()
+}
+
+func TestOptions(t *testing.T) {
+	// Test case 1: Threshold options
+	// Expected output: ThresholdType, Threshold and PercentageRounding set accordingly
+	o := optionsOf(WithPercentageThreshold(50))
+	assert.Equal(t, ThresholdPercentage, o.ThresholdType)
+	assert.Equal(t, float32(50), o.Threshold)
+	o = optionsOf(WithCountThreshold(10))
+	assert.Equal(t, ThresholdCount, o.ThresholdType)
+	assert.Equal(t, float32(10), o.Threshold)
+	o = optionsOf(WithConsecutiveThreshold(3))
+	assert.Equal(t, ThresholdConsecutive, o.ThresholdType)
+	assert.Equal(t, float32(3), o.Threshold)
+	o = optionsOf(WithPercentageRounding(RoundingCeil))
+	assert.Equal(t, RoundingCeil, o.PercentageRounding)
+	o = optionsOf(WithThreshold(ThresholdCount, 7))
+	assert.Equal(t, ThresholdCount, o.ThresholdType)
+	assert.Equal(t, float32(7), o.Threshold)
+
+	// Test case 2: Multiple threshold rules
+	// Expected output: Thresholds and ThresholdsOperator set
+	rules := []ThresholdRule{
+		{ThresholdType: ThresholdPercentage, Threshold: 50},
+		{ThresholdType: ThresholdCount, Threshold: 10},
+	}
+	o = optionsOf(WithThresholds(OperatorOr, rules...))
+	assert.Equal(t, OperatorOr, o.ThresholdsOperator)
+	assert.Equal(t, rules, o.Thresholds)
+
+	// Test case 3: Window options
+	// Expected output: MinimumCount, RequireMinimumCountPerBucket, IntervalInSeconds, OpenDurationInSeconds and HalfOpenMaxProbes set
+	o = optionsOf(WithMinimumCount(20), WithMinimumCountPerBucket(), WithInterval(120), WithOpenDuration(30), WithHalfOpenMaxProbes(2))
+	assert.Equal(t, int64(20), o.MinimumCount)
+	assert.True(t, o.RequireMinimumCountPerBucket)
+	assert.Equal(t, 120, o.IntervalInSeconds)
+	assert.Equal(t, 30, o.OpenDurationInSeconds)
+	assert.Equal(t, int64(2), o.HalfOpenMaxProbes)
+
+	// Test case 4: Cooldown options
+	// Expected output: CooldownJitter, Rand, BackoffMultiplier and MaxOpenDurationInSeconds set
+	r := rand.New(rand.NewSource(1))
+	o = optionsOf(WithCooldownJitter(0.2), WithRand(r), WithBackoff(2, 300))
+	assert.Equal(t, 0.2, o.CooldownJitter)
+	assert.Equal(t, r, o.Rand)
+	assert.Equal(t, 2.0, o.BackoffMultiplier)
+	assert.Equal(t, 300, o.MaxOpenDurationInSeconds)
+
+	// Test case 5: Slow call options
+	// Expected output: SlowCallThreshold and SlowCallRateThreshold set
+	o = optionsOf(WithSlowCalls(time.Second, 40))
+	assert.Equal(t, time.Second, o.SlowCallThreshold)
+	assert.Equal(t, float32(40), o.SlowCallRateThreshold)
+
+	// Test case 6: Failure classification options
+	// Expected output: IsFailure and CountContextErrorsAsFailure set
+	errIgnored := errors.New("ignored")
+	o = optionsOf(WithIsFailure(func(err error) bool { return err != errIgnored }), WithContextErrorsAsFailure())
+	assert.False(t, o.IsFailure(errIgnored))
+	assert.True(t, o.IsFailure(errors.New("other")))
+	assert.True(t, o.CountContextErrorsAsFailure)
+
+	// Test case 7: Close consecutive count
+	// Expected output: CloseConsecutiveCount set
+	o = optionsOf(WithCloseConsecutiveCount(5))
+	assert.Equal(t, int64(5), o.CloseConsecutiveCount)
+
+	// Test case 8: Callback options
+	// Expected output: Each callback set to the given function
+	var called []string
+	o = optionsOf(
+		OnOpen(func(t CallbackEvent) { called = append(called, "open") }),
+		OnClose(func(t CallbackEvent) { called = append(called, "closed") }),
+		OnStateChange(func(from, to CircuitState, t CallbackEvent) { called = append(called, "change") }),
+		OnCallbackPanic(func(t CallbackEvent, recovered interface{}) { called = append(called, "panic") }),
+	)
+	o.OnCircuitOpen(CallbackEvent{})
+	o.OnCircuitClosed(CallbackEvent{})
+	o.OnStateChange(StateClosed, StateOpen, CallbackEvent{})
+	o.OnCallbackPanic(CallbackEvent{}, nil)
+	assert.Equal(t, []string{"open", "closed", "change", "panic"}, called)
+
+	// Test case 9: Callback delivery and clock options
+	// Expected output: AsyncCallbacks and Clock set
+	clock := NewFakeClock(time.Unix(1700000000, 0))
+	o = optionsOf(WithAsyncCallbacks(), WithClock(clock))
+	assert.True(t, o.AsyncCallbacks)
+	assert.Equal(t, clock, o.Clock)
+
+	// Test case 10: Later options override earlier ones
+	// Expected output: The last threshold wins
+	o = optionsOf(WithCountThreshold(10), WithPercentageThreshold(50))
+	assert.Equal(t, ThresholdPercentage, o.ThresholdType)
+	assert.Equal(t, float32(50), o.Threshold)
+}
+
+func TestClone(t *testing.T) {
+	clock := NewFakeClock(time.Unix(1700000000, 0))
+	opened := []string{}
+	base, err := NewCircuit("orders",
+		WithConsecutiveThreshold(2),
+		WithMinimumCount(1),
+		WithInterval(60),
+		WithClock(clock),
+		OnOpen(func(t CallbackEvent) { opened = append(opened, t.Name) }),
+	)
+	assert.NoError(t, err)
+	defer base.Close()
+	base.UpdateStatus(false)
+
+	// Test case 1: Read back the options of a circuit
+	// Expected output: The configured options with the defaults filled in, except the seeded Rand
+	options := base.GetOptions()
+	assert.Equal(t, "orders", options.Name)
+	assert.Equal(t, float32(2), options.Threshold)
+	assert.Equal(t, Clock(clock), options.Clock)
+	assert.Nil(t, options.Rand)
+	assert.NotNil(t, options.Logger)
+
+	// Test case 2: Clone the circuit with another name and a changed threshold
+	// Expected output: A circuit with the new name, the new threshold and no recorded counts
+	search, err := base.Clone("search", WithConsecutiveThreshold(3))
+	assert.NoError(t, err)
+	defer search.Close()
+	assert.Equal(t, "search", search.GetOptions().Name)
+	assert.Equal(t, float32(3), search.GetOptions().Threshold)
+	assert.Equal(t, 60, search.GetOptions().IntervalInSeconds)
+	assert.Equal(t, int64(0), search.Data().FailureCount)
+	assert.True(t, search.(*CircuitImplementation).Options.Rand != base.(*CircuitImplementation).Options.Rand)
+
+	// Test case 3: Build two circuits from one options snapshot
+	// Expected output: Both share the callbacks and the clock but trip independently
+	accounts, err := base.Clone("accounts")
+	assert.NoError(t, err)
+	defer accounts.Close()
+	base.UpdateStatus(false)
+	accounts.UpdateStatus(false)
+	assert.True(t, base.IsCircuitOpen())
+	assert.False(t, accounts.IsCircuitOpen())
+	accounts.UpdateStatus(false)
+	assert.True(t, accounts.IsCircuitOpen())
+	assert.Equal(t, []string{"orders", "accounts"}, opened)
+	clock.Advance(60 * time.Second)
+	assert.False(t, base.IsCircuitOpen())
+	assert.False(t, accounts.IsCircuitOpen())
+
+	// Test case 4: Clone with an invalid override
+	// Expected output: The validation error
+	_, err = base.Clone("invalid", WithInterval(2))
+	assert.EqualError(t, err, "invalid interval 2, should be at least 60")
+
+	// Test case 5: Change the failure status codes and threshold strategies of a circuit after cloning it
+	// Expected output: The clone keeps its own copies, and so does a copy returned by GetOptions
+	never := ThresholdStrategyFunc(func(data CircuitData, options CircuitOptions) bool { return false })
+	payments, err := NewCircuit("payments",
+		WithConsecutiveThreshold(2),
+		WithMinimumCount(1),
+		WithInterval(60),
+		WithClock(clock),
+		WithThresholdStrategy("never", never),
+		WithFailureStatusCodes(http.StatusBadGateway),
+	)
+	assert.NoError(t, err)
+	defer payments.Close()
+	refunds, err := payments.Clone("refunds")
+	assert.NoError(t, err)
+	defer refunds.Close()
+	source := payments.(*CircuitImplementation)
+	source.Options.FailureStatusCodes[0] = http.StatusTooManyRequests
+	source.Options.ThresholdStrategies["always"] = never
+	options = refunds.GetOptions()
+	assert.Equal(t, []int{http.StatusBadGateway}, options.FailureStatusCodes)
+	assert.Len(t, options.ThresholdStrategies, 1)
+	options.FailureStatusCodes[0] = http.StatusServiceUnavailable
+	delete(options.ThresholdStrategies, "never")
+	assert.Equal(t, []int{http.StatusBadGateway}, refunds.GetOptions().FailureStatusCodes)
+	assert.Len(t, refunds.GetOptions().ThresholdStrategies, 1)
+}
@@ -0,0 +1,41 @@
+package tripper
+
+import (
+	"errors"
+	"sync/atomic"
+)
+
+// ErrTooManyRequests is returned by Execute, ExecuteContext and
+// CircuitTransport when MaxConcurrent calls are already in flight.
+var ErrTooManyRequests = errors.New("too many concurrent requests")
+
+// acquireSlot reserves a slot for a call through Execute or CircuitTransport.
+// It returns false, counting the rejection, when MaxConcurrent calls are
+// already in flight. Every call that acquired a slot must give it back with
+// releaseSlot, even when the circuit rejects it. A nil circuit has no limit.
+func (m *CircuitImplementation) acquireSlot() bool {
+	if m == nil {
+		return true
+	}
+
+	m.Mutex.RLock()
+	maxConcurrent := m.Options.MaxConcurrent
+	m.Mutex.RUnlock()
+
+	// calls are always counted, so that a change of MaxConcurrent with
+	// UpdateOptions applies to the calls already in flight
+	if inFlight := atomic.AddInt64(&m.inFlight, 1); maxConcurrent > 0 && inFlight > maxConcurrent {
+		atomic.AddInt64(&m.inFlight, -1)
+		atomic.AddInt64(&m.concurrencyRejections, 1)
+		return false
+	}
+	return true
+}
+
+// releaseSlot gives back a slot reserved by acquireSlot.
+func (m *CircuitImplementation) releaseSlot() {
+	if m == nil {
+		return
+	}
+	atomic.AddInt64(&m.inFlight, -1)
+}
@@ -0,0 +1,120 @@
+package tripper
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMaxConcurrent(t *testing.T) {
+	m, err := ConfigureCircuit(CircuitOptions{
+		Name:              "TEST_MaxConcurrent",
+		Threshold:         2,
+		ThresholdType:     ThresholdConsecutive,
+		IntervalInSeconds: 60,
+		MaxConcurrent:     3,
+		Clock:             NewFakeClock(time.Unix(1700000000, 0)),
+	})
+	assert.NoError(t, err)
+	defer m.Close()
+
+	// Test case 1: Saturate the bulkhead with calls that block
+	// Expected output: MaxConcurrent calls are in flight
+	release := make(chan struct{})
+	var wg sync.WaitGroup
+	for i := 0; i < 3; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			assert.NoError(t, m.Execute(func() error {
+				<-release
+				return nil
+			}))
+		}()
+	}
+	waitFor(t, func() bool { return m.Data().InFlight == 3 })
+
+	// Test case 2: Make more calls while the bulkhead is saturated, concurrently
+	// Expected output: Every excess call is rejected without running and the circuit does not trip
+	var rejected sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		rejected.Add(1)
+		go func() {
+			defer rejected.Done()
+			assert.Equal(t, ErrTooManyRequests, m.Execute(func() error {
+				t.Error("rejected call was run")
+				return nil
+			}))
+		}()
+	}
+	rejected.Wait()
+	data := m.Data()
+	assert.Equal(t, int64(5), data.ConcurrencyRejected)
+	assert.Equal(t, int64(0), data.FailureCount)
+	assert.Equal(t, int64(0), data.ConsecutiveCounter)
+	assert.False(t, m.IsCircuitOpen())
+
+	// Test case 3: Let the blocked calls finish
+	// Expected output: Their slots are given back and new calls are admitted
+	close(release)
+	wg.Wait()
+	assert.Equal(t, int64(0), m.Data().InFlight)
+	assert.Equal(t, int64(3), m.Data().SuccessCount)
+	assert.NoError(t, m.Execute(func() error { return nil }))
+
+	// Test case 4: Make calls while the circuit is open
+	// Expected output: ErrCircuitOpen is returned and no slot is held
+	m.UpdateStatus(false)
+	m.UpdateStatus(false)
+	assert.Equal(t, ErrCircuitOpen, m.Execute(func() error { return nil }))
+	assert.Equal(t, int64(0), m.Data().InFlight)
+	assert.Equal(t, int64(5), m.Data().ConcurrencyRejected)
+}
+
+func TestMaxConcurrentTransport(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer server.Close()
+
+	m, err := ConfigureCircuit(CircuitOptions{
+		Name:              "TEST_MaxConcurrentTransport",
+		Threshold:         2,
+		ThresholdType:     ThresholdConsecutive,
+		IntervalInSeconds: 60,
+		MaxConcurrent:     1,
+		Clock:             NewFakeClock(time.Unix(1700000000, 0)),
+	})
+	assert.NoError(t, err)
+	defer m.Close()
+	client := &http.Client{Transport: NewCircuitTransport(m, nil)}
+
+	// Test case 1: Send a request while another one is in flight
+	// Expected output: The second request fails with ErrTooManyRequests without being sent
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		resp, err := client.Get(server.URL)
+		if assert.NoError(t, err) {
+			resp.Body.Close()
+		}
+	}()
+	waitFor(t, func() bool { return m.Data().InFlight == 1 })
+	_, err = client.Get(server.URL)
+	assert.True(t, errors.Is(err, ErrTooManyRequests))
+	assert.Equal(t, int64(1), m.Data().ConcurrencyRejected)
+
+	// Test case 2: Let the first request finish
+	// Expected output: Only its outcome is recorded
+	close(release)
+	<-done
+	assert.Equal(t, int64(0), m.Data().InFlight)
+	assert.Equal(t, int64(1), m.Data().SuccessCount)
+	assert.Equal(t, int64(0), m.Data().FailureCount)
+}
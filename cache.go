@@ -0,0 +1,52 @@
+package tripper
+
+import "errors"
+
+// Cache stores the values loaded by GetWithCircuit, e.g. an in-memory map or a
+// client of a shared cache. It must be safe for concurrent use when the
+// circuit is.
+type Cache interface {
+	Get(key string) ([]byte, bool) // Returns the value stored under key and whether there is one
+	Set(key string, value []byte)  // Stores value under key
+}
+
+// CircuitCache serves values from a read-through cache through a circuit: the
+// values are loaded while the circuit allows calls and served from the cache
+// while it does not.
+//
+//	cache := &tripper.CircuitCache{Circuit: circuit, Cache: store}
+//	profile, err := cache.Get("user:42", loadProfile)
+type CircuitCache struct {
+	Circuit Circuit // Circuit recording the outcome of every load
+	Cache   Cache   // Cache refreshed by successful loads and read while the circuit is open
+}
+
+// Get returns the value for key like GetWithCircuit.
+func (c *CircuitCache) Get(key string, loader func() ([]byte, error)) ([]byte, error) {
+	return GetWithCircuit(c.Circuit, key, loader, c.Cache)
+}
+
+// GetWithCircuit loads the value for key through c with Execute and stores it
+// in cache once the load succeeded. While c rejects calls, with
+// ErrCircuitOpen or ErrTooManyRequests, the value cached under key is returned
+// instead, so a dependency that is down does not take its readers down with
+// it; the error is only returned when nothing is cached. A failed load is
+// recorded in c and its error returned without falling back to the cache.
+func GetWithCircuit(c Circuit, key string, loader func() ([]byte, error), cache Cache) ([]byte, error) {
+	var value []byte
+	err := c.Execute(func() error {
+		var err error
+		value, err = loader()
+		return err
+	})
+	if err == nil {
+		cache.Set(key, value)
+		return value, nil
+	}
+	if errors.Is(err, ErrCircuitOpen) || errors.Is(err, ErrTooManyRequests) {
+		if cached, ok := cache.Get(key); ok {
+			return cached, nil
+		}
+	}
+	return nil, err
+}
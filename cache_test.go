@@ -0,0 +1,133 @@
+package tripper
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// mapCache is a Cache backed by a map.
+type mapCache struct {
+	mutex  sync.Mutex
+	values map[string][]byte
+}
+
+func (c *mapCache) Get(key string) ([]byte, bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	value, ok := c.values[key]
+	return value, ok
+}
+
+func (c *mapCache) Set(key string, value []byte) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if c.values == nil {
+		c.values = make(map[string][]byte)
+	}
+	c.values[key] = value
+}
+
+func TestGetWithCircuit(t *testing.T) {
+	m, err := ConfigureCircuit(CircuitOptions{
+		Name:              "TEST_GetWithCircuit",
+		Threshold:         2,
+		ThresholdType:     ThresholdConsecutive,
+		IntervalInSeconds: 60,
+		Clock:             NewFakeClock(time.Unix(1700000000, 0)),
+	})
+	assert.NoError(t, err)
+	defer m.Close()
+	cache := &mapCache{}
+	loads := 0
+	load := func(value string, err error) func() ([]byte, error) {
+		return func() ([]byte, error) {
+			loads++
+			if err != nil {
+				return nil, err
+			}
+			return []byte(value), nil
+		}
+	}
+
+	// Test case 1: Get a value while the circuit is closed
+	// Expected output: The value is loaded, cached and returned
+	value, err := GetWithCircuit(m, "user:1", load("alice", nil), cache)
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("alice"), value)
+	cached, ok := cache.Get("user:1")
+	assert.True(t, ok)
+	assert.Equal(t, []byte("alice"), cached)
+	assert.Equal(t, 1, loads)
+
+	// Test case 2: Load a value that changed
+	// Expected output: The cache is refreshed with the new value
+	value, err = GetWithCircuit(m, "user:1", load("alice v2", nil), cache)
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("alice v2"), value)
+	cached, _ = cache.Get("user:1")
+	assert.Equal(t, []byte("alice v2"), cached)
+
+	// Test case 3: Fail to load while the circuit is closed
+	// Expected output: The error is returned instead of the cached value and the failures trip the circuit
+	for i := 0; i < 2; i++ {
+		value, err = GetWithCircuit(m, "user:1", load("", errService), cache)
+		assert.Equal(t, errService, err)
+		assert.Nil(t, value)
+	}
+	assert.True(t, m.IsCircuitOpen())
+	assert.Equal(t, 4, loads)
+
+	// Test case 4: Get values while the circuit is open
+	// Expected output: The cached value is served without loading, and ErrCircuitOpen is returned for a key that is not cached
+	value, err = GetWithCircuit(m, "user:1", load("unused", nil), cache)
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("alice v2"), value)
+	value, err = (&CircuitCache{Circuit: m, Cache: cache}).Get("user:2", load("unused", nil))
+	assert.Equal(t, ErrCircuitOpen, err)
+	assert.Nil(t, value)
+	assert.Equal(t, 4, loads)
+	_, ok = cache.Get("user:2")
+	assert.False(t, ok)
+}
+
+func TestCircuitCacheTooManyRequests(t *testing.T) {
+	m, err := ConfigureCircuit(CircuitOptions{
+		Name:              "TEST_CircuitCacheTooManyRequests",
+		Threshold:         2,
+		ThresholdType:     ThresholdConsecutive,
+		IntervalInSeconds: 60,
+		MaxConcurrent:     1,
+		Clock:             NewFakeClock(time.Unix(1700000000, 0)),
+	})
+	assert.NoError(t, err)
+	defer m.Close()
+	cache := &CircuitCache{Circuit: m, Cache: &mapCache{}}
+	_, err = cache.Get("config", func() ([]byte, error) { return []byte("v1"), nil })
+	assert.NoError(t, err)
+
+	// Test case 1: Get a value while MaxConcurrent loads are in flight
+	// Expected output: The cached value is served
+	release := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		value, err := cache.Get("config", func() ([]byte, error) {
+			<-release
+			return []byte("v2"), nil
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, []byte("v2"), value)
+	}()
+	waitFor(t, func() bool { return m.Data().InFlight == 1 })
+	value, err := cache.Get("config", func() ([]byte, error) {
+		t.Error("rejected load was run")
+		return nil, nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("v1"), value)
+	close(release)
+	<-done
+}
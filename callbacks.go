@@ -0,0 +1,195 @@
+package tripper
+
+import (
+	"log"
+	"sync/atomic"
+	"time"
+)
+
+// callbackBufferSize is the number of events that can be queued for an
+// AsyncCallbacks circuit before UpdateStatus waits for the callback goroutine.
+const callbackBufferSize = 64
+
+// callbackEvent builds the event passed to callbacks for a transition from the
+// given state to the current one for the given reason. The caller must hold
+// m.Mutex.
+func (m *CircuitImplementation) callbackEvent(timestamp int64, fromState CircuitState, reason string) CallbackEvent {
+	return CallbackEvent{
+		Name:         m.Options.Name,
+		Timestamp:    unixSeconds(timestamp),
+		at:           timestamp,
+		SuccessCount: m.SuccessCount,
+		FailureCount: m.FailureCount,
+		FromState:    fromState,
+		ToState:      m.state(),
+		Reason:       reason,
+	}
+}
+
+// rejectionEvent builds the event passed to OnRejected for a call rejected by
+// the circuit. It returns false when OnRejected is not set.
+func (m *CircuitImplementation) rejectionEvent() (CallbackEvent, bool) {
+	m.Mutex.RLock()
+	defer m.Mutex.RUnlock()
+
+	if m.Options.OnRejected == nil {
+		return CallbackEvent{}, false
+	}
+	state, now := m.state(), m.now()
+	return CallbackEvent{
+		Name:         m.Options.Name,
+		Timestamp:    unixSeconds(now),
+		at:           now,
+		SuccessCount: atomic.LoadInt64(&m.SuccessCount),
+		FailureCount: atomic.LoadInt64(&m.FailureCount),
+		FromState:    state,
+		ToState:      state,
+		rejected:     true,
+	}, true
+}
+
+// updateNotice returns the OnUpdate call for an event just recorded, with the
+// data right after it, or nil without OnUpdate. It must be called after
+// m.Mutex is released, after the events of any state change were dispatched.
+// The caller must hold m.Mutex for reading.
+func (m *CircuitImplementation) updateNotice(success bool) func() {
+	if m.Options.OnUpdate == nil {
+		return nil
+	}
+	options := m.Options
+	data := m.data()
+	capturedAt := atomic.LoadInt64(&m.LastCapturedAt)
+	event := CallbackEvent{
+		Name:         options.Name,
+		Timestamp:    unixSeconds(capturedAt),
+		at:           capturedAt,
+		SuccessCount: data.SuccessCount,
+		FailureCount: data.FailureCount,
+		FromState:    data.State,
+		ToState:      data.State,
+	}
+	return func() {
+		safeCall(options, event, func() {
+			options.OnUpdate(success, data)
+		})
+	}
+}
+
+// dispatch delivers events to the callbacks in order. It must be called
+// without holding m.Mutex so that a slow callback never blocks other updates.
+func (m *CircuitImplementation) dispatch(events ...CallbackEvent) {
+	for _, event := range events {
+		if m.callbacks == nil {
+			m.notify(event)
+			continue
+		}
+		select {
+		case m.callbacks <- event:
+		case <-m.done:
+		}
+	}
+}
+
+// runCallbacks delivers queued events in order until the circuit is closed.
+func (m *CircuitImplementation) runCallbacks() {
+	defer close(m.stopped)
+	for {
+		select {
+		case event := <-m.callbacks:
+			m.notify(event)
+		case <-m.done:
+			return
+		}
+	}
+}
+
+// notify invokes the callbacks for an event. Rejection events only go to
+// OnRejected. Otherwise OnStateChange is called first and
+// only when the state actually changed, followed by OnCircuitOpen or
+// OnCircuitClosed depending on the new state, unless MinCallbackInterval
+// suppresses it. State changes are also written to the Logger and the
+// subscribers. The callbacks are read under m.Mutex since UpdateOptions may
+// replace them concurrently.
+func (m *CircuitImplementation) notify(event CallbackEvent) {
+	m.Mutex.RLock()
+	options := m.Options
+	m.Mutex.RUnlock()
+
+	if event.rejected {
+		if options.OnRejected != nil {
+			safeCall(options, event, func() {
+				options.OnRejected(event)
+			})
+		}
+		return
+	}
+	if event.FromState != event.ToState {
+		logTransition(options.Logger, event)
+		m.publish(event, options.BlockSlowSubscribers)
+	}
+	if event.FromState != event.ToState && options.OnStateChange != nil {
+		safeCall(options, event, func() {
+			options.OnStateChange(event.FromState, event.ToState, event)
+		})
+	}
+	if m.throttled(options, event) {
+		return
+	}
+	switch event.ToState {
+	case StateOpen:
+		if options.OnCircuitOpen != nil {
+			safeCall(options, event, func() {
+				options.OnCircuitOpen(event)
+			})
+		}
+	case StateClosed:
+		if options.OnCircuitClosed != nil {
+			safeCall(options, event, func() {
+				options.OnCircuitClosed(event)
+			})
+		}
+	}
+}
+
+// throttled reports whether the OnCircuitOpen or OnCircuitClosed call for an
+// event is suppressed because the same callback was called less than
+// MinCallbackInterval before the event. Only the calls that are made start a
+// new interval, so a flapping circuit calls each of them at most once per
+// interval.
+func (m *CircuitImplementation) throttled(options CircuitOptions, event CallbackEvent) bool {
+	if options.MinCallbackInterval <= 0 {
+		return false
+	}
+	var last *int64
+	switch event.ToState {
+	case StateOpen:
+		last = &m.lastOpenCallbackAt
+	case StateClosed:
+		last = &m.lastCloseCallbackAt
+	default:
+		return false
+	}
+	m.throttleMu.Lock()
+	defer m.throttleMu.Unlock()
+
+	if *last != 0 && time.Duration(event.at-*last) < options.MinCallbackInterval {
+		return true
+	}
+	*last = event.at
+	return false
+}
+
+// safeCall runs a user callback and contains any panic it raises, reporting it
+// to OnCallbackPanic or the standard logger.
+func safeCall(options CircuitOptions, event CallbackEvent, fn func()) {
+	defer func() {
+		if r := recover(); r != nil {
+			if options.OnCallbackPanic != nil {
+				options.OnCallbackPanic(event, r)
+				return
+			}
+			log.Printf("tripper: callback for circuit %s panicked: %v", event.Name, r)
+		}
+	}()
+	fn()
+}
@@ -0,0 +1,379 @@
+package tripper
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSlowCallbackDoesNotBlockUpdates(t *testing.T) {
+	started := make(chan struct{})
+	release := make(chan struct{})
+	monitorOptions := CircuitOptions{
+		Name:              "TEST_SlowCallback",
+		Threshold:         2,
+		MinimumCount:      3,
+		IntervalInSeconds: 60,
+		ThresholdType:     ThresholdCount,
+		OnCircuitOpen: func(x CallbackEvent) {
+			close(started)
+			<-release
+		},
+	}
+	m, err := ConfigureCircuit(monitorOptions)
+	assert.NoError(t, err)
+
+	// Trip the circuit from another goroutine; its open callback blocks until released
+	tripped := make(chan struct{})
+	go func() {
+		m.UpdateStatus(false)
+		m.UpdateStatus(false)
+		m.UpdateStatus(false)
+		close(tripped)
+	}()
+	<-started
+
+	// While the callback is running other updates and reads are not serialized behind it
+	done := make(chan struct{})
+	go func() {
+		for i := 0; i < 10; i++ {
+			m.UpdateStatus(true)
+		}
+		assert.Equal(t, int64(10), m.Data().SuccessCount)
+		assert.True(t, m.IsCircuitOpen())
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("UpdateStatus blocked by a running callback")
+	}
+
+	close(release)
+	<-tripped
+}
+
+func TestAsyncCallbacks(t *testing.T) {
+	events := make(chan CallbackEvent, 2)
+	release := make(chan struct{})
+	monitorOptions := CircuitOptions{
+		Name:              "TEST_AsyncCallbacks",
+		Threshold:         50,
+		MinimumCount:      3,
+		IntervalInSeconds: 60,
+		ThresholdType:     ThresholdPercentage,
+		AsyncCallbacks:    true,
+		Clock:             NewFakeClock(time.Unix(1700000000, 0)),
+		OnCircuitOpen: func(x CallbackEvent) {
+			<-release
+			events <- x
+		},
+		OnCircuitClosed: func(x CallbackEvent) {
+			events <- x
+		},
+	}
+	m, err := ConfigureCircuit(monitorOptions)
+	assert.NoError(t, err)
+	defer m.Close()
+
+	// Test case 1: The tripping call returns while the callback is still blocked
+	// Expected output: Circuit open without waiting for the callback
+	done := make(chan struct{})
+	go func() {
+		m.UpdateStatus(false)
+		m.UpdateStatus(false)
+		m.UpdateStatus(false)
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("UpdateStatus waited for an async callback")
+	}
+	assert.True(t, m.IsCircuitOpen())
+
+	// Test case 2: Events are delivered in order once the callback goroutine proceeds
+	// Expected output: The open event followed by the closed event
+	for i := 0; i < 10; i++ {
+		m.UpdateStatus(true)
+	}
+	assert.False(t, m.IsCircuitOpen())
+	close(release)
+	assert.Equal(t, StateOpen, (<-events).ToState)
+	assert.Equal(t, StateClosed, (<-events).ToState)
+}
+
+func TestCallbackPanicIsRecovered(t *testing.T) {
+	var recovered []interface{}
+	closedCalled := false
+	monitorOptions := CircuitOptions{
+		Name:              "TEST_CallbackPanic",
+		Threshold:         50,
+		MinimumCount:      2,
+		IntervalInSeconds: 60,
+		ThresholdType:     ThresholdPercentage,
+		OnCircuitOpen: func(x CallbackEvent) {
+			panic("open handler failed")
+		},
+		OnStateChange: func(from, to CircuitState, x CallbackEvent) {
+			if to == StateClosed {
+				panic("state change handler failed")
+			}
+		},
+		OnCircuitClosed: func(x CallbackEvent) {
+			closedCalled = true
+		},
+		OnCallbackPanic: func(x CallbackEvent, r interface{}) {
+			assert.Equal(t, "TEST_CallbackPanic", x.Name)
+			recovered = append(recovered, r)
+		},
+	}
+	m, err := ConfigureCircuit(monitorOptions)
+	assert.NoError(t, err)
+
+	// Test case 1: A panicking open callback does not escape UpdateStatus
+	// Expected output: Circuit open and the panic reported
+	assert.NotPanics(t, func() {
+		m.UpdateStatus(false)
+		m.UpdateStatus(false)
+	})
+	assert.True(t, m.IsCircuitOpen())
+	assert.Equal(t, []interface{}{"open handler failed"}, recovered)
+
+	// Test case 2: A panic in one callback does not skip the next one
+	// Expected output: Circuit closed and OnCircuitClosed still called
+	assert.NotPanics(t, func() {
+		m.UpdateStatus(true)
+		m.UpdateStatus(true)
+		m.UpdateStatus(true)
+	})
+	assert.False(t, m.IsCircuitOpen())
+	assert.True(t, closedCalled)
+	assert.Equal(t, []interface{}{"open handler failed", "state change handler failed"}, recovered)
+}
+
+func TestAsyncCallbackPanicIsRecovered(t *testing.T) {
+	events := make(chan CallbackEvent, 1)
+	monitorOptions := CircuitOptions{
+		Name:              "TEST_AsyncCallbackPanic",
+		Threshold:         50,
+		MinimumCount:      2,
+		IntervalInSeconds: 60,
+		ThresholdType:     ThresholdPercentage,
+		AsyncCallbacks:    true,
+		Clock:             NewFakeClock(time.Unix(1700000000, 0)),
+		OnCircuitOpen: func(x CallbackEvent) {
+			panic("open handler failed")
+		},
+		OnCircuitClosed: func(x CallbackEvent) {
+			events <- x
+		},
+	}
+	m, err := ConfigureCircuit(monitorOptions)
+	assert.NoError(t, err)
+	defer m.Close()
+
+	// The callback goroutine survives the panic and keeps delivering events
+	m.UpdateStatus(false)
+	m.UpdateStatus(false)
+	m.UpdateStatus(true)
+	m.UpdateStatus(true)
+	m.UpdateStatus(true)
+	assert.Equal(t, StateClosed, (<-events).ToState)
+}
+
+func TestMinCallbackInterval(t *testing.T) {
+	clock := NewFakeClock(time.Unix(1700000000, 0))
+	var opened, closed []int64
+	var changes int
+	m, err := ConfigureCircuit(CircuitOptions{
+		Name:                "TEST_MinCallbackInterval",
+		Threshold:           1,
+		ThresholdType:       ThresholdConsecutive,
+		IntervalInSeconds:   60,
+		MinCallbackInterval: 10 * time.Second,
+		Clock:               clock,
+		OnCircuitOpen: func(x CallbackEvent) {
+			opened = append(opened, x.Timestamp)
+		},
+		OnCircuitClosed: func(x CallbackEvent) {
+			closed = append(closed, x.Timestamp)
+		},
+		OnStateChange: func(from, to CircuitState, x CallbackEvent) {
+			changes++
+		},
+	})
+	assert.NoError(t, err)
+	defer m.Close()
+
+	// Test case 1: Flap the circuit open and closed every second for 30 seconds
+	// Expected output: OnCircuitOpen and OnCircuitClosed are called at most once per 10 seconds
+	for i := 0; i < 30; i++ {
+		m.UpdateStatus(false)
+		m.UpdateStatus(true)
+		clock.Advance(time.Second)
+	}
+	assert.Equal(t, []int64{1700000000, 1700000010, 1700000020}, opened)
+	assert.Equal(t, []int64{1700000000, 1700000010, 1700000020}, closed)
+
+	// Test case 2: Check the state tracked while the callbacks were suppressed
+	// Expected output: OnStateChange and Data reflect every transition
+	assert.Equal(t, 60, changes)
+	assert.Equal(t, int64(30), m.Data().TripCount)
+	assert.False(t, m.IsCircuitOpen())
+
+	// Test case 3: Open the circuit once the interval has passed since the last call
+	// Expected output: OnCircuitOpen is called again
+	m.UpdateStatus(false)
+	assert.Equal(t, 4, len(opened))
+	assert.True(t, m.IsCircuitOpen())
+}
+
+func TestCallbackReason(t *testing.T) {
+	clock := NewFakeClock(time.Unix(1700000000, 0))
+	var reasons []string
+	newCircuit := func(options CircuitOptions) Circuit {
+		options.Name = "TEST_CallbackReason"
+		options.Threshold = 2
+		options.ThresholdType = ThresholdConsecutive
+		options.IntervalInSeconds = 60
+		options.OnStateChange = func(from, to CircuitState, event CallbackEvent) {
+			reasons = append(reasons, event.Reason)
+		}
+		options.Clock = clock
+		m, err := ConfigureCircuit(options)
+		assert.NoError(t, err)
+		return m
+	}
+
+	// Test case 1: Trip the circuit and let its counts show recovery
+	// Expected output: ReasonTripped, then ReasonRecovered
+	m := newCircuit(CircuitOptions{})
+	defer m.Close()
+	m.UpdateStatus(false)
+	m.UpdateStatus(false)
+	m.UpdateStatus(true)
+	assert.Equal(t, []string{ReasonTripped, ReasonRecovered}, reasons)
+
+	// Test case 2: Let the interval reset close the open circuit, then call Reset
+	// Expected output: ReasonIntervalReset, then ReasonManualReset
+	reasons = nil
+	m.UpdateStatus(false)
+	m.UpdateStatus(false)
+	clock.Advance(time.Minute)
+	m.UpdateStatus(false)
+	m.UpdateStatus(false)
+	m.Reset()
+	assert.Equal(t, []string{ReasonTripped, ReasonIntervalReset, ReasonTripped, ReasonManualReset}, reasons)
+
+	// Test case 3: Let the open duration elapse into half-open probes
+	// Expected output: ReasonOpenDurationElapsed, ReasonProbeFailed and ReasonRecovered
+	reasons = nil
+	probes := newCircuit(CircuitOptions{OpenDurationInSeconds: 10, HalfOpenMaxProbes: 1})
+	defer probes.Close()
+	probes.UpdateStatus(false)
+	probes.UpdateStatus(false)
+	clock.Advance(10 * time.Second)
+	assert.Equal(t, StateHalfOpen, probes.State())
+	probes.UpdateStatus(false)
+	clock.Advance(10 * time.Second)
+	probes.UpdateStatus(true)
+	assert.Equal(t, []string{ReasonTripped, ReasonOpenDurationElapsed, ReasonProbeFailed, ReasonOpenDurationElapsed, ReasonRecovered}, reasons)
+
+	// Test case 4: Leave an open circuit without events past StaleAfterSeconds
+	// Expected output: ReasonStaleData
+	reasons = nil
+	stale := newCircuit(CircuitOptions{StaleAfterSeconds: 5})
+	defer stale.Close()
+	stale.UpdateStatus(false)
+	stale.UpdateStatus(false)
+	clock.Advance(6 * time.Second)
+	assert.False(t, stale.IsCircuitOpen())
+	assert.Equal(t, []string{ReasonTripped, ReasonStaleData}, reasons)
+}
+
+func TestOnUpdate(t *testing.T) {
+	var mu sync.Mutex
+	var outcomes []bool
+	var updates []CircuitData
+	var m Circuit
+	m, err := ConfigureCircuit(CircuitOptions{
+		Name:              "TEST_OnUpdate",
+		Threshold:         50,
+		ThresholdType:     ThresholdPercentage,
+		MinimumCount:      4,
+		IntervalInSeconds: 60,
+		OnUpdate: func(success bool, data CircuitData) {
+			// the lock is not held, so the circuit can be read
+			assert.Equal(t, data.TotalCount, m.Data().TotalCount)
+			mu.Lock()
+			defer mu.Unlock()
+			outcomes = append(outcomes, success)
+			updates = append(updates, data)
+		},
+		Clock: NewFakeClock(time.Unix(1700000000, 0)),
+	})
+	assert.NoError(t, err)
+	defer m.Close()
+
+	// Test case 1: Record events, including the one that trips the circuit
+	// Expected output: OnUpdate is called once per event with the outcome and the data right after it
+	m.UpdateStatus(true)
+	m.UpdateStatus(false)
+	m.UpdateStatusWeighted(true, 1)
+	m.UpdateStatusErr(errors.New("failed"))
+	assert.Equal(t, []bool{true, false, true, false}, outcomes)
+	for i, data := range updates {
+		assert.Equal(t, int64(i+1), data.TotalCount)
+	}
+	assert.Equal(t, StateClosed, updates[2].State)
+	assert.Equal(t, StateOpen, updates[3].State)
+
+	// Test case 2: Batch and skip events
+	// Expected output: OnUpdate is not called
+	m.UpdateStatusBatch(1, 1)
+	m.RecordAttempts(1, 1, true)
+	m.UpdateStatusExt(true, false)
+	assert.Len(t, outcomes, 4)
+}
+
+func TestOnUpdateConcurrent(t *testing.T) {
+	var calls, failures int64
+	m, err := ConfigureCircuit(CircuitOptions{
+		Name:              "TEST_OnUpdateConcurrent",
+		Threshold:         100,
+		ThresholdType:     ThresholdCount,
+		MinimumCount:      2000,
+		IntervalInSeconds: 60,
+		OnUpdate: func(success bool, data CircuitData) {
+			atomic.AddInt64(&calls, 1)
+			if !success {
+				atomic.AddInt64(&failures, 1)
+			}
+		},
+		Clock: NewFakeClock(time.Unix(1700000000, 0)),
+	})
+	assert.NoError(t, err)
+	defer m.Close()
+
+	// Test case 1: Record events from many goroutines
+	// Expected output: OnUpdate is called exactly once per UpdateStatus call
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				m.UpdateStatus(j%10 != 0)
+			}
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, int64(1000), atomic.LoadInt64(&calls))
+	assert.Equal(t, int64(100), atomic.LoadInt64(&failures))
+	assert.Equal(t, int64(1000), m.Data().TotalCount)
+}
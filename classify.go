@@ -0,0 +1,53 @@
+package tripper
+
+// UpdateStatusErr records the outcome of a call from the error it returned:
+// nil is a success and any other error a failure, unless the IsFailure
+// classifier rejects it. With ErrorClassifier, failures are also counted per
+// class in Data().FailuresByClass.
+//
+//	err := callService()
+//	circuit.UpdateStatusErr(err)
+func (m *CircuitImplementation) UpdateStatusErr(err error) {
+	if m == nil {
+		return
+	}
+
+	m.Mutex.RLock()
+	options := m.Options
+	m.Mutex.RUnlock()
+
+	failure := isFailure(options, err)
+	m.updateStatus(!failure, 0, weightScale, classifyError(options, err, failure))
+}
+
+// classifyError returns the class of a failure with ErrorClassifier, or an
+// empty string for successes and when no classifier is configured. It runs the
+// classifier, so it must be called without holding m.Mutex.
+func classifyError(options CircuitOptions, err error, failure bool) string {
+	if !failure || err == nil || options.ErrorClassifier == nil {
+		return ""
+	}
+	return options.ErrorClassifier(err)
+}
+
+// countFailureClass counts a failure of the given class. The caller must hold
+// m.Mutex.
+func (m *CircuitImplementation) countFailureClass(class string) {
+	if m.failureClasses == nil {
+		m.failureClasses = make(map[string]int64)
+	}
+	m.failureClasses[class]++
+}
+
+// failuresByClass returns a copy of the failure counts per class, or nil when
+// none were counted. The caller must hold m.Mutex for reading.
+func (m *CircuitImplementation) failuresByClass() map[string]int64 {
+	if len(m.failureClasses) == 0 {
+		return nil
+	}
+	counts := make(map[string]int64, len(m.failureClasses))
+	for class, count := range m.failureClasses {
+		counts[class] = count
+	}
+	return counts
+}
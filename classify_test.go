@@ -0,0 +1,86 @@
+package tripper
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUpdateStatusErr(t *testing.T) {
+	errTimeout := errors.New("timeout")
+	errRefused := errors.New("connection refused")
+	errNotFound := errors.New("not found")
+	m, err := ConfigureCircuit(CircuitOptions{
+		Name:              "TEST_UpdateStatusErr",
+		Threshold:         5,
+		ThresholdType:     ThresholdCount,
+		MinimumCount:      10,
+		IntervalInSeconds: 60,
+		Clock:             NewFakeClock(time.Unix(1700000000, 0)),
+		IsFailure: func(err error) bool {
+			return !errors.Is(err, errNotFound)
+		},
+		ErrorClassifier: func(err error) string {
+			return err.Error()
+		},
+	})
+	assert.NoError(t, err)
+	defer m.Close()
+
+	// Test case 1: Record outcomes from their errors
+	// Expected output: nil and errors rejected by IsFailure are successes, the failures are counted per class
+	m.UpdateStatusErr(nil)
+	m.UpdateStatusErr(errNotFound)
+	m.UpdateStatusErr(errTimeout)
+	m.UpdateStatusErr(errTimeout)
+	m.UpdateStatusErr(errRefused)
+	data := m.Data()
+	assert.Equal(t, int64(2), data.SuccessCount)
+	assert.Equal(t, int64(3), data.FailureCount)
+	assert.Equal(t, map[string]int64{"timeout": 2, "connection refused": 1}, data.FailuresByClass)
+
+	// Test case 2: Record failures through Execute
+	// Expected output: They are classified too
+	assert.Equal(t, errRefused, m.Execute(func() error { return errRefused }))
+	assert.Equal(t, int64(2), m.Data().FailuresByClass["connection refused"])
+
+	// Test case 3: Change the map returned by Data
+	// Expected output: The counts of the circuit are not affected
+	data.FailuresByClass["timeout"] = 100
+	assert.Equal(t, int64(2), m.Data().FailuresByClass["timeout"])
+
+	// Test case 4: Save and restore the state
+	// Expected output: The counts per class are restored
+	state, err := m.MarshalState()
+	assert.NoError(t, err)
+	m.Reset()
+	assert.Nil(t, m.Data().FailuresByClass)
+	assert.NoError(t, m.RestoreState(state))
+	assert.Equal(t, map[string]int64{"timeout": 2, "connection refused": 2}, m.Data().FailuresByClass)
+
+	// Test case 5: Let the interval reset
+	// Expected output: The counts per class are cleared with the other counts
+	m.GetOptions().Clock.(*FakeClock).Advance(60 * time.Second)
+	assert.Nil(t, m.Data().FailuresByClass)
+}
+
+func TestUpdateStatusErrWithoutClassifier(t *testing.T) {
+	m, err := ConfigureCircuit(CircuitOptions{
+		Name:              "TEST_UpdateStatusErrWithoutClassifier",
+		Threshold:         2,
+		ThresholdType:     ThresholdConsecutive,
+		IntervalInSeconds: 60,
+		Clock:             NewFakeClock(time.Unix(1700000000, 0)),
+	})
+	assert.NoError(t, err)
+	defer m.Close()
+
+	// Test case 1: Record failures without ErrorClassifier
+	// Expected output: Any error is a failure and nothing is counted per class
+	m.UpdateStatusErr(errors.New("failed"))
+	m.UpdateStatusErr(errors.New("failed"))
+	assert.True(t, m.IsCircuitOpen())
+	assert.Nil(t, m.Data().FailuresByClass)
+}
@@ -0,0 +1,184 @@
+package tripper
+
+import (
+	"sync"
+	"time"
+)
+
+// Clock is the time source used by a circuit. It returns the current time and
+// creates the tickers that drive the interval reset.
+type Clock interface {
+	Now() int64                                  // Current time in Unix seconds
+	NewTicker(d time.Duration, fn func()) Ticker // Calls fn every d until stopped
+}
+
+// PreciseClock is implemented by clocks that also provide the current time
+// with sub-second precision. Circuits take all their timestamps from it, so
+// that open durations, interval resets, OpenedAt and the time.Time fields of
+// CircuitData have sub-second precision; with other clocks they are whole
+// seconds.
+type PreciseClock interface {
+	NowTime() time.Time
+}
+
+// Ticker is a periodic timer created by a Clock.
+type Ticker interface {
+	Stop()
+	Reset(d time.Duration)
+}
+
+// realClock is the default Clock backed by the time package.
+type realClock struct{}
+
+func (realClock) Now() int64 {
+	return time.Now().Unix()
+}
+
+func (realClock) NowTime() time.Time {
+	return time.Now()
+}
+
+func (realClock) NewTicker(d time.Duration, fn func()) Ticker {
+	t := &realTicker{
+		ticker: time.NewTicker(d),
+		done:   make(chan struct{}),
+	}
+	go func() {
+		for {
+			select {
+			case <-t.ticker.C:
+				fn()
+			case <-t.done:
+				return
+			}
+		}
+	}()
+	return t
+}
+
+// realTicker wraps a time.Ticker and the goroutine that calls the tick function.
+type realTicker struct {
+	ticker *time.Ticker
+	done   chan struct{}
+	once   sync.Once
+}
+
+func (t *realTicker) Stop() {
+	t.ticker.Stop()
+	t.once.Do(func() {
+		close(t.done)
+	})
+}
+
+func (t *realTicker) Reset(d time.Duration) {
+	t.ticker.Reset(d)
+}
+
+// FakeClock is a Clock whose time only moves when Advance is called. Tick
+// functions are run synchronously from Advance, which makes interval based
+// behavior deterministic in tests.
+type FakeClock struct {
+	mutex   sync.Mutex
+	now     time.Time
+	tickers []*fakeTicker
+}
+
+// NewFakeClock creates a FakeClock starting at the given time.
+func NewFakeClock(start time.Time) *FakeClock {
+	return &FakeClock{now: start}
+}
+
+// Now returns the current fake time in Unix seconds.
+func (c *FakeClock) Now() int64 {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.now.Unix()
+}
+
+// NowTime returns the current fake time.
+func (c *FakeClock) NowTime() time.Time {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.now
+}
+
+// NewTicker creates a ticker that fires when the fake time is advanced past its deadline.
+func (c *FakeClock) NewTicker(d time.Duration, fn func()) Ticker {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	t := &fakeTicker{
+		clock:  c,
+		period: d,
+		next:   c.now.Add(d),
+		fn:     fn,
+	}
+	c.tickers = append(c.tickers, t)
+	return t
+}
+
+// Advance moves the fake time forward by d, firing every tick that falls
+// within the elapsed period in chronological order.
+func (c *FakeClock) Advance(d time.Duration) {
+	c.mutex.Lock()
+	target := c.now.Add(d)
+	c.mutex.Unlock()
+
+	for {
+		c.mutex.Lock()
+		var due *fakeTicker
+		for _, t := range c.tickers {
+			if !t.next.After(target) && (due == nil || t.next.Before(due.next)) {
+				due = t
+			}
+		}
+		if due == nil {
+			c.now = target
+			c.mutex.Unlock()
+			return
+		}
+		c.now = due.next
+		due.next = due.next.Add(due.period)
+		fn := due.fn
+		c.mutex.Unlock()
+		fn()
+	}
+}
+
+// ActiveTickers returns the number of tickers that have not been stopped.
+func (c *FakeClock) ActiveTickers() int {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return len(c.tickers)
+}
+
+// fakeTicker is a Ticker registered with a FakeClock.
+type fakeTicker struct {
+	clock  *FakeClock
+	period time.Duration
+	next   time.Time
+	fn     func()
+}
+
+func (t *fakeTicker) Stop() {
+	t.clock.mutex.Lock()
+	defer t.clock.mutex.Unlock()
+	for i, x := range t.clock.tickers {
+		if x == t {
+			t.clock.tickers = append(t.clock.tickers[:i], t.clock.tickers[i+1:]...)
+			return
+		}
+	}
+}
+
+func (t *fakeTicker) Reset(d time.Duration) {
+	t.clock.mutex.Lock()
+	defer t.clock.mutex.Unlock()
+	t.period = d
+	t.next = t.clock.now.Add(d)
+	for _, x := range t.clock.tickers {
+		if x == t {
+			return
+		}
+	}
+	t.clock.tickers = append(t.clock.tickers, t)
+}
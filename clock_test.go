@@ -0,0 +1,54 @@
+package tripper
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFakeClock(t *testing.T) {
+	// Test case 1: Now only moves when advanced
+	// Expected output: Start time, then start time plus the advanced duration
+	clock := NewFakeClock(time.Unix(1000, 0))
+	assert.Equal(t, int64(1000), clock.Now())
+	clock.Advance(90 * time.Second)
+	assert.Equal(t, int64(1090), clock.Now())
+
+	// Test case 2: Tickers fire once per elapsed period
+	// Expected output: Three ticks after advancing three and a half periods
+	ticks := 0
+	ticker := clock.NewTicker(10*time.Second, func() {
+		ticks++
+	})
+	clock.Advance(35 * time.Second)
+	assert.Equal(t, 3, ticks)
+	assert.Equal(t, 1, clock.ActiveTickers())
+
+	// Test case 3: Reset restarts the period from the current time
+	// Expected output: No tick until the new period has elapsed
+	ticker.Reset(20 * time.Second)
+	clock.Advance(19 * time.Second)
+	assert.Equal(t, 3, ticks)
+	clock.Advance(time.Second)
+	assert.Equal(t, 4, ticks)
+
+	// Test case 4: Stopped tickers no longer fire
+	// Expected output: Tick count unchanged
+	ticker.Stop()
+	clock.Advance(time.Minute)
+	assert.Equal(t, 4, ticks)
+	assert.Equal(t, 0, clock.ActiveTickers())
+}
+
+func TestFakeClockTickSeesCurrentTime(t *testing.T) {
+	// The tick function observes the time at which it was due, not the advance target
+	clock := NewFakeClock(time.Unix(0, 0))
+	var seen []int64
+	clock.NewTicker(5*time.Second, func() {
+		seen = append(seen, clock.Now())
+	})
+	clock.Advance(16 * time.Second)
+	assert.Equal(t, []int64{5, 10, 15}, seen)
+	assert.Equal(t, int64(16), clock.Now())
+}
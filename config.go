@@ -0,0 +1,129 @@
+package tripper
+
+import (
+	"bytes"
+	"encoding/json"
+	"time"
+)
+
+// CircuitConfig is the part of CircuitOptions that can be read from a JSON or
+// YAML configuration file. Callbacks, the Clock, Rand and the Logger cannot
+// come from a file; pass them as options to FromConfig or set them on the
+// result of Options before calling ConfigureCircuit.
+type CircuitConfig struct {
+	Name                         string                `json:"name" yaml:"name"`
+	ThresholdType                string                `json:"threshold_type" yaml:"threshold_type"`
+	Threshold                    float32               `json:"threshold" yaml:"threshold"`
+	CloseThreshold               float32               `json:"close_threshold,omitempty" yaml:"close_threshold,omitempty"`
+	Thresholds                   []ThresholdRuleConfig `json:"thresholds,omitempty" yaml:"thresholds,omitempty"`
+	ThresholdsOperator           string                `json:"thresholds_operator,omitempty" yaml:"thresholds_operator,omitempty"`
+	PercentageRounding           string                `json:"percentage_rounding,omitempty" yaml:"percentage_rounding,omitempty"`
+	MinimumCount                 int64                 `json:"minimum_count" yaml:"minimum_count"`
+	MinimumFailures              int64                 `json:"minimum_failures,omitempty" yaml:"minimum_failures,omitempty"`
+	HalfLifeSeconds              float64               `json:"half_life_seconds,omitempty" yaml:"half_life_seconds,omitempty"`
+	MinRequestsPerSecond         float64               `json:"min_requests_per_second,omitempty" yaml:"min_requests_per_second,omitempty"`
+	RequireMinimumCountPerBucket bool                  `json:"require_minimum_count_per_bucket,omitempty" yaml:"require_minimum_count_per_bucket,omitempty"`
+	RecordHistory                int                   `json:"record_history,omitempty" yaml:"record_history,omitempty"`
+	IntervalSeconds              int                   `json:"interval_seconds" yaml:"interval_seconds"`
+	OpenDurationSeconds          int                   `json:"open_duration_seconds,omitempty" yaml:"open_duration_seconds,omitempty"`
+	StickyOpen                   bool                  `json:"sticky_open,omitempty" yaml:"sticky_open,omitempty"`
+	WarmupSeconds                int                   `json:"warmup_seconds,omitempty" yaml:"warmup_seconds,omitempty"`
+	RequireFullWindow            bool                  `json:"require_full_window,omitempty" yaml:"require_full_window,omitempty"`
+	StaleAfterSeconds            int                   `json:"stale_after_seconds,omitempty" yaml:"stale_after_seconds,omitempty"`
+	MaxOpenSeconds               int                   `json:"max_open_seconds,omitempty" yaml:"max_open_seconds,omitempty"`
+	BackoffMultiplier            float64               `json:"backoff_multiplier,omitempty" yaml:"backoff_multiplier,omitempty"`
+	MaxOpenDurationSeconds       int                   `json:"max_open_duration_seconds,omitempty" yaml:"max_open_duration_seconds,omitempty"`
+	CooldownJitter               float64               `json:"cooldown_jitter,omitempty" yaml:"cooldown_jitter,omitempty"`
+	HalfOpenMaxProbes            int64                 `json:"half_open_max_probes,omitempty" yaml:"half_open_max_probes,omitempty"`
+	CloseConsecutiveCount        int64                 `json:"close_consecutive_count,omitempty" yaml:"close_consecutive_count,omitempty"`
+	AllowedInterleavedSuccesses  int64                 `json:"allowed_interleaved_successes,omitempty" yaml:"allowed_interleaved_successes,omitempty"`
+	SlowCallThresholdMillis      int64                 `json:"slow_call_threshold_ms,omitempty" yaml:"slow_call_threshold_ms,omitempty"`
+	SlowCallRateThreshold        float32               `json:"slow_call_rate_threshold,omitempty" yaml:"slow_call_rate_threshold,omitempty"`
+	BadCallRateThreshold         float32               `json:"bad_call_rate_threshold,omitempty" yaml:"bad_call_rate_threshold,omitempty"`
+	FailureStatusCodes           []int                 `json:"failure_status_codes,omitempty" yaml:"failure_status_codes,omitempty"`
+	CountContextErrorsAsFailure  bool                  `json:"count_context_errors_as_failure,omitempty" yaml:"count_context_errors_as_failure,omitempty"`
+	RecoverPanics                bool                  `json:"recover_panics,omitempty" yaml:"recover_panics,omitempty"`
+	ProbeOnceKeepsOpen           bool                  `json:"probe_once_keeps_open,omitempty" yaml:"probe_once_keeps_open,omitempty"`
+	InvertPolarity               bool                  `json:"invert_polarity,omitempty" yaml:"invert_polarity,omitempty"`
+	MaxConcurrent                int64                 `json:"max_concurrent,omitempty" yaml:"max_concurrent,omitempty"`
+	MinCallbackIntervalSeconds   int                   `json:"min_callback_interval_seconds,omitempty" yaml:"min_callback_interval_seconds,omitempty"`
+	AsyncCallbacks               bool                  `json:"async_callbacks,omitempty" yaml:"async_callbacks,omitempty"`
+	ManualReset                  bool                  `json:"manual_reset,omitempty" yaml:"manual_reset,omitempty"`
+	AlignToWallClock             bool                  `json:"align_to_wall_clock,omitempty" yaml:"align_to_wall_clock,omitempty"`
+}
+
+// ThresholdRuleConfig is a ThresholdRule read from a configuration file.
+type ThresholdRuleConfig struct {
+	ThresholdType  string  `json:"threshold_type" yaml:"threshold_type"`
+	Threshold      float32 `json:"threshold" yaml:"threshold"`
+	CloseThreshold float32 `json:"close_threshold,omitempty" yaml:"close_threshold,omitempty"`
+}
+
+// Options converts the configuration to CircuitOptions. The options are not
+// validated until they are passed to ConfigureCircuit.
+func (c CircuitConfig) Options() CircuitOptions {
+	var rules []ThresholdRule
+	for _, rule := range c.Thresholds {
+		rules = append(rules, ThresholdRule{ThresholdType: rule.ThresholdType, Threshold: rule.Threshold, CloseThreshold: rule.CloseThreshold})
+	}
+	return CircuitOptions{
+		Name:                         c.Name,
+		ThresholdType:                c.ThresholdType,
+		Threshold:                    c.Threshold,
+		CloseThreshold:               c.CloseThreshold,
+		Thresholds:                   rules,
+		ThresholdsOperator:           c.ThresholdsOperator,
+		PercentageRounding:           c.PercentageRounding,
+		MinimumCount:                 c.MinimumCount,
+		MinimumFailures:              c.MinimumFailures,
+		HalfLifeSeconds:              c.HalfLifeSeconds,
+		MinRequestsPerSecond:         c.MinRequestsPerSecond,
+		RequireMinimumCountPerBucket: c.RequireMinimumCountPerBucket,
+		RecordHistory:                c.RecordHistory,
+		IntervalInSeconds:            c.IntervalSeconds,
+		OpenDurationInSeconds:        c.OpenDurationSeconds,
+		StickyOpen:                   c.StickyOpen,
+		WarmupSeconds:                c.WarmupSeconds,
+		RequireFullWindow:            c.RequireFullWindow,
+		StaleAfterSeconds:            c.StaleAfterSeconds,
+		MaxOpenSeconds:               c.MaxOpenSeconds,
+		BackoffMultiplier:            c.BackoffMultiplier,
+		MaxOpenDurationInSeconds:     c.MaxOpenDurationSeconds,
+		CooldownJitter:               c.CooldownJitter,
+		HalfOpenMaxProbes:            c.HalfOpenMaxProbes,
+		CloseConsecutiveCount:        c.CloseConsecutiveCount,
+		AllowedInterleavedSuccesses:  c.AllowedInterleavedSuccesses,
+		SlowCallThreshold:            time.Duration(c.SlowCallThresholdMillis) * time.Millisecond,
+		SlowCallRateThreshold:        c.SlowCallRateThreshold,
+		BadCallRateThreshold:         c.BadCallRateThreshold,
+		FailureStatusCodes:           c.FailureStatusCodes,
+		CountContextErrorsAsFailure:  c.CountContextErrorsAsFailure,
+		RecoverPanics:                c.RecoverPanics,
+		ProbeOnceKeepsOpen:           c.ProbeOnceKeepsOpen,
+		InvertPolarity:               c.InvertPolarity,
+		MaxConcurrent:                c.MaxConcurrent,
+		MinCallbackInterval:          time.Duration(c.MinCallbackIntervalSeconds) * time.Second,
+		AsyncCallbacks:               c.AsyncCallbacks,
+		ManualReset:                  c.ManualReset,
+		AlignToWallClock:             c.AlignToWallClock,
+	}
+}
+
+// FromConfig creates a circuit from a JSON CircuitConfig. Unknown fields are
+// rejected to catch typos. opts are applied after the configuration, e.g. to
+// set the callbacks, and the result is validated like ConfigureCircuit.
+//
+//	circuit, err := tripper.FromConfig(data, tripper.OnOpen(onOpen))
+func FromConfig(data []byte, opts ...Option) (Circuit, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	var config CircuitConfig
+	if err := decoder.Decode(&config); err != nil {
+		return nil, err
+	}
+	monitorOptions := config.Options()
+	for _, opt := range opts {
+		opt(&monitorOptions)
+	}
+	return ConfigureCircuit(monitorOptions)
+}
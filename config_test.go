@@ -0,0 +1,71 @@
+package tripper
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFromConfig(t *testing.T) {
+	data := []byte(`{
+		"name": "payments",
+		"threshold_type": "PERCENTAGE",
+		"threshold": 50,
+		"minimum_count": 4,
+		"interval_seconds": 60,
+		"open_duration_seconds": 30,
+		"slow_call_threshold_ms": 250,
+		"slow_call_rate_threshold": 80
+	}`)
+
+	// Test case 1: Unmarshal a config blob and convert it to options
+	// Expected output: The fields are mapped to CircuitOptions
+	var config CircuitConfig
+	assert.NoError(t, json.Unmarshal(data, &config))
+	options := config.Options()
+	assert.Equal(t, "payments", options.Name)
+	assert.Equal(t, ThresholdPercentage, options.ThresholdType)
+	assert.Equal(t, float32(50), options.Threshold)
+	assert.Equal(t, int64(4), options.MinimumCount)
+	assert.Equal(t, 60, options.IntervalInSeconds)
+	assert.Equal(t, 30, options.OpenDurationInSeconds)
+	assert.Equal(t, 250*time.Millisecond, options.SlowCallThreshold)
+
+	// Test case 2: Configure a circuit from the blob with a callback set programmatically
+	// Expected output: The circuit trips according to the config and calls the callback
+	opened := 0
+	circuit, err := FromConfig(data, WithClock(NewFakeClock(time.Unix(1700000000, 0))), OnOpen(func(CallbackEvent) { opened++ }))
+	assert.NoError(t, err)
+	defer circuit.Close()
+	for i := 0; i < 4; i++ {
+		circuit.UpdateStatus(i%2 == 0)
+	}
+	assert.True(t, circuit.IsCircuitOpen())
+	assert.Equal(t, 1, opened)
+
+	// Test case 3: Configure a circuit from a blob with multiple threshold rules
+	// Expected output: The rules are mapped to Thresholds
+	circuit, err = FromConfig([]byte(`{
+		"name": "search",
+		"thresholds": [{"threshold_type": "COUNT", "threshold": 5}, {"threshold_type": "CONSECUTIVE", "threshold": 3}],
+		"thresholds_operator": "OR",
+		"minimum_count": 10,
+		"interval_seconds": 60
+	}`))
+	assert.NoError(t, err)
+	defer circuit.Close()
+	assert.Equal(t, []ThresholdRule{{ThresholdType: ThresholdCount, Threshold: 5}, {ThresholdType: ThresholdConsecutive, Threshold: 3}}, circuit.GetOptions().Thresholds)
+	assert.Equal(t, OperatorOr, circuit.GetOptions().ThresholdsOperator)
+
+	// Test case 4: Configure a circuit from an invalid blob
+	// Expected output: The same validation error as ConfigureCircuit
+	_, err = FromConfig([]byte(`{"name": "x", "threshold_type": "PERCENTAGE", "threshold": 50, "minimum_count": 4, "interval_seconds": 2}`))
+	assert.EqualError(t, err, "invalid interval 2, should be at least 60")
+
+	// Test case 5: Configure a circuit from a blob with a misspelled field
+	// Expected output: Error
+	_, err = FromConfig([]byte(`{"name": "x", "treshold": 50}`))
+	assert.EqualError(t, err, `json: unknown field "treshold"`)
+}
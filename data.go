@@ -0,0 +1,124 @@
+package tripper
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// circuitDataJSON is the JSON representation of CircuitData. The field names
+// are part of the API and must not change.
+type circuitDataJSON struct {
+	State                  string           `json:"state"`
+	IsCircuitOpen          bool             `json:"is_circuit_open"`
+	SuccessCount           int64            `json:"success_count"`
+	FailureCount           int64            `json:"failure_count"`
+	SlowCallCount          int64            `json:"slow_call_count"`
+	BadCallCount           int64            `json:"bad_call_count"`
+	TotalCount             int64            `json:"total_count"`
+	FailureRate            float64          `json:"failure_rate"`
+	EWMAFailureRate        float64          `json:"ewma_failure_rate"`
+	ConsecutiveCounter     int64            `json:"consecutive_counter"`
+	WeightedSuccessCount   float64          `json:"weighted_success_count"`
+	WeightedFailureCount   float64          `json:"weighted_failure_count"`
+	CircuitOpenedSince     int64            `json:"circuit_opened_since"`
+	CircuitOpenedSinceTime string           `json:"circuit_opened_since_time,omitempty"`
+	TripCount              int64            `json:"trip_count"`
+	LastStateChangedAt     int64            `json:"last_state_changed_at"`
+	LastStateChangedAtTime string           `json:"last_state_changed_at_time,omitempty"`
+	BackoffLevel           int64            `json:"backoff_level"`
+	InFlight               int64            `json:"in_flight"`
+	ConcurrencyRejected    int64            `json:"concurrency_rejected"`
+	RejectedThisEpisode    int64            `json:"rejected_this_episode"`
+	SkippedCount           int64            `json:"skipped_count"`
+	AllowedCount           int64            `json:"allowed_count"`
+	RejectedCount          int64            `json:"rejected_count"`
+	ShedRatio              float64          `json:"shed_ratio"`
+	Threshold              float32          `json:"threshold"`
+	ThresholdType          string           `json:"threshold_type,omitempty"`
+	MinimumCount           int64            `json:"minimum_count"`
+	IntervalSeconds        int              `json:"interval_seconds"`
+	FailuresByClass        map[string]int64 `json:"failures_by_class,omitempty"`
+	LatencyCount           int64            `json:"latency_count,omitempty"`
+	LatencyMinMillis       float64          `json:"latency_min_ms,omitempty"`
+	LatencyMaxMillis       float64          `json:"latency_max_ms,omitempty"`
+	LatencyP50Millis       float64          `json:"latency_p50_ms,omitempty"`
+	LatencyP95Millis       float64          `json:"latency_p95_ms,omitempty"`
+	LatencyP99Millis       float64          `json:"latency_p99_ms,omitempty"`
+}
+
+// MarshalJSON encodes the data with stable snake_case field names, e.g. for
+// debug endpoints. Nonzero timestamps are also included as RFC 3339 times, and
+// the latency summary in milliseconds once a latency was recorded.
+func (d CircuitData) MarshalJSON() ([]byte, error) {
+	return json.Marshal(circuitDataJSON{
+		State:                  d.State.String(),
+		IsCircuitOpen:          d.IsCircuitOpen,
+		SuccessCount:           d.SuccessCount,
+		FailureCount:           d.FailureCount,
+		SlowCallCount:          d.SlowCallCount,
+		BadCallCount:           d.BadCallCount,
+		TotalCount:             d.TotalCount,
+		FailureRate:            d.FailureRate,
+		EWMAFailureRate:        d.EWMAFailureRate,
+		ConsecutiveCounter:     d.ConsecutiveCounter,
+		WeightedSuccessCount:   d.WeightedSuccessCount,
+		WeightedFailureCount:   d.WeightedFailureCount,
+		CircuitOpenedSince:     d.CircuitOpenedSince,
+		CircuitOpenedSinceTime: formatTimestamp(d.CircuitOpenedSince),
+		TripCount:              d.TripCount,
+		LastStateChangedAt:     d.LastStateChangedAt,
+		LastStateChangedAtTime: formatTimestamp(d.LastStateChangedAt),
+		BackoffLevel:           d.BackoffLevel,
+		InFlight:               d.InFlight,
+		ConcurrencyRejected:    d.ConcurrencyRejected,
+		RejectedThisEpisode:    d.RejectedThisEpisode,
+		SkippedCount:           d.SkippedCount,
+		AllowedCount:           d.AllowedCount,
+		RejectedCount:          d.RejectedCount,
+		ShedRatio:              d.ShedRatio,
+		Threshold:              d.Threshold,
+		ThresholdType:          d.ThresholdType,
+		MinimumCount:           d.MinimumCount,
+		IntervalSeconds:        d.IntervalInSeconds,
+		FailuresByClass:        d.FailuresByClass,
+		LatencyCount:           d.Latency.Count,
+		LatencyMinMillis:       millis(d.Latency.Min),
+		LatencyMaxMillis:       millis(d.Latency.Max),
+		LatencyP50Millis:       millis(d.Latency.P50),
+		LatencyP95Millis:       millis(d.Latency.P95),
+		LatencyP99Millis:       millis(d.Latency.P99),
+	})
+}
+
+// String returns a single-line summary of the data for logs.
+func (d CircuitData) String() string {
+	s := fmt.Sprintf("state=%s successes=%d failures=%d failure_rate=%.4f consecutive_failures=%d trips=%d",
+		d.State, d.SuccessCount, d.FailureCount, d.FailureRate, d.ConsecutiveCounter, d.TripCount)
+	if d.CircuitOpenedSince != 0 {
+		s += " opened_since=" + formatTimestamp(d.CircuitOpenedSince)
+	}
+	return s
+}
+
+// shedRatio returns the share of the calls that were rejected between 0 and 1.
+func shedRatio(allowed, rejected int64) float64 {
+	if allowed+rejected == 0 {
+		return 0
+	}
+	return float64(rejected) / float64(allowed+rejected)
+}
+
+// millis returns a duration in milliseconds.
+func millis(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
+// formatTimestamp formats a Unix timestamp as an RFC 3339 time in UTC, or
+// returns an empty string for 0.
+func formatTimestamp(timestamp int64) string {
+	if timestamp == 0 {
+		return ""
+	}
+	return time.Unix(timestamp, 0).UTC().Format(time.RFC3339)
+}
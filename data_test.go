@@ -0,0 +1,82 @@
+package tripper
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCircuitDataJSON(t *testing.T) {
+	// Test case 1: Marshal the data of a closed circuit
+	// Expected output: Stable field names and no formatted times
+	data, err := json.Marshal(CircuitData{State: StateClosed, SuccessCount: 3, FailureCount: 1, TotalCount: 4, FailureRate: 0.25})
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{
+		"state": "CLOSED",
+		"is_circuit_open": false,
+		"success_count": 3,
+		"failure_count": 1,
+		"slow_call_count": 0,
+		"bad_call_count": 0,
+		"total_count": 4,
+		"failure_rate": 0.25,
+		"ewma_failure_rate": 0,
+		"consecutive_counter": 0,
+		"weighted_success_count": 0,
+		"weighted_failure_count": 0,
+		"circuit_opened_since": 0,
+		"trip_count": 0,
+		"last_state_changed_at": 0,
+		"backoff_level": 0,
+		"in_flight": 0,
+		"concurrency_rejected": 0,
+		"rejected_this_episode": 0,
+		"skipped_count": 0,
+		"allowed_count": 0,
+		"rejected_count": 0,
+		"shed_ratio": 0,
+		"threshold": 0,
+		"minimum_count": 0,
+		"interval_seconds": 0
+	}`, string(data))
+
+	// Test case 2: Marshal the data of an open circuit
+	// Expected output: The timestamps are also included as RFC 3339 times
+	m, err := ConfigureCircuit(CircuitOptions{
+		Name:              "TEST_CircuitDataJSON",
+		Threshold:         2,
+		ThresholdType:     ThresholdConsecutive,
+		IntervalInSeconds: 60,
+		Clock:             NewFakeClock(time.Unix(1700000000, 0)),
+	})
+	assert.NoError(t, err)
+	defer m.Close()
+	m.UpdateStatus(false)
+	m.UpdateStatus(false)
+	data, err = json.Marshal(m.Data())
+	assert.NoError(t, err)
+	var fields map[string]interface{}
+	assert.NoError(t, json.Unmarshal(data, &fields))
+	assert.Equal(t, "OPEN", fields["state"])
+	assert.Equal(t, true, fields["is_circuit_open"])
+	assert.Equal(t, float64(1700000000), fields["circuit_opened_since"])
+	assert.Equal(t, "2023-11-14T22:13:20Z", fields["circuit_opened_since_time"])
+	assert.Equal(t, "2023-11-14T22:13:20Z", fields["last_state_changed_at_time"])
+	assert.Equal(t, float64(2), fields["threshold"])
+	assert.Equal(t, "CONSECUTIVE", fields["threshold_type"])
+	assert.Equal(t, float64(60), fields["interval_seconds"])
+}
+
+func TestCircuitDataString(t *testing.T) {
+	// Test case 1: Format the data of a closed circuit
+	// Expected output: A single line without the opening time
+	d := CircuitData{State: StateClosed, SuccessCount: 3, FailureCount: 1, FailureRate: 0.25}
+	assert.Equal(t, "state=CLOSED successes=3 failures=1 failure_rate=0.2500 consecutive_failures=0 trips=0", d.String())
+
+	// Test case 2: Format the data of an open circuit
+	// Expected output: The opening time is included
+	d = CircuitData{State: StateOpen, FailureCount: 2, FailureRate: 1, ConsecutiveCounter: 2, CircuitOpenedSince: 1700000000, TripCount: 1}
+	assert.Equal(t, "state=OPEN successes=0 failures=2 failure_rate=1.0000 consecutive_failures=2 trips=1 opened_since=2023-11-14T22:13:20Z", d.String())
+}
@@ -0,0 +1,69 @@
+package tripper
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Sentinel errors wrapped by the ConfigError returned for invalid options.
+// Use errors.Is to check which validation failed.
+var (
+	ErrInvalidThresholdType               = errors.New("invalid threshold type")
+	ErrInvalidThreshold                   = errors.New("invalid threshold value")
+	ErrInvalidThresholdStrategy           = errors.New("invalid threshold strategy")
+	ErrInvalidCloseThreshold              = errors.New("invalid close threshold")
+	ErrInvalidThresholdsOperator          = errors.New("invalid thresholds operator")
+	ErrInvalidMinimumCount                = errors.New("invalid minimum count")
+	ErrInvalidMinimumFailures             = errors.New("invalid minimum failures")
+	ErrInvalidMinRequestsPerSecond        = errors.New("invalid minimum request rate")
+	ErrInvalidHalfLife                    = errors.New("invalid half life")
+	ErrInvalidPercentageRounding          = errors.New("invalid percentage rounding")
+	ErrMinimumCountBelowThreshold         = errors.New("minimum count should be greater than threshold")
+	ErrInvalidFailureStatusCode           = errors.New("invalid failure status code")
+	ErrInvalidMaxConcurrent               = errors.New("invalid max concurrent")
+	ErrInvalidOpenDuration                = errors.New("invalid open duration")
+	ErrInvalidWarmup                      = errors.New("invalid warmup")
+	ErrInvalidStaleAfter                  = errors.New("invalid stale after")
+	ErrInvalidMaxOpen                     = errors.New("invalid max open")
+	ErrInvalidMinCallbackInterval         = errors.New("invalid min callback interval")
+	ErrInvalidSlowCallThreshold           = errors.New("invalid slow call threshold")
+	ErrInvalidSlowCallRateThreshold       = errors.New("invalid slow call rate threshold")
+	ErrInvalidBadCallRateThreshold        = errors.New("invalid bad call rate threshold")
+	ErrInvalidBackoffMultiplier           = errors.New("invalid backoff multiplier")
+	ErrInvalidMaxOpenDuration             = errors.New("invalid max open duration")
+	ErrInvalidCooldownJitter              = errors.New("invalid cooldown jitter")
+	ErrInvalidHalfOpenMaxProbes           = errors.New("invalid half open max probes")
+	ErrInvalidCloseConsecutiveCount       = errors.New("invalid close consecutive count")
+	ErrInvalidAllowedInterleavedSuccesses = errors.New("invalid allowed interleaved successes")
+	ErrInvalidRecordHistory               = errors.New("invalid record history")
+	ErrInvalidInterval                    = errors.New("invalid interval")
+	ErrNameChanged                        = errors.New("circuit name cannot be changed")
+)
+
+// ConfigError is returned by ConfigureCircuit, NewCircuit and UpdateOptions
+// when the options are invalid. It wraps one of the sentinel errors above.
+type ConfigError struct {
+	Field  string // Name of the CircuitOptions field that is invalid
+	Reason string // Human-readable description of the problem
+	err    error
+}
+
+// Error returns the reason, e.g. "invalid minimum count 0".
+func (e *ConfigError) Error() string {
+	return e.Reason
+}
+
+// Unwrap returns the sentinel error describing the kind of problem.
+func (e *ConfigError) Unwrap() error {
+	return e.err
+}
+
+// configError creates a ConfigError for field wrapping err with a reason
+// formatted from format and args.
+func configError(err error, field string, format string, args ...interface{}) error {
+	return &ConfigError{
+		Field:  field,
+		Reason: fmt.Sprintf(format, args...),
+		err:    err,
+	}
+}
@@ -0,0 +1,132 @@
+package tripper
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConfigErrors(t *testing.T) {
+	valid := CircuitOptions{
+		Name:              "TEST_ConfigErrors",
+		Threshold:         50,
+		ThresholdType:     ThresholdPercentage,
+		MinimumCount:      20,
+		IntervalInSeconds: 60,
+		Clock:             NewFakeClock(time.Unix(1700000000, 0)),
+	}
+	tests := []struct {
+		name     string
+		modify   func(o *CircuitOptions)
+		sentinel error
+		field    string
+		message  string
+	}{
+		{"threshold type", func(o *CircuitOptions) { o.ThresholdType = "INVALID" }, ErrInvalidThresholdType, "ThresholdType", "invalid threshold type INVALID"},
+		{"built-in threshold strategy", func(o *CircuitOptions) {
+			o.ThresholdStrategies = map[string]ThresholdStrategy{ThresholdCount: thresholdStrategies[ThresholdCount]}
+		}, ErrInvalidThresholdStrategy, "ThresholdStrategies", "invalid threshold strategy COUNT"},
+		{"nil threshold strategy", func(o *CircuitOptions) { o.ThresholdStrategies = map[string]ThresholdStrategy{"CUSTOM": nil} }, ErrInvalidThresholdStrategy, "ThresholdStrategies", "invalid threshold strategy CUSTOM"},
+		{"percentage threshold", func(o *CircuitOptions) { o.Threshold = 101 }, ErrInvalidThreshold, "Threshold", "invalid threshold value 101.000000 for percentage type"},
+		{"count threshold", func(o *CircuitOptions) { o.ThresholdType = ThresholdCount; o.Threshold = 0 }, ErrInvalidThreshold, "Threshold", "invalid threshold value 0.000000 for count type"},
+		{"failure count threshold", func(o *CircuitOptions) { o.ThresholdType = ThresholdFailureCount; o.Threshold = 0 }, ErrInvalidThreshold, "Threshold", "invalid threshold value 0.000000 for failure count type"},
+		{"consecutive threshold", func(o *CircuitOptions) { o.ThresholdType = ThresholdConsecutive; o.Threshold = 0 }, ErrInvalidThreshold, "Threshold", "invalid threshold value 0.000000 for consecutive type"},
+		{"negative consecutive threshold", func(o *CircuitOptions) { o.ThresholdType = ThresholdConsecutive; o.Threshold = -3 }, ErrInvalidThreshold, "Threshold", "invalid threshold value -3.000000 for consecutive type"},
+		{"fractional consecutive threshold", func(o *CircuitOptions) { o.ThresholdType = ThresholdConsecutive; o.Threshold = 2.5 }, ErrInvalidThreshold, "Threshold", "invalid threshold value 2.500000 for consecutive type"},
+		{"consecutive threshold rule", func(o *CircuitOptions) {
+			o.Thresholds = []ThresholdRule{{ThresholdType: ThresholdPercentage, Threshold: 50}, {ThresholdType: ThresholdConsecutive, Threshold: 0}}
+		}, ErrInvalidThreshold, "Threshold", "invalid threshold value 0.000000 for consecutive type"},
+		{"close threshold above threshold", func(o *CircuitOptions) { o.CloseThreshold = 60 }, ErrInvalidCloseThreshold, "CloseThreshold", "invalid close threshold 60.000000 for PERCENTAGE threshold 50.000000"},
+		{"consecutive close threshold", func(o *CircuitOptions) { o.ThresholdType = ThresholdConsecutive; o.Threshold = 3; o.CloseThreshold = 1 }, ErrInvalidCloseThreshold, "CloseThreshold", "invalid close threshold 1.000000 for CONSECUTIVE threshold 3.000000"},
+		{"thresholds operator", func(o *CircuitOptions) { o.ThresholdsOperator = "XOR" }, ErrInvalidThresholdsOperator, "ThresholdsOperator", "invalid thresholds operator XOR"},
+		{"minimum count", func(o *CircuitOptions) { o.MinimumCount = 0 }, ErrInvalidMinimumCount, "MinimumCount", "invalid minimum count 0"},
+		{"negative minimum count for consecutive", func(o *CircuitOptions) {
+			o.ThresholdType = ThresholdConsecutive
+			o.Threshold = 5
+			o.MinimumCount = -1
+		}, ErrInvalidMinimumCount, "MinimumCount", "invalid minimum count -1"},
+		{"minimum count below threshold", func(o *CircuitOptions) { o.ThresholdType = ThresholdCount; o.Threshold = 20 }, ErrMinimumCountBelowThreshold, "MinimumCount", "minimum count should be greater than threshold"},
+		{"minimum failures", func(o *CircuitOptions) { o.MinimumFailures = -1 }, ErrInvalidMinimumFailures, "MinimumFailures", "invalid minimum failures -1"},
+		{"ewma threshold", func(o *CircuitOptions) { o.ThresholdType = ThresholdEWMA; o.Threshold = 101; o.HalfLifeSeconds = 10 }, ErrInvalidThreshold, "Threshold", "invalid threshold value 101.000000 for ewma type"},
+		{"ewma without half life", func(o *CircuitOptions) { o.ThresholdType = ThresholdEWMA }, ErrInvalidHalfLife, "HalfLifeSeconds", "invalid half life 0.000000"},
+		{"negative half life", func(o *CircuitOptions) { o.HalfLifeSeconds = -1 }, ErrInvalidHalfLife, "HalfLifeSeconds", "invalid half life -1.000000"},
+		{"minimum request rate", func(o *CircuitOptions) { o.MinRequestsPerSecond = -1 }, ErrInvalidMinRequestsPerSecond, "MinRequestsPerSecond", "invalid minimum request rate -1.000000"},
+		{"percentage rounding", func(o *CircuitOptions) { o.PercentageRounding = "UP" }, ErrInvalidPercentageRounding, "PercentageRounding", "invalid percentage rounding UP"},
+		{"failure status code", func(o *CircuitOptions) { o.FailureStatusCodes = []int{429, 42} }, ErrInvalidFailureStatusCode, "FailureStatusCodes", "invalid failure status code 42"},
+		{"max concurrent", func(o *CircuitOptions) { o.MaxConcurrent = -1 }, ErrInvalidMaxConcurrent, "MaxConcurrent", "invalid max concurrent -1"},
+		{"open duration", func(o *CircuitOptions) { o.OpenDurationInSeconds = -1 }, ErrInvalidOpenDuration, "OpenDurationInSeconds", "invalid open duration -1"},
+		{"warmup", func(o *CircuitOptions) { o.WarmupSeconds = -1 }, ErrInvalidWarmup, "WarmupSeconds", "invalid warmup -1"},
+		{"stale after", func(o *CircuitOptions) { o.StaleAfterSeconds = -1 }, ErrInvalidStaleAfter, "StaleAfterSeconds", "invalid stale after -1"},
+		{"max open", func(o *CircuitOptions) { o.MaxOpenSeconds = -1 }, ErrInvalidMaxOpen, "MaxOpenSeconds", "invalid max open -1"},
+		{"min callback interval", func(o *CircuitOptions) { o.MinCallbackInterval = -time.Second }, ErrInvalidMinCallbackInterval, "MinCallbackInterval", "invalid min callback interval -1s"},
+		{"slow call threshold", func(o *CircuitOptions) { o.SlowCallThreshold = -time.Second }, ErrInvalidSlowCallThreshold, "SlowCallThreshold", "invalid slow call threshold -1s"},
+		{"slow call rate threshold", func(o *CircuitOptions) { o.SlowCallThreshold = time.Second }, ErrInvalidSlowCallRateThreshold, "SlowCallRateThreshold", "invalid slow call rate threshold 0.000000"},
+		{"bad call rate threshold", func(o *CircuitOptions) { o.BadCallRateThreshold = 50 }, ErrInvalidBadCallRateThreshold, "BadCallRateThreshold", "invalid bad call rate threshold 50.000000"},
+		{"allowed interleaved successes", func(o *CircuitOptions) { o.AllowedInterleavedSuccesses = -1 }, ErrInvalidAllowedInterleavedSuccesses, "AllowedInterleavedSuccesses", "invalid allowed interleaved successes -1"},
+		{"record history", func(o *CircuitOptions) { o.RecordHistory = -1 }, ErrInvalidRecordHistory, "RecordHistory", "invalid record history -1"},
+		{"close consecutive count", func(o *CircuitOptions) { o.CloseConsecutiveCount = -1 }, ErrInvalidCloseConsecutiveCount, "CloseConsecutiveCount", "invalid close consecutive count -1"},
+		{"interval", func(o *CircuitOptions) { o.IntervalInSeconds = 2 }, ErrInvalidInterval, "IntervalInSeconds", "invalid interval 2, should be at least 60"},
+		{"zero interval", func(o *CircuitOptions) { o.IntervalInSeconds = 0 }, ErrInvalidInterval, "IntervalInSeconds", "invalid interval 0, should be at least 60"},
+		{"interval below minimum", func(o *CircuitOptions) { o.IntervalInSeconds = MinIntervalInSeconds - 1 }, ErrInvalidInterval, "IntervalInSeconds", "invalid interval 59, should be at least 60"},
+		{"negative interval", func(o *CircuitOptions) { o.IntervalInSeconds = -60 }, ErrInvalidInterval, "IntervalInSeconds", "invalid interval -60, should be at least 60"},
+		{"interval multiple", func(o *CircuitOptions) { o.IntervalInSeconds = 90 }, ErrInvalidInterval, "IntervalInSeconds", "invalid interval 90, should be a multiple of 60"},
+	}
+
+	// Test case 1: Configure a circuit with each kind of invalid option
+	// Expected output: A ConfigError matching the sentinel with the same message as before
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			o := valid
+			tt.modify(&o)
+			_, err := ConfigureCircuit(o)
+			assert.EqualError(t, err, tt.message)
+			assert.True(t, errors.Is(err, tt.sentinel))
+			var configErr *ConfigError
+			assert.True(t, errors.As(err, &configErr))
+			assert.Equal(t, tt.field, configErr.Field)
+			assert.Equal(t, tt.message, configErr.Reason)
+		})
+	}
+
+	// Test case 2: Validate each kind of invalid option without configuring a circuit
+	// Expected output: The same ConfigError as ConfigureCircuit, and no ticker is started
+	for _, tt := range tests {
+		t.Run("Validate "+tt.name, func(t *testing.T) {
+			clock := NewFakeClock(time.Unix(1700000000, 0))
+			o := valid
+			o.Clock = clock
+			tt.modify(&o)
+			err := o.Validate()
+			assert.EqualError(t, err, tt.message)
+			assert.True(t, errors.Is(err, tt.sentinel))
+			var configErr *ConfigError
+			assert.True(t, errors.As(err, &configErr))
+			assert.Equal(t, tt.field, configErr.Field)
+			assert.Equal(t, 0, clock.ActiveTickers())
+		})
+	}
+	assert.NoError(t, valid.Validate())
+	for _, interval := range []int{MinIntervalInSeconds, 2 * MinIntervalInSeconds, 10 * MinIntervalInSeconds} {
+		o := valid
+		o.IntervalInSeconds = interval
+		assert.NoError(t, o.Validate())
+	}
+
+	// Test case 3: Change the name of a circuit with UpdateOptions
+	// Expected output: A ConfigError matching ErrNameChanged
+	m, err := ConfigureCircuit(valid)
+	assert.NoError(t, err)
+	defer m.Close()
+	o := valid
+	o.Name = "renamed"
+	err = m.UpdateOptions(o)
+	assert.True(t, errors.Is(err, ErrNameChanged))
+	assert.EqualError(t, err, "circuit name cannot be changed from TEST_ConfigErrors to renamed")
+
+	// Test case 4: A sentinel does not match other errors
+	// Expected output: errors.Is is false
+	_, err = ConfigureCircuit(CircuitOptions{Name: "x", ThresholdType: ThresholdPercentage, Threshold: 50, MinimumCount: 0, IntervalInSeconds: 60})
+	assert.False(t, errors.Is(err, ErrInvalidInterval))
+}
@@ -0,0 +1,46 @@
+package tripper
+
+import (
+	"math"
+	"time"
+)
+
+// recordEWMA adds events to the exponentially weighted failure rate used by
+// ThresholdEWMA. The weighted sums of failures and of all events decay by half
+// every HalfLifeSeconds, so the rate follows the recent events without the
+// hard boundaries of the interval, while events recorded at the same time all
+// count. The caller must hold m.Mutex.
+func (m *CircuitImplementation) recordEWMA(successes, failures float64, now time.Time) {
+	if m.Options.HalfLifeSeconds <= 0 {
+		return
+	}
+	if !m.ewmaUpdatedAt.IsZero() {
+		if elapsed := now.Sub(m.ewmaUpdatedAt).Seconds(); elapsed > 0 {
+			decay := math.Pow(2, -elapsed/m.Options.HalfLifeSeconds)
+			m.ewmaFailures *= decay
+			m.ewmaTotal *= decay
+		}
+	}
+	m.ewmaUpdatedAt = now
+	m.ewmaFailures += failures
+	m.ewmaTotal += successes + failures
+}
+
+// ewmaFailureRate returns the exponentially weighted failure rate between 0
+// and 1. Both sums decay alike, so the rate does not change between events.
+// The caller must hold m.Mutex for reading.
+func (m *CircuitImplementation) ewmaFailureRate() float64 {
+	if m.ewmaTotal == 0 {
+		return 0
+	}
+	return m.ewmaFailures / m.ewmaTotal
+}
+
+// clearEWMA forgets the exponentially weighted failure rate once the circuit
+// recovers, as no events are recorded while it is open to bring the rate
+// down. The caller must hold m.Mutex.
+func (m *CircuitImplementation) clearEWMA() {
+	m.ewmaFailures = 0
+	m.ewmaTotal = 0
+	m.ewmaUpdatedAt = time.Time{}
+}
@@ -0,0 +1,93 @@
+package tripper
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEWMA(t *testing.T) {
+	clock := NewFakeClock(time.Unix(1700000000, 0))
+	m, err := ConfigureCircuit(CircuitOptions{
+		Name:              "TEST_EWMA",
+		Threshold:         50,
+		ThresholdType:     ThresholdEWMA,
+		HalfLifeSeconds:   10,
+		MinimumCount:      1,
+		IntervalInSeconds: 300,
+		Clock:             clock,
+	})
+	assert.NoError(t, err)
+	defer m.Close()
+
+	// Test case 1: Record a success every second
+	// Expected output: The EWMA failure rate stays at 0
+	for i := 0; i < 100; i++ {
+		clock.Advance(time.Second)
+		m.UpdateStatus(true)
+	}
+	assert.Equal(t, float64(0), m.Data().EWMAFailureRate)
+
+	// Test case 2: Record a short burst of failures
+	// Expected output: The EWMA failure rate rises to about 19% and the circuit stays closed
+	for i := 0; i < 3; i++ {
+		clock.Advance(time.Second)
+		m.UpdateStatus(false)
+	}
+	assert.InDelta(t, 0.19, m.Data().EWMAFailureRate, 0.01)
+	assert.False(t, m.IsCircuitOpen())
+
+	// Test case 3: Record successes until the burst has decayed
+	// Expected output: The EWMA failure rate drops below 1%
+	for i := 0; i < 70; i++ {
+		clock.Advance(time.Second)
+		m.UpdateStatus(true)
+	}
+	assert.Less(t, m.Data().EWMAFailureRate, 0.01)
+
+	// Test case 4: Switch to failures only
+	// Expected output: The circuit opens after about one half life, i.e. 10 samples
+	samples := 0
+	for !m.IsCircuitOpen() && samples < 100 {
+		clock.Advance(time.Second)
+		m.UpdateStatus(false)
+		samples++
+	}
+	assert.True(t, m.IsCircuitOpen())
+	assert.True(t, samples >= 9 && samples <= 11, "opened after %d samples", samples)
+	// The plain failure percentage of the interval is still below the threshold
+	assert.Less(t, m.Data().FailureRate, 0.5)
+}
+
+func TestEWMAClose(t *testing.T) {
+	clock := NewFakeClock(time.Unix(1700000000, 0))
+	m, err := ConfigureCircuit(CircuitOptions{
+		Name:              "TEST_EWMAClose",
+		Threshold:         50,
+		ThresholdType:     ThresholdEWMA,
+		HalfLifeSeconds:   10,
+		MinimumCount:      1,
+		IntervalInSeconds: 60,
+		Clock:             clock,
+	})
+	assert.NoError(t, err)
+	defer m.Close()
+
+	// Test case 1: Open the circuit and let the interval reset
+	// Expected output: The circuit closes and the EWMA failure rate is cleared
+	m.UpdateStatus(false)
+	assert.True(t, m.IsCircuitOpen())
+	clock.Advance(60 * time.Second)
+	assert.False(t, m.IsCircuitOpen())
+	assert.Equal(t, float64(0), m.Data().EWMAFailureRate)
+
+	// Test case 2: Record events in a closed circuit across an interval reset
+	// Expected output: The EWMA failure rate carries over the reset
+	m.UpdateStatus(true)
+	m.UpdateStatus(true)
+	m.UpdateStatus(false)
+	clock.Advance(60 * time.Second)
+	assert.InDelta(t, 1.0/3, m.Data().EWMAFailureRate, 0.001)
+	assert.Equal(t, int64(0), m.Data().FailureCount)
+}
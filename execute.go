@@ -0,0 +1,125 @@
+package tripper
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// ErrCircuitOpen is returned by Execute and ExecuteContext when the circuit is
+// open, or half-open with all of its probes already admitted.
+var ErrCircuitOpen = errors.New("circuit is open")
+
+// ErrPanic is wrapped by the error returned by Execute and ExecuteContext with
+// RecoverPanics when the function panics.
+var ErrPanic = errors.New("function panicked")
+
+// Execute runs fn if the circuit is closed and records its outcome. It
+// returns ErrCircuitOpen without calling fn when the circuit is open. A
+// half-open circuit only runs fn for up to HalfOpenMaxProbes probe calls. With
+// MaxConcurrent, it returns ErrTooManyRequests without calling fn or recording
+// a failure when that many calls are already running.
+func (m *CircuitImplementation) Execute(fn func() error) error {
+	return m.ExecuteContext(context.Background(), func(context.Context) error {
+		return fn()
+	})
+}
+
+// ExecuteContext runs fn with ctx if the context is still active and the
+// circuit is closed, and records its outcome. Errors rejected by the IsFailure
+// classifier are recorded as successes. Errors caused by ctx being
+// cancelled or exceeding its deadline are only recorded as failures when
+// CountContextErrorsAsFailure is set. A panic in fn is recorded as a failure
+// and then raised again, or returned as an error wrapping ErrPanic with
+// RecoverPanics.
+func (m *CircuitImplementation) ExecuteContext(ctx context.Context, fn func(context.Context) error) error {
+	if m == nil {
+		return noopCircuit{}.ExecuteContext(ctx, fn)
+	}
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if !m.acquireSlot() {
+		return ErrTooManyRequests
+	}
+	defer m.releaseSlot()
+	allowed, probe := m.allowRequest()
+	if !allowed {
+		return ErrCircuitOpen
+	}
+
+	start := m.nowTime()
+	recovered, panicked, err := callRecovering(ctx, fn)
+
+	m.Mutex.RLock()
+	options := m.Options
+	m.Mutex.RUnlock()
+	if panicked {
+		err = fmt.Errorf("%w: %v", ErrPanic, recovered)
+		m.updateStatus(false, m.nowTime().Sub(start), weightScale, classifyError(options, err, true))
+		if !options.RecoverPanics {
+			panic(recovered)
+		}
+		return err
+	}
+	if err != nil && isContextError(err) && !options.CountContextErrorsAsFailure {
+		m.releaseProbe(probe)
+		return err
+	}
+	failure := isFailure(options, err)
+	m.updateStatus(!failure, m.nowTime().Sub(start), weightScale, classifyError(options, err, failure))
+	return err
+}
+
+// ProbeOnce runs fn exactly once whatever the state of the circuit, e.g. for a
+// scripted recovery check while it is open, and records its outcome like
+// Execute. It returns whether fn succeeded together with the error of fn. A
+// successful probe closes the circuit with Reset before its success is
+// recorded, unless ProbeOnceKeepsOpen is set.
+func (m *CircuitImplementation) ProbeOnce(fn func() error) (bool, error) {
+	if m == nil {
+		return noopCircuit{}.ProbeOnce(fn)
+	}
+
+	start := m.nowTime()
+	err := fn()
+
+	m.Mutex.RLock()
+	options := m.Options
+	m.Mutex.RUnlock()
+	failure := isFailure(options, err)
+	if !failure && !options.ProbeOnceKeepsOpen {
+		m.Reset()
+	}
+	m.updateStatus(!failure, m.nowTime().Sub(start), weightScale, classifyError(options, err, failure))
+	return !failure, err
+}
+
+// callRecovering calls fn with ctx and recovers from a panic in it, returning
+// the recovered value instead.
+func callRecovering(ctx context.Context, fn func(context.Context) error) (recovered interface{}, panicked bool, err error) {
+	defer func() {
+		if recovered = recover(); recovered != nil {
+			panicked = true
+		}
+	}()
+	return nil, false, fn(ctx)
+}
+
+// isFailure reports whether err should be recorded as a failure, using the
+// IsFailure classifier when one is configured.
+func isFailure(options CircuitOptions, err error) bool {
+	if err == nil {
+		return false
+	}
+	if options.IsFailure != nil {
+		return options.IsFailure(err)
+	}
+	return true
+}
+
+// isContextError reports whether err was caused by a cancelled or expired context.
+func isContextError(err error) bool {
+	return errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded)
+}
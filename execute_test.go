@@ -0,0 +1,434 @@
+package tripper
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var errService = errors.New("service unavailable")
+
+func newExecuteCircuit(t *testing.T, countContextErrors bool) Circuit {
+	m, err := ConfigureCircuit(CircuitOptions{
+		Name:                        "TEST_Execute",
+		Threshold:                   2,
+		MinimumCount:                3,
+		IntervalInSeconds:           60,
+		ThresholdType:               ThresholdCount,
+		CountContextErrorsAsFailure: countContextErrors,
+	})
+	assert.NoError(t, err)
+	return m
+}
+
+func TestExecute(t *testing.T) {
+	m := newExecuteCircuit(t, false)
+
+	// Test case 1: Successful and failing calls are recorded
+	// Expected output: fn errors are returned and counted
+	assert.NoError(t, m.Execute(func() error { return nil }))
+	assert.Equal(t, errService, m.Execute(func() error { return errService }))
+	assert.Equal(t, errService, m.Execute(func() error { return errService }))
+	assert.Equal(t, int64(1), m.Data().SuccessCount)
+	assert.Equal(t, int64(2), m.Data().FailureCount)
+	assert.True(t, m.IsCircuitOpen())
+
+	// Test case 2: An open circuit short-circuits without calling fn
+	// Expected output: ErrCircuitOpen and nothing recorded
+	called := false
+	err := m.Execute(func() error {
+		called = true
+		return nil
+	})
+	assert.Equal(t, ErrCircuitOpen, err)
+	assert.False(t, called)
+	assert.Equal(t, int64(3), m.Data().SuccessCount+m.Data().FailureCount)
+}
+
+func TestExecuteContext(t *testing.T) {
+	// Test case 1: An already cancelled context does not run fn
+	// Expected output: The context error and nothing recorded
+	m := newExecuteCircuit(t, true)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	called := false
+	err := m.ExecuteContext(ctx, func(context.Context) error {
+		called = true
+		return nil
+	})
+	assert.Equal(t, context.Canceled, err)
+	assert.False(t, called)
+	assert.Equal(t, int64(0), m.Data().FailureCount)
+
+	// Test case 2: The context is passed through to fn
+	// Expected output: fn observes the deadline
+	ctx, cancel = context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	assert.NoError(t, m.ExecuteContext(ctx, func(fnCtx context.Context) error {
+		_, ok := fnCtx.Deadline()
+		assert.True(t, ok)
+		return nil
+	}))
+	assert.Equal(t, int64(1), m.Data().SuccessCount)
+}
+
+func TestExecuteContextCancellationCounts(t *testing.T) {
+	m := newExecuteCircuit(t, true)
+
+	// Cancellation errors returned by fn are recorded as failures
+	for i := 0; i < 3; i++ {
+		err := m.ExecuteContext(context.Background(), func(context.Context) error {
+			return context.DeadlineExceeded
+		})
+		assert.Equal(t, context.DeadlineExceeded, err)
+	}
+	assert.Equal(t, int64(3), m.Data().FailureCount)
+	assert.True(t, m.IsCircuitOpen())
+}
+
+func TestExecuteContextCancellationIgnored(t *testing.T) {
+	m := newExecuteCircuit(t, false)
+
+	// Cancellation errors returned by fn, including wrapped ones, are not recorded
+	for i := 0; i < 3; i++ {
+		ctx, cancel := context.WithCancel(context.Background())
+		err := m.ExecuteContext(ctx, func(fnCtx context.Context) error {
+			cancel()
+			return fmt.Errorf("request aborted: %w", fnCtx.Err())
+		})
+		assert.True(t, errors.Is(err, context.Canceled))
+	}
+	assert.Equal(t, int64(0), m.Data().FailureCount)
+	assert.False(t, m.IsCircuitOpen())
+
+	// Other errors are still recorded as failures
+	for i := 0; i < 3; i++ {
+		_ = m.ExecuteContext(context.Background(), func(context.Context) error {
+			return errService
+		})
+	}
+	assert.Equal(t, int64(3), m.Data().FailureCount)
+	assert.True(t, m.IsCircuitOpen())
+}
+
+func TestExecuteIsFailure(t *testing.T) {
+	errNotFound := errors.New("not found")
+	m, err := ConfigureCircuit(CircuitOptions{
+		Name:              "TEST_IsFailure",
+		Threshold:         2,
+		MinimumCount:      3,
+		IntervalInSeconds: 60,
+		ThresholdType:     ThresholdCount,
+		IsFailure: func(err error) bool {
+			return !errors.Is(err, errNotFound)
+		},
+	})
+	assert.NoError(t, err)
+
+	// Test case 1: Errors ignored by the classifier are recorded as successes
+	// Expected output: The error is returned but the circuit stays closed
+	for i := 0; i < 5; i++ {
+		err = m.Execute(func() error {
+			return fmt.Errorf("lookup user: %w", errNotFound)
+		})
+		assert.True(t, errors.Is(err, errNotFound))
+	}
+	assert.Equal(t, int64(5), m.Data().SuccessCount)
+	assert.Equal(t, int64(0), m.Data().FailureCount)
+	assert.False(t, m.IsCircuitOpen())
+
+	// Test case 2: Other errors are still failures
+	// Expected output: Circuit open
+	_ = m.Execute(func() error { return errService })
+	_ = m.Execute(func() error { return errService })
+	assert.Equal(t, int64(2), m.Data().FailureCount)
+	assert.True(t, m.IsCircuitOpen())
+}
+
+func TestOnRejected(t *testing.T) {
+	rejected := []CallbackEvent{}
+	m, err := ConfigureCircuit(CircuitOptions{
+		Name:              "TEST_OnRejected",
+		Threshold:         2,
+		MinimumCount:      3,
+		IntervalInSeconds: 60,
+		ThresholdType:     ThresholdCount,
+		Clock:             NewFakeClock(time.Unix(1700000000, 0)),
+		OnRejected: func(t CallbackEvent) {
+			rejected = append(rejected, t)
+		},
+	})
+	assert.NoError(t, err)
+	defer m.Close()
+
+	// Test case 1: Execute calls while the circuit is closed
+	// Expected output: OnRejected is not called
+	assert.NoError(t, m.Execute(func() error { return nil }))
+	for i := 0; i < 2; i++ {
+		assert.Equal(t, errService, m.Execute(func() error { return errService }))
+	}
+	assert.True(t, m.IsCircuitOpen())
+	assert.Empty(t, rejected)
+
+	// Test case 2: Execute calls while the circuit is open
+	// Expected output: OnRejected is called once per rejected call with the counts
+	for i := 0; i < 3; i++ {
+		assert.Equal(t, ErrCircuitOpen, m.Execute(func() error { return nil }))
+	}
+	assert.Len(t, rejected, 3)
+	for _, event := range rejected {
+		assert.Equal(t, "TEST_OnRejected", event.Name)
+		assert.Equal(t, int64(1), event.SuccessCount)
+		assert.Equal(t, int64(2), event.FailureCount)
+		assert.Equal(t, StateOpen, event.FromState)
+		assert.Equal(t, StateOpen, event.ToState)
+		assert.Equal(t, int64(1700000000), event.Timestamp)
+	}
+
+	// Test case 3: Ask AllowRequest while the circuit is open
+	// Expected output: The rejection is reported as well
+	assert.False(t, m.AllowRequest())
+	assert.Len(t, rejected, 4)
+}
+
+func TestRejectedThisEpisode(t *testing.T) {
+	clock := NewFakeClock(time.Unix(1700000000, 0))
+	m, err := ConfigureCircuit(CircuitOptions{
+		Name:                  "TEST_RejectedThisEpisode",
+		Threshold:             2,
+		ThresholdType:         ThresholdConsecutive,
+		IntervalInSeconds:     60,
+		OpenDurationInSeconds: 10,
+		HalfOpenMaxProbes:     1,
+		Clock:                 clock,
+	})
+	assert.NoError(t, err)
+	defer m.Close()
+
+	// Test case 1: Reject calls while the circuit is open
+	// Expected output: Every rejection by Execute and AllowRequest is counted
+	m.UpdateStatus(false)
+	m.UpdateStatus(false)
+	for i := 0; i < 3; i++ {
+		assert.Equal(t, ErrCircuitOpen, m.Execute(func() error { return nil }))
+	}
+	assert.False(t, m.AllowRequest())
+	assert.Equal(t, int64(4), m.Data().RejectedThisEpisode)
+
+	// Test case 2: Fail the probe once the circuit is half-open
+	// Expected output: The circuit opens again within the same episode
+	clock.Advance(10 * time.Second)
+	assert.Equal(t, errService, m.Execute(func() error { return errService }))
+	assert.False(t, m.AllowRequest())
+	assert.Equal(t, int64(5), m.Data().RejectedThisEpisode)
+
+	// Test case 3: Close the circuit with a successful probe
+	// Expected output: The episode counter is reset and calls are admitted
+	clock.Advance(10 * time.Second)
+	assert.NoError(t, m.Execute(func() error { return nil }))
+	assert.Equal(t, StateClosed, m.State())
+	assert.Equal(t, int64(0), m.Data().RejectedThisEpisode)
+
+	// Test case 4: Open the circuit again
+	// Expected output: Only the rejections of the new episode are counted
+	m.UpdateStatus(false)
+	m.UpdateStatus(false)
+	assert.False(t, m.AllowRequest())
+	assert.Equal(t, int64(1), m.Data().RejectedThisEpisode)
+}
+
+func TestShedRatio(t *testing.T) {
+	clock := NewFakeClock(time.Unix(1700000000, 0))
+	m, err := ConfigureCircuit(CircuitOptions{
+		Name:              "TEST_ShedRatio",
+		Threshold:         2,
+		ThresholdType:     ThresholdConsecutive,
+		IntervalInSeconds: 60,
+		Clock:             clock,
+	})
+	assert.NoError(t, err)
+	defer m.Close()
+
+	// Test case 1: Make calls while the circuit is closed
+	// Expected output: They are counted as allowed, and nothing is shed
+	for i := 0; i < 3; i++ {
+		assert.NoError(t, m.Execute(func() error { return nil }))
+	}
+	assert.True(t, m.AllowRequest())
+	m.UpdateStatus(true)
+	data := m.Data()
+	assert.Equal(t, int64(4), data.AllowedCount)
+	assert.Equal(t, int64(0), data.RejectedCount)
+	assert.Equal(t, 0.0, data.ShedRatio)
+
+	// Test case 2: Trip the circuit through Execute and keep making calls
+	// Expected output: The rejected calls are counted and the shed ratio is over all admission decisions
+	assert.Equal(t, errService, m.Execute(func() error { return errService }))
+	assert.Equal(t, errService, m.Execute(func() error { return errService }))
+	assert.True(t, m.IsCircuitOpen())
+	for i := 0; i < 4; i++ {
+		assert.Equal(t, ErrCircuitOpen, m.Execute(func() error { return nil }))
+	}
+	assert.False(t, m.AllowRequest())
+	assert.False(t, m.AllowRequest())
+	data = m.Data()
+	assert.Equal(t, int64(6), data.AllowedCount)
+	assert.Equal(t, int64(6), data.RejectedCount)
+	assert.Equal(t, 0.5, data.ShedRatio)
+	assert.Equal(t, int64(6), data.TotalCount)
+
+	// Test case 3: The interval ends
+	// Expected output: The admission counts are cleared with the other counts
+	clock.Advance(60 * time.Second)
+	data = m.Data()
+	assert.Equal(t, int64(0), data.AllowedCount)
+	assert.Equal(t, int64(0), data.RejectedCount)
+	assert.Equal(t, 0.0, data.ShedRatio)
+}
+
+func TestExecutePanic(t *testing.T) {
+	newCircuit := func(recoverPanics bool) Circuit {
+		m, err := ConfigureCircuit(CircuitOptions{
+			Name:              "TEST_ExecutePanic",
+			Threshold:         2,
+			ThresholdType:     ThresholdConsecutive,
+			IntervalInSeconds: 60,
+			RecoverPanics:     recoverPanics,
+			ErrorClassifier: func(err error) string {
+				if errors.Is(err, ErrPanic) {
+					return "panic"
+				}
+				return "error"
+			},
+			Clock: NewFakeClock(time.Unix(1700000000, 0)),
+		})
+		assert.NoError(t, err)
+		return m
+	}
+
+	// Test case 1: A function that panics without RecoverPanics
+	// Expected output: The panic is raised again after being recorded as a failure
+	m := newCircuit(false)
+	defer m.Close()
+	assert.PanicsWithValue(t, "boom", func() {
+		_ = m.Execute(func() error { panic("boom") })
+	})
+	assert.Equal(t, int64(1), m.Data().FailureCount)
+	assert.Equal(t, map[string]int64{"panic": 1}, m.Data().FailuresByClass)
+	assert.Equal(t, int64(0), m.Data().InFlight)
+
+	// Test case 2: A function that panics with RecoverPanics
+	// Expected output: An error wrapping ErrPanic is returned and the panics trip the circuit
+	m = newCircuit(true)
+	defer m.Close()
+	err := m.Execute(func() error { panic("boom") })
+	assert.True(t, errors.Is(err, ErrPanic))
+	assert.EqualError(t, err, "function panicked: boom")
+	assert.False(t, m.IsCircuitOpen())
+	_ = m.Execute(func() error { panic(errService) })
+	assert.Equal(t, int64(2), m.Data().FailureCount)
+	assert.True(t, m.IsCircuitOpen())
+}
+
+func TestProbeOnce(t *testing.T) {
+	newCircuit := func(keepsOpen bool) Circuit {
+		m, err := ConfigureCircuit(CircuitOptions{
+			Name:                  "TEST_ProbeOnce",
+			Threshold:             2,
+			ThresholdType:         ThresholdConsecutive,
+			IntervalInSeconds:     60,
+			OpenDurationInSeconds: 600,
+			ProbeOnceKeepsOpen:    keepsOpen,
+			Clock:                 NewFakeClock(time.Unix(1700000000, 0)),
+		})
+		assert.NoError(t, err)
+		m.UpdateStatus(false)
+		m.UpdateStatus(false)
+		assert.True(t, m.IsCircuitOpen())
+		return m
+	}
+
+	// Test case 1: Probe an open circuit with a failing function
+	// Expected output: The function runs once, the failure is recorded and the circuit stays open
+	m := newCircuit(false)
+	defer m.Close()
+	calls := 0
+	ok, err := m.ProbeOnce(func() error {
+		calls++
+		return errService
+	})
+	assert.False(t, ok)
+	assert.Equal(t, errService, err)
+	assert.Equal(t, 1, calls)
+	assert.Equal(t, int64(3), m.Data().FailureCount)
+	assert.True(t, m.IsCircuitOpen())
+
+	// Test case 2: Probe the open circuit with a succeeding function
+	// Expected output: The circuit closes and only the success of the probe is counted
+	ok, err = m.ProbeOnce(func() error {
+		calls++
+		return nil
+	})
+	assert.True(t, ok)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, calls)
+	assert.Equal(t, StateClosed, m.State())
+	assert.Equal(t, int64(1), m.Data().SuccessCount)
+	assert.Equal(t, int64(0), m.Data().FailureCount)
+
+	// Test case 3: Probe an open circuit with ProbeOnceKeepsOpen
+	// Expected output: The success is recorded but the circuit stays open for its open duration
+	m = newCircuit(true)
+	defer m.Close()
+	ok, err = m.ProbeOnce(func() error { return nil })
+	assert.True(t, ok)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), m.Data().SuccessCount)
+	assert.True(t, m.IsCircuitOpen())
+}
+
+func TestExecuteLatencyFromClock(t *testing.T) {
+	clock := NewFakeClock(time.Unix(1700000000, 0))
+	m, err := ConfigureCircuit(CircuitOptions{
+		Name:                  "TEST_ExecuteLatencyFromClock",
+		Threshold:             50,
+		ThresholdType:         ThresholdPercentage,
+		MinimumCount:          10,
+		IntervalInSeconds:     60,
+		SlowCallThreshold:     100 * time.Millisecond,
+		SlowCallRateThreshold: 50,
+		Clock:                 clock,
+	})
+	assert.NoError(t, err)
+	defer m.Close()
+
+	// Test case 1: Execute a call during which the circuit's clock does not move
+	// Expected output: The call takes no time and is not slow
+	assert.NoError(t, m.Execute(func() error { return nil }))
+	assert.Equal(t, int64(0), m.Data().SlowCallCount)
+	assert.Equal(t, time.Duration(0), m.Data().Latency.Max)
+
+	// Test case 2: Execute a call during which the circuit's clock moves by a second
+	// Expected output: The latency is taken from the clock, so the call is slow
+	assert.NoError(t, m.Execute(func() error {
+		clock.Advance(time.Second)
+		return nil
+	}))
+	assert.Equal(t, int64(1), m.Data().SlowCallCount)
+	assert.Equal(t, time.Second, m.Data().Latency.Max)
+
+	// Test case 3: Probe with a call during which the circuit's clock moves by 200ms
+	// Expected output: The counts are reset by the successful probe, which is then recorded as slow
+	ok, err := m.ProbeOnce(func() error {
+		clock.Advance(200 * time.Millisecond)
+		return nil
+	})
+	assert.True(t, ok)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), m.Data().SlowCallCount)
+	assert.Equal(t, 200*time.Millisecond, m.Data().Latency.Max)
+}
@@ -0,0 +1,178 @@
+package tripper
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// AllowRequest reports whether a call may go through the circuit, for flows
+// that cannot use Execute. It returns false while the circuit is open, unless
+// it was disabled with SetEnabled. While it is half-open, a true result
+// reserves one of the HalfOpenMaxProbes probe slots, so the outcome of the
+// call must be recorded with UpdateStatus:
+//
+//	if circuit.AllowRequest() {
+//		err := stream()
+//		circuit.UpdateStatus(err == nil)
+//	}
+func (m *CircuitImplementation) AllowRequest() bool {
+	if m == nil {
+		return noopCircuit{}.AllowRequest()
+	}
+
+	allowed, _ := m.allowRequest()
+	return allowed
+}
+
+// AllowProbe is like AllowRequest, and also returns a function that gives back
+// the half-open probe slot the call was admitted with, for a call whose
+// outcome is then skipped with UpdateStatusExt. The function only gives back
+// the slot reserved by this call, and only while the circuit is still in the
+// half-open period it was admitted in; otherwise, and after its first call, it
+// does nothing:
+//
+//	allowed, release := circuit.AllowProbe()
+//	if allowed {
+//		err := stream(input)
+//		if errors.Is(err, errBadInput) {
+//			release()
+//		}
+//		circuit.UpdateStatusExt(err == nil, !errors.Is(err, errBadInput))
+//	}
+func (m *CircuitImplementation) AllowProbe() (bool, func()) {
+	if m == nil {
+		return noopCircuit{}.AllowProbe()
+	}
+
+	allowed, probe := m.allowRequest()
+	if !probe.held {
+		return allowed, func() {}
+	}
+	var once sync.Once
+	return allowed, func() {
+		once.Do(func() {
+			m.releaseProbe(probe)
+		})
+	}
+}
+
+// probeSlot is a half-open probe slot reserved by allowRequest, identified by
+// the TripCount of the circuit when it was reserved. The circuit opens again
+// before every half-open period, so a slot can only be given back to the
+// period it was reserved in.
+type probeSlot struct {
+	held  bool  // Whether a probe slot was reserved
+	trips int64 // TripCount when the slot was reserved
+}
+
+// allowRequest reports whether a call may go through the circuit and the probe
+// slot it reserved, if it was admitted as a probe. Admitted and rejected calls
+// are counted for the current interval, and rejected calls also for the
+// current open episode and reported to OnRejected.
+func (m *CircuitImplementation) allowRequest() (bool, probeSlot) {
+	m.startTicker()
+	allowed, probe := m.admitRequest()
+	if allowed {
+		atomic.AddInt64(&m.allowedCount, 1)
+	} else {
+		atomic.AddInt64(&m.rejectedCount, 1)
+		atomic.AddInt64(&m.rejectedThisEpisode, 1)
+		if event, ok := m.rejectionEvent(); ok {
+			m.dispatch(event)
+		}
+	}
+	return allowed, probe
+}
+
+// admitRequest decides whether a call may go through the circuit. A closed
+// circuit admits every call and an open one none. A half-open circuit admits
+// up to HalfOpenMaxProbes probe calls, whose outcomes decide whether it closes
+// or opens again. A disabled circuit admits every call without reserving a
+// probe slot.
+func (m *CircuitImplementation) admitRequest() (bool, probeSlot) {
+	m.refreshState()
+
+	m.Mutex.RLock()
+	halfOpen := m.HalfOpen && !m.Disabled
+	open := m.CircuitOpen && !m.Disabled
+	m.Mutex.RUnlock()
+	if !halfOpen {
+		return !open, probeSlot{}
+	}
+
+	m.Mutex.Lock()
+	defer m.Mutex.Unlock()
+	if !m.HalfOpen || m.Disabled {
+		return !m.CircuitOpen || m.Disabled, probeSlot{}
+	}
+	if m.HalfOpenProbes >= m.Options.HalfOpenMaxProbes {
+		return false, probeSlot{}
+	}
+	m.HalfOpenProbes++
+	return true, probeSlot{held: true, trips: m.TripCount}
+}
+
+// releaseProbe gives back a probe slot reserved by allowRequest whose outcome
+// was not recorded, so that a half-open circuit can admit another probe. A
+// slot reserved in an earlier half-open period is not given back, since the
+// probes of the current one were counted from zero.
+func (m *CircuitImplementation) releaseProbe(probe probeSlot) {
+	if !probe.held {
+		return
+	}
+
+	m.Mutex.Lock()
+	defer m.Mutex.Unlock()
+	if m.HalfOpen && m.TripCount == probe.trips && m.HalfOpenProbes > 0 {
+		m.HalfOpenProbes--
+	}
+}
+
+// settleProbes closes a half-open circuit in which HalfOpenMaxProbes probes
+// already succeeded, which only happens when UpdateOptions lowered it. It
+// returns the callback event for the transition, if any. The caller must hold
+// m.Mutex.
+func (m *CircuitImplementation) settleProbes(now int64) []CallbackEvent {
+	if !m.HalfOpen || m.HalfOpenSuccesses < m.Options.HalfOpenMaxProbes {
+		return nil
+	}
+	fromState := m.state()
+	m.HalfOpen = false
+	m.HalfOpenProbes = 0
+	m.HalfOpenSuccesses = 0
+	m.CircuitOpen = false
+	m.CircuitOpenedSince = 0
+	m.BackoffLevel = 0
+	m.recordTransition(fromState, now, ReasonRecovered)
+	return []CallbackEvent{m.callbackEvent(now, fromState, ReasonRecovered)}
+}
+
+// recordProbe applies the outcome of a probe to a half-open circuit. The
+// probes are its only gate, MinimumCount does not apply: a failure opens it
+// again for another open duration, and it closes once HalfOpenMaxProbes probes
+// succeeded. It returns the callback event for the transition, if any. The
+// caller must hold m.Mutex.
+func (m *CircuitImplementation) recordProbe(success bool) []CallbackEvent {
+	if success {
+		m.HalfOpenSuccesses++
+		if m.HalfOpenSuccesses < m.Options.HalfOpenMaxProbes {
+			return nil
+		}
+	}
+	fromState := m.state()
+	m.HalfOpen = false
+	m.HalfOpenProbes = 0
+	m.HalfOpenSuccesses = 0
+	reason := ReasonRecovered
+	if success {
+		m.CircuitOpen = false
+		m.CircuitOpenedSince = 0
+		m.BackoffLevel = 0
+	} else {
+		m.CircuitOpen = true
+		m.startOpenDuration()
+		reason = ReasonProbeFailed
+	}
+	m.recordTransition(fromState, m.LastCapturedAt, reason)
+	return []CallbackEvent{m.callbackEvent(m.LastCapturedAt, fromState, reason)}
+}
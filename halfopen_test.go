@@ -0,0 +1,498 @@
+package tripper
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newHalfOpenCircuit(t *testing.T, maxProbes int64, openDuration int) (Circuit, *FakeClock, *[][2]CircuitState) {
+	clock := NewFakeClock(time.Unix(1700000000, 0))
+	var mutex sync.Mutex
+	transitions := &[][2]CircuitState{}
+	m, err := ConfigureCircuit(CircuitOptions{
+		Name:                  "TEST_HalfOpen",
+		Threshold:             2,
+		ThresholdType:         ThresholdConsecutive,
+		MinimumCount:          1,
+		IntervalInSeconds:     60,
+		OpenDurationInSeconds: openDuration,
+		HalfOpenMaxProbes:     maxProbes,
+		Clock:                 clock,
+		OnStateChange: func(from, to CircuitState, t CallbackEvent) {
+			mutex.Lock()
+			*transitions = append(*transitions, [2]CircuitState{from, to})
+			mutex.Unlock()
+		},
+	})
+	assert.NoError(t, err)
+	return m, clock, transitions
+}
+
+func TestHalfOpen(t *testing.T) {
+	m, clock, transitions := newHalfOpenCircuit(t, 1, 30)
+	defer m.Close()
+	m.UpdateStatus(false)
+	m.UpdateStatus(false)
+	assert.Equal(t, StateOpen, m.State())
+
+	// Test case 1: The open duration elapses
+	// Expected output: The circuit becomes half-open instead of closing and is still reported as open
+	clock.Advance(30 * time.Second)
+	assert.Equal(t, StateHalfOpen, m.State())
+	assert.True(t, m.IsCircuitOpen())
+
+	// Test case 2: A probe fails
+	// Expected output: The circuit opens again for another open duration
+	assert.Equal(t, errService, m.Execute(func() error { return errService }))
+	assert.Equal(t, StateOpen, m.State())
+	assert.Equal(t, int64(2), m.Data().TripCount)
+	assert.Equal(t, ErrCircuitOpen, m.Execute(func() error { return nil }))
+	clock.Advance(29 * time.Second)
+	assert.Equal(t, StateOpen, m.State())
+	clock.Advance(time.Second)
+	assert.Equal(t, StateHalfOpen, m.State())
+
+	// Test case 3: A probe succeeds
+	// Expected output: The circuit closes and admits every call again
+	assert.NoError(t, m.Execute(func() error { return nil }))
+	assert.Equal(t, StateClosed, m.State())
+	assert.NoError(t, m.Execute(func() error { return nil }))
+	assert.Equal(t, [][2]CircuitState{
+		{StateClosed, StateOpen},
+		{StateOpen, StateHalfOpen},
+		{StateHalfOpen, StateOpen},
+		{StateOpen, StateHalfOpen},
+		{StateHalfOpen, StateClosed},
+	}, *transitions)
+}
+
+func TestHalfOpenIsCircuitOpen(t *testing.T) {
+	m, clock, _ := newHalfOpenCircuit(t, 2, 30)
+	defer m.Close()
+	m.UpdateStatus(false)
+	m.UpdateStatus(false)
+	clock.Advance(30 * time.Second)
+
+	// Test case 1: Read IsCircuitOpen while half-open
+	// Expected output: The circuit is reported as open although AllowRequest admits the probes
+	assert.Equal(t, StateHalfOpen, m.State())
+	assert.True(t, m.IsCircuitOpen())
+	assert.True(t, m.AllowRequest())
+	assert.True(t, m.AllowRequest())
+	assert.False(t, m.AllowRequest())
+	assert.True(t, m.IsCircuitOpen())
+
+	// Test case 2: Record the outcome of the admitted probes
+	// Expected output: The circuit closes and IsCircuitOpen returns false
+	m.UpdateStatus(true)
+	assert.True(t, m.IsCircuitOpen())
+	m.UpdateStatus(true)
+	assert.Equal(t, StateClosed, m.State())
+	assert.False(t, m.IsCircuitOpen())
+
+	// Test case 3: Disable a half-open circuit
+	// Expected output: It is not reported as open
+	m.UpdateStatus(false)
+	m.UpdateStatus(false)
+	clock.Advance(30 * time.Second)
+	assert.True(t, m.IsCircuitOpen())
+	m.SetEnabled(false)
+	assert.False(t, m.IsCircuitOpen())
+}
+
+func TestHalfOpenWithoutOpenDuration(t *testing.T) {
+	m, clock, _ := newHalfOpenCircuit(t, 1, 0)
+	defer m.Close()
+
+	// Test case 1: The interval reset while the circuit is open
+	// Expected output: The circuit stays open until the interval has passed since it opened
+	clock.Advance(10 * time.Second)
+	m.UpdateStatus(false)
+	m.UpdateStatus(false)
+	clock.Advance(50 * time.Second)
+	assert.Equal(t, StateOpen, m.State())
+	clock.Advance(10 * time.Second)
+	assert.Equal(t, StateHalfOpen, m.State())
+
+	// Test case 2: The interval reset while the circuit is half-open
+	// Expected output: The circuit stays half-open until a probe completes
+	clock.Advance(time.Minute)
+	assert.Equal(t, StateHalfOpen, m.State())
+	assert.NoError(t, m.Execute(func() error { return nil }))
+	assert.Equal(t, StateClosed, m.State())
+}
+
+func TestHalfOpenProbeLimit(t *testing.T) {
+	m, clock, _ := newHalfOpenCircuit(t, 3, 30)
+	defer m.Close()
+	m.UpdateStatus(false)
+	m.UpdateStatus(false)
+	clock.Advance(30 * time.Second)
+	assert.Equal(t, StateHalfOpen, m.State())
+
+	// Test case 1: Many goroutines call Execute on a half-open circuit
+	// Expected output: Only 3 probes run fn, the others are short-circuited
+	var executed, rejected int64
+	release := make(chan struct{})
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := m.Execute(func() error {
+				atomic.AddInt64(&executed, 1)
+				<-release
+				return nil
+			})
+			if err == ErrCircuitOpen {
+				atomic.AddInt64(&rejected, 1)
+			}
+		}()
+	}
+	waitFor(t, func() bool { return atomic.LoadInt64(&rejected) == 47 })
+	assert.Equal(t, int64(3), atomic.LoadInt64(&executed))
+	close(release)
+	wg.Wait()
+
+	// Test case 2: The probes succeed
+	// Expected output: The circuit closes
+	assert.Equal(t, StateClosed, m.State())
+}
+
+func TestHalfOpenProbeReleased(t *testing.T) {
+	m, clock, _ := newHalfOpenCircuit(t, 1, 30)
+	defer m.Close()
+	m.UpdateStatus(false)
+	m.UpdateStatus(false)
+	clock.Advance(30 * time.Second)
+
+	// Test case 1: The only probe fails with a context error that is not recorded
+	// Expected output: The circuit stays half-open and admits another probe
+	err := m.ExecuteContext(context.Background(), func(context.Context) error {
+		return context.DeadlineExceeded
+	})
+	assert.True(t, errors.Is(err, context.DeadlineExceeded))
+	assert.Equal(t, StateHalfOpen, m.State())
+	assert.NoError(t, m.Execute(func() error { return nil }))
+	assert.Equal(t, StateClosed, m.State())
+}
+
+func TestHalfOpenMaxProbesLowered(t *testing.T) {
+	withMaxProbes := func(m Circuit, maxProbes int64) {
+		options := m.GetOptions()
+		options.HalfOpenMaxProbes = maxProbes
+		assert.NoError(t, m.UpdateOptions(options))
+	}
+
+	// Test case 1: Lower HalfOpenMaxProbes to 0 while half-open
+	// Expected output: The circuit closes and admits calls again
+	m, clock, transitions := newHalfOpenCircuit(t, 2, 30)
+	defer m.Close()
+	m.UpdateStatus(false)
+	m.UpdateStatus(false)
+	clock.Advance(30 * time.Second)
+	assert.True(t, m.AllowRequest())
+	assert.True(t, m.AllowRequest())
+	assert.False(t, m.AllowRequest())
+	withMaxProbes(m, 0)
+	assert.Equal(t, StateClosed, m.State())
+	assert.True(t, m.AllowRequest())
+	assert.Equal(t, [2]CircuitState{StateHalfOpen, StateClosed}, (*transitions)[len(*transitions)-1])
+
+	// Test case 2: Lower HalfOpenMaxProbes below the probes that already succeeded
+	// Expected output: The circuit closes
+	m, clock, _ = newHalfOpenCircuit(t, 3, 30)
+	defer m.Close()
+	m.UpdateStatus(false)
+	m.UpdateStatus(false)
+	clock.Advance(30 * time.Second)
+	assert.NoError(t, m.Execute(func() error { return nil }))
+	assert.Equal(t, StateHalfOpen, m.State())
+	withMaxProbes(m, 1)
+	assert.Equal(t, StateClosed, m.State())
+
+	// Test case 3: Lower HalfOpenMaxProbes above the probes that already succeeded
+	// Expected output: The circuit stays half-open and closes after the remaining probes
+	m, clock, _ = newHalfOpenCircuit(t, 3, 30)
+	defer m.Close()
+	m.UpdateStatus(false)
+	m.UpdateStatus(false)
+	clock.Advance(30 * time.Second)
+	assert.NoError(t, m.Execute(func() error { return nil }))
+	withMaxProbes(m, 2)
+	assert.Equal(t, StateHalfOpen, m.State())
+	assert.NoError(t, m.Execute(func() error { return nil }))
+	assert.Equal(t, StateClosed, m.State())
+}
+
+func TestHalfOpenMaxProbesValidation(t *testing.T) {
+	// Test case 1: A negative number of probes
+	// Expected output: Error
+	_, err := ConfigureCircuit(CircuitOptions{
+		Name:              "TEST_HalfOpenMaxProbesValidation",
+		Threshold:         2,
+		ThresholdType:     ThresholdConsecutive,
+		MinimumCount:      1,
+		IntervalInSeconds: 60,
+		HalfOpenMaxProbes: -1,
+	})
+	assert.EqualError(t, err, "invalid half open max probes -1")
+	assert.True(t, errors.Is(err, ErrInvalidHalfOpenMaxProbes))
+}
+
+func TestHalfOpenRestoreState(t *testing.T) {
+	m, _, _ := newHalfOpenCircuit(t, 1, 30)
+	defer m.Close()
+	m.UpdateStatus(false)
+	m.UpdateStatus(false)
+	data, err := m.MarshalState()
+	assert.NoError(t, err)
+
+	// Test case 1: Restore an open circuit whose open duration has passed
+	// Expected output: The circuit is restored as half-open
+	restored, clock, _ := newHalfOpenCircuit(t, 1, 30)
+	defer restored.Close()
+	clock.Advance(time.Minute)
+	assert.NoError(t, restored.RestoreState(data))
+	assert.Equal(t, StateHalfOpen, restored.State())
+
+	// Test case 2: Restore a half-open circuit
+	// Expected output: The circuit is restored as half-open
+	data, err = restored.MarshalState()
+	assert.NoError(t, err)
+	assert.NoError(t, m.RestoreState(data))
+	assert.Equal(t, StateHalfOpen, m.State())
+}
+
+func TestAllowRequest(t *testing.T) {
+	m, clock, _ := newHalfOpenCircuit(t, 2, 30)
+	defer m.Close()
+
+	// Test case 1: A closed circuit
+	// Expected output: Every request is allowed
+	for i := 0; i < 10; i++ {
+		assert.True(t, m.AllowRequest())
+	}
+
+	// Test case 2: An open circuit
+	// Expected output: No request is allowed
+	m.UpdateStatus(false)
+	m.UpdateStatus(false)
+	assert.False(t, m.AllowRequest())
+
+	// Test case 3: A half-open circuit
+	// Expected output: Only HalfOpenMaxProbes requests are allowed until an outcome is recorded
+	clock.Advance(30 * time.Second)
+	assert.True(t, m.AllowRequest())
+	assert.True(t, m.AllowRequest())
+	assert.False(t, m.AllowRequest())
+	assert.Equal(t, StateHalfOpen, m.State())
+
+	// Test case 4: The probes admitted by AllowRequest succeed
+	// Expected output: The circuit closes once both succeeded and allows every request
+	m.UpdateStatus(true)
+	assert.Equal(t, StateHalfOpen, m.State())
+	assert.False(t, m.AllowRequest())
+	m.UpdateStatus(true)
+	assert.Equal(t, StateClosed, m.State())
+	assert.True(t, m.AllowRequest())
+
+	// Test case 5: An open circuit without HalfOpenMaxProbes once its open duration elapsed
+	// Expected output: The circuit closes and allows every request
+	m, clock, _ = newHalfOpenCircuit(t, 0, 30)
+	defer m.Close()
+	m.UpdateStatus(false)
+	m.UpdateStatus(false)
+	assert.False(t, m.AllowRequest())
+	clock.Advance(30 * time.Second)
+	assert.True(t, m.AllowRequest())
+	assert.Equal(t, StateClosed, m.State())
+}
+
+func TestAllowProbe(t *testing.T) {
+	m, clock, _ := newHalfOpenCircuit(t, 1, 30)
+	defer m.Close()
+	m.UpdateStatus(false)
+	m.UpdateStatus(false)
+	clock.Advance(30 * time.Second)
+	assert.Equal(t, StateHalfOpen, m.State())
+
+	// Test case 1: Release the slot of a call rejected by the half-open circuit
+	// Expected output: The slot reserved by the admitted probe stays reserved
+	allowed, release := m.AllowProbe()
+	assert.True(t, allowed)
+	rejected, releaseRejected := m.AllowProbe()
+	assert.False(t, rejected)
+	releaseRejected()
+	assert.False(t, m.AllowRequest())
+
+	// Test case 2: Release the slot of the admitted probe twice
+	// Expected output: The slot is given back once, so only one more probe is admitted
+	release()
+	release()
+	allowed, staleRelease := m.AllowProbe()
+	assert.True(t, allowed)
+	assert.False(t, m.AllowRequest())
+
+	// Test case 3: The probe fails and the circuit becomes half-open again before the probe is released
+	// Expected output: The release does not give back the slot of the new half-open period
+	m.UpdateStatus(false)
+	assert.Equal(t, StateOpen, m.State())
+	clock.Advance(30 * time.Second)
+	assert.Equal(t, StateHalfOpen, m.State())
+	assert.True(t, m.AllowRequest())
+	staleRelease()
+	assert.False(t, m.AllowRequest())
+	assert.Equal(t, StateHalfOpen, m.State())
+}
+
+func TestBackoff(t *testing.T) {
+	clock := NewFakeClock(time.Unix(1700000000, 0))
+	m, err := ConfigureCircuit(CircuitOptions{
+		Name:                     "TEST_Backoff",
+		Threshold:                1,
+		ThresholdType:            ThresholdConsecutive,
+		MinimumCount:             1,
+		IntervalInSeconds:        600,
+		OpenDurationInSeconds:    10,
+		HalfOpenMaxProbes:        1,
+		BackoffMultiplier:        2,
+		MaxOpenDurationInSeconds: 60,
+		Clock:                    clock,
+	})
+	assert.NoError(t, err)
+	defer m.Close()
+
+	// openFor returns how long the circuit stays open before it becomes half-open
+	openFor := func() int64 {
+		opened := clock.Now()
+		for m.State() == StateOpen {
+			clock.Advance(time.Second)
+		}
+		return clock.Now() - opened
+	}
+
+	// Test case 1: Trip the circuit and keep failing the probes
+	// Expected output: The open duration doubles on every trip up to the maximum
+	m.UpdateStatus(false)
+	for i, expected := range []int64{10, 20, 40, 60, 60} {
+		assert.Equal(t, int64(i+1), m.Data().BackoffLevel)
+		assert.Equal(t, expected, openFor())
+		assert.Equal(t, errService, m.Execute(func() error { return errService }))
+	}
+
+	// Test case 2: A probe succeeds
+	// Expected output: The circuit closes and the backoff is reset
+	openFor()
+	assert.NoError(t, m.Execute(func() error { return nil }))
+	assert.Equal(t, StateClosed, m.State())
+	assert.Equal(t, int64(0), m.Data().BackoffLevel)
+	m.UpdateStatus(false)
+	assert.Equal(t, int64(1), m.Data().BackoffLevel)
+	assert.Equal(t, int64(10), openFor())
+}
+
+func TestBackoffWithoutHalfOpen(t *testing.T) {
+	clock := NewFakeClock(time.Unix(1700000000, 0))
+	m, err := ConfigureCircuit(CircuitOptions{
+		Name:                  "TEST_BackoffWithoutHalfOpen",
+		Threshold:             1,
+		ThresholdType:         ThresholdConsecutive,
+		MinimumCount:          1,
+		IntervalInSeconds:     600,
+		OpenDurationInSeconds: 10,
+		BackoffMultiplier:     3,
+		Clock:                 clock,
+	})
+	assert.NoError(t, err)
+	defer m.Close()
+
+	// Test case 1: The first call after each recovery fails again
+	// Expected output: The open duration triples on every trip
+	for _, expected := range []int64{10, 30, 90} {
+		m.UpdateStatus(false)
+		opened := clock.Now()
+		for m.IsCircuitOpen() {
+			clock.Advance(time.Second)
+		}
+		assert.Equal(t, expected, clock.Now()-opened)
+	}
+
+	// Test case 2: A success is recorded after the circuit closed
+	// Expected output: The backoff is reset
+	m.UpdateStatus(true)
+	assert.Equal(t, int64(0), m.Data().BackoffLevel)
+
+	// Test case 3: An invalid multiplier
+	// Expected output: Error
+	_, err = ConfigureCircuit(CircuitOptions{
+		Name:              "TEST_BackoffWithoutHalfOpen",
+		Threshold:         1,
+		ThresholdType:     ThresholdConsecutive,
+		MinimumCount:      1,
+		IntervalInSeconds: 60,
+		BackoffMultiplier: 0.5,
+	})
+	assert.EqualError(t, err, "invalid backoff multiplier 0.500000")
+}
+
+func TestHalfOpenIgnoresMinimumCount(t *testing.T) {
+	clock := NewFakeClock(time.Unix(1700000000, 0))
+	m, err := ConfigureCircuit(CircuitOptions{
+		Name:                  "TEST_HalfOpenIgnoresMinimumCount",
+		Threshold:             50,
+		ThresholdType:         ThresholdPercentage,
+		MinimumCount:          100,
+		IntervalInSeconds:     60,
+		OpenDurationInSeconds: 30,
+		HalfOpenMaxProbes:     3,
+		Clock:                 clock,
+	})
+	assert.NoError(t, err)
+	defer m.Close()
+	openCircuit := func() {
+		for i := 0; i < 100; i++ {
+			m.UpdateStatus(false)
+		}
+		assert.Equal(t, StateOpen, m.State())
+		clock.Advance(30 * time.Second)
+		assert.Equal(t, StateHalfOpen, m.State())
+	}
+
+	// Test case 1: All probes of a half-open circuit succeed, far fewer than MinimumCount
+	// Expected output: The circuit stays half-open until HalfOpenMaxProbes probes succeeded, then closes
+	openCircuit()
+	for i := 0; i < 2; i++ {
+		assert.True(t, m.AllowRequest())
+		m.UpdateStatus(true)
+		assert.Equal(t, StateHalfOpen, m.State())
+	}
+	assert.True(t, m.AllowRequest())
+	m.UpdateStatus(true)
+	assert.Equal(t, StateClosed, m.State())
+	assert.Equal(t, int64(3), m.Data().TotalCount)
+
+	// Test case 2: A probe fails after others succeeded
+	// Expected output: The circuit opens again at once
+	clock.Advance(60 * time.Second)
+	openCircuit()
+	m.UpdateStatus(true)
+	m.UpdateStatus(false)
+	assert.Equal(t, StateOpen, m.State())
+
+	// Test case 3: The next half-open phase
+	// Expected output: The successes of the previous phase are not carried over
+	clock.Advance(30 * time.Second)
+	assert.Equal(t, StateHalfOpen, m.State())
+	m.UpdateStatus(true)
+	m.UpdateStatus(true)
+	assert.Equal(t, StateHalfOpen, m.State())
+	m.UpdateStatus(true)
+	assert.Equal(t, StateClosed, m.State())
+}
@@ -0,0 +1,52 @@
+package tripper
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// Handler returns a read-only http.Handler serving the Data of every
+// registered circuit as a JSON object keyed by circuit name, e.g. for a
+// /circuits debug endpoint. The name query parameter, which may be repeated,
+// restricts the response to the given circuits.
+//
+//	http.Handle("/circuits", t.Handler())
+func (t *TripperImplementation) Handler() http.Handler {
+	return http.HandlerFunc(t.serveCircuits)
+}
+
+func (t *TripperImplementation) serveCircuits(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	names := r.URL.Query()["name"]
+	t.Mutex.RLock()
+	circuits := make(map[string]Circuit, len(t.Monitors))
+	for name, circuit := range t.Monitors {
+		circuits[name] = circuit
+	}
+	t.Mutex.RUnlock()
+
+	if len(names) > 0 {
+		filtered := make(map[string]Circuit, len(names))
+		for _, name := range names {
+			if circuit, ok := circuits[name]; ok {
+				filtered[name] = circuit
+			}
+		}
+		circuits = filtered
+	}
+
+	// Data is read without holding the registry lock so a busy circuit does
+	// not block AddMonitor or RemoveMonitor
+	data := make(map[string]CircuitData, len(circuits))
+	for name, circuit := range circuits {
+		data[name] = circuit.Data()
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(data)
+}
@@ -0,0 +1,65 @@
+package tripper
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHandler(t *testing.T) {
+	tripper := Configure(TripperOptions{})
+	clock := NewFakeClock(time.Unix(1700000000, 0))
+	for _, name := range []string{"payments", "search"} {
+		_, err := tripper.AddMonitor(CircuitOptions{
+			Name:              name,
+			Threshold:         2,
+			ThresholdType:     ThresholdConsecutive,
+			IntervalInSeconds: 60,
+			Clock:             clock,
+		})
+		assert.NoError(t, err)
+	}
+	payments, _ := tripper.GetMonitor("payments")
+	payments.UpdateStatus(false)
+	payments.UpdateStatus(false)
+	search, _ := tripper.GetMonitor("search")
+	search.UpdateStatus(true)
+
+	get := func(target string) (*httptest.ResponseRecorder, map[string]map[string]interface{}) {
+		rec := httptest.NewRecorder()
+		tripper.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, target, nil))
+		var body map[string]map[string]interface{}
+		if rec.Code == http.StatusOK {
+			assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+		}
+		return rec, body
+	}
+
+	// Test case 1: Request every circuit
+	// Expected output: The data of both circuits with their states
+	rec, body := get("/circuits")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+	assert.Len(t, body, 2)
+	assert.Equal(t, "OPEN", body["payments"]["state"])
+	assert.Equal(t, float64(2), body["payments"]["failure_count"])
+	assert.Equal(t, "2023-11-14T22:13:20Z", body["payments"]["last_state_changed_at_time"])
+	assert.Equal(t, "CLOSED", body["search"]["state"])
+	assert.Equal(t, float64(1), body["search"]["success_count"])
+
+	// Test case 2: Filter by name
+	// Expected output: Only the requested circuit, unknown names are ignored
+	_, body = get("/circuits?name=search&name=unknown")
+	assert.Len(t, body, 1)
+	assert.Equal(t, "CLOSED", body["search"]["state"])
+
+	// Test case 3: Send a POST request
+	// Expected output: Method not allowed
+	rec = httptest.NewRecorder()
+	tripper.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/circuits", nil))
+	assert.Equal(t, http.StatusMethodNotAllowed, rec.Code)
+}
@@ -0,0 +1,98 @@
+package tripper
+
+import "time"
+
+// historySize is the number of past windows kept by a circuit.
+const historySize = 10
+
+// WindowStats summarizes a past interval of a circuit.
+type WindowStats struct {
+	StartTime    int64 // Timestamp when the interval started
+	SuccessCount int64 // Number of successes recorded when the interval ended
+	FailureCount int64 // Number of failures recorded when the interval ended
+	WasOpen      bool  // Whether the circuit was open at any time during the interval
+}
+
+// History returns the stats of the last completed intervals, oldest first.
+// At most 10 intervals are kept.
+func (m *CircuitImplementation) History() []WindowStats {
+	if m == nil {
+		return noopCircuit{}.History()
+	}
+
+	m.Mutex.RLock()
+	defer m.Mutex.RUnlock()
+
+	history := make([]WindowStats, len(m.history))
+	copy(history, m.history)
+	return history
+}
+
+// recordWindow appends the stats of the interval ending now to the history,
+// dropping the oldest interval once historySize intervals are kept. The caller
+// must hold m.Mutex.
+func (m *CircuitImplementation) recordWindow() {
+	m.history = append(m.history, WindowStats{
+		StartTime:    unixSeconds(m.WindowStartedAt),
+		SuccessCount: m.SuccessCount,
+		FailureCount: m.FailureCount,
+		WasOpen:      m.windowOpened || m.CircuitOpen,
+	})
+	if len(m.history) > historySize {
+		m.history = m.history[len(m.history)-historySize:]
+	}
+}
+
+// TransitionRecord describes a state transition kept with RecordHistory.
+type TransitionRecord struct {
+	Timestamp          int64        // Timestamp of the transition
+	Time               time.Time    // Time of the transition, with sub-second precision when the Clock is a PreciseClock
+	FromState          CircuitState // State before the transition
+	ToState            CircuitState // State after the transition
+	Reason             string       // Why the state changed, one of the Reason constants
+	SuccessCount       int64        // Number of successes recorded at the time of the transition
+	FailureCount       int64        // Number of failures recorded at the time of the transition
+	ConsecutiveCounter int64        // Number of failures in the current streak at the time of the transition
+}
+
+// Transitions returns the last RecordHistory state transitions, oldest first,
+// e.g. to find out after the fact why a circuit tripped. It returns nothing
+// unless RecordHistory is set.
+func (m *CircuitImplementation) Transitions() []TransitionRecord {
+	if m == nil {
+		return noopCircuit{}.Transitions()
+	}
+
+	m.Mutex.RLock()
+	defer m.Mutex.RUnlock()
+
+	transitions := m.transitions
+	if len(transitions) > m.Options.RecordHistory {
+		// UpdateOptions lowered RecordHistory
+		transitions = transitions[len(transitions)-m.Options.RecordHistory:]
+	}
+	return append([]TransitionRecord(nil), transitions...)
+}
+
+// recordTransitionHistory appends a transition to the transitions kept with
+// RecordHistory, dropping the oldest ones beyond RecordHistory. The caller
+// must hold m.Mutex.
+func (m *CircuitImplementation) recordTransitionHistory(fromState, toState CircuitState, timestamp int64, reason string) {
+	if m.Options.RecordHistory <= 0 {
+		m.transitions = nil
+		return
+	}
+	m.transitions = append(m.transitions, TransitionRecord{
+		Timestamp:          unixSeconds(timestamp),
+		Time:               timeOf(timestamp),
+		FromState:          fromState,
+		ToState:            toState,
+		Reason:             reason,
+		SuccessCount:       m.SuccessCount,
+		FailureCount:       m.FailureCount,
+		ConsecutiveCounter: m.ConsecutiveCounter,
+	})
+	if len(m.transitions) > m.Options.RecordHistory {
+		m.transitions = m.transitions[len(m.transitions)-m.Options.RecordHistory:]
+	}
+}
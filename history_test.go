@@ -0,0 +1,119 @@
+package tripper
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHistory(t *testing.T) {
+	start := int64(1700000000)
+	clock := NewFakeClock(time.Unix(start, 0))
+	m, err := ConfigureCircuit(CircuitOptions{
+		Name:              "TEST_History",
+		Threshold:         3,
+		ThresholdType:     ThresholdConsecutive,
+		MinimumCount:      1,
+		IntervalInSeconds: 60,
+		Clock:             clock,
+	})
+	assert.NoError(t, err)
+	defer m.Close()
+
+	// Test case 1: No interval completed yet
+	// Expected output: Empty history
+	assert.Empty(t, m.History())
+
+	// Test case 2: Run a healthy interval, a tripping interval and a recovering interval
+	// Expected output: One entry per interval with its counts and whether it was open
+	for i := 0; i < 5; i++ {
+		m.UpdateStatus(true)
+	}
+	clock.Advance(time.Minute)
+	m.UpdateStatus(true)
+	m.UpdateStatus(false)
+	m.UpdateStatus(false)
+	m.UpdateStatus(false)
+	m.UpdateStatus(true)
+	clock.Advance(time.Minute)
+	m.UpdateStatus(true)
+	m.UpdateStatus(true)
+	clock.Advance(time.Minute)
+	assert.Equal(t, []WindowStats{
+		{StartTime: start, SuccessCount: 5, FailureCount: 0, WasOpen: false},
+		{StartTime: start + 60, SuccessCount: 2, FailureCount: 3, WasOpen: true},
+		{StartTime: start + 120, SuccessCount: 2, FailureCount: 0, WasOpen: false},
+	}, m.History())
+
+	// Test case 3: Run more intervals than the history keeps
+	// Expected output: Only the last 10 intervals, oldest first
+	for i := 0; i < 9; i++ {
+		for j := 0; j <= i; j++ {
+			m.UpdateStatus(false)
+		}
+		clock.Advance(time.Minute)
+	}
+	history := m.History()
+	assert.Len(t, history, 10)
+	assert.Equal(t, WindowStats{StartTime: start + 120, SuccessCount: 2}, history[0])
+	for i, window := range history[1:] {
+		assert.Equal(t, start+180+int64(i)*60, window.StartTime)
+		assert.Equal(t, int64(i+1), window.FailureCount)
+		assert.Equal(t, i+1 >= 3, window.WasOpen)
+	}
+
+	// Test case 4: Modify the returned history
+	// Expected output: The circuit's history is unchanged
+	history[0].SuccessCount = 100
+	assert.Equal(t, int64(2), m.History()[0].SuccessCount)
+}
+
+func TestTransitions(t *testing.T) {
+	clock := NewFakeClock(time.Unix(1700000000, 0))
+	m, err := ConfigureCircuit(CircuitOptions{
+		Name:              "TEST_Transitions",
+		Threshold:         2,
+		ThresholdType:     ThresholdConsecutive,
+		IntervalInSeconds: 60,
+		RecordHistory:     3,
+		Clock:             clock,
+	})
+	assert.NoError(t, err)
+	defer m.Close()
+
+	// Test case 1: Trip the circuit and let it recover
+	// Expected output: Both transitions are recorded in order with their counts
+	assert.Empty(t, m.Transitions())
+	m.UpdateStatus(true)
+	m.UpdateStatus(false)
+	m.UpdateStatus(false)
+	clock.Advance(time.Second)
+	m.UpdateStatus(true)
+	assert.Equal(t, []TransitionRecord{
+		{Timestamp: 1700000000, Time: time.Unix(1700000000, 0), FromState: StateClosed, ToState: StateOpen, Reason: ReasonTripped, SuccessCount: 1, FailureCount: 2, ConsecutiveCounter: 2},
+		{Timestamp: 1700000001, Time: time.Unix(1700000001, 0), FromState: StateOpen, ToState: StateClosed, Reason: ReasonRecovered, SuccessCount: 2, FailureCount: 2, ConsecutiveCounter: 0},
+	}, m.Transitions())
+
+	// Test case 2: Record more transitions than RecordHistory
+	// Expected output: Only the last RecordHistory transitions are kept, oldest first
+	m.UpdateStatus(false)
+	m.UpdateStatus(false)
+	m.Reset()
+	transitions := m.Transitions()
+	if assert.Len(t, transitions, 3) {
+		assert.Equal(t, ReasonRecovered, transitions[0].Reason)
+		assert.Equal(t, ReasonTripped, transitions[1].Reason)
+		assert.Equal(t, ReasonManualReset, transitions[2].Reason)
+	}
+
+	// Test case 3: Lower RecordHistory with UpdateOptions
+	// Expected output: Only the newest transition is returned
+	options := m.GetOptions()
+	options.RecordHistory = 1
+	assert.NoError(t, m.UpdateOptions(options))
+	transitions = m.Transitions()
+	if assert.Len(t, transitions, 1) {
+		assert.Equal(t, ReasonManualReset, transitions[0].Reason)
+	}
+}
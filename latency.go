@@ -0,0 +1,147 @@
+package tripper
+
+import (
+	"math"
+	"sync/atomic"
+	"time"
+)
+
+// latencyBuckets are the upper bounds of the buckets of the latency
+// histogram. Slower calls fall into a last bucket bounded by the maximum.
+var latencyBuckets = [...]time.Duration{
+	time.Millisecond,
+	2 * time.Millisecond,
+	5 * time.Millisecond,
+	10 * time.Millisecond,
+	25 * time.Millisecond,
+	50 * time.Millisecond,
+	100 * time.Millisecond,
+	250 * time.Millisecond,
+	500 * time.Millisecond,
+	time.Second,
+	2500 * time.Millisecond,
+	5 * time.Second,
+	10 * time.Second,
+}
+
+// LatencySummary summarizes the latencies recorded in the current interval.
+// The percentiles are the upper bounds of the histogram buckets they fall
+// into, between 1ms and 10s, capped at Max; all fields are zero when no
+// latency was recorded.
+type LatencySummary struct {
+	Count int64         // Number of calls recorded with a latency
+	Min   time.Duration // Shortest latency recorded
+	Max   time.Duration // Longest latency recorded
+	P50   time.Duration // Median latency
+	P95   time.Duration // 95th percentile of the latencies
+	P99   time.Duration // 99th percentile of the latencies
+}
+
+// latencyHistogram counts latencies in fixed buckets. It is updated with
+// sync/atomic, so events recorded under the read lock can add to it.
+type latencyHistogram struct {
+	min     int64 // Shortest latency in nanoseconds (0 when none was recorded)
+	max     int64 // Longest latency in nanoseconds
+	buckets [len(latencyBuckets) + 1]int64
+}
+
+// UpdateStatusWithLatency updates the status of the Circuit based on the
+// success of the event and how long it took. Calls slower than
+// SlowCallThreshold are counted as slow even when they succeed. Events are
+// ignored once the circuit was closed with Close.
+func (m *CircuitImplementation) UpdateStatusWithLatency(success bool, latency time.Duration) {
+	m.updateStatus(success, latency, weightScale, "")
+}
+
+// slowCallRateBreached reports whether the percentage of slow calls reaches
+// SlowCallRateThreshold. The caller must hold m.Mutex for reading.
+func (m *CircuitImplementation) slowCallRateBreached() bool {
+	if m.Options.SlowCallThreshold <= 0 || m.Options.SlowCallRateThreshold <= 0 {
+		return false
+	}
+	totalRequests := float64(atomic.LoadInt64(&m.SuccessCount)) + float64(atomic.LoadInt64(&m.FailureCount))
+	if totalRequests == 0 {
+		return false
+	}
+	slowCallPercentage := float64(m.SlowCallCount) / totalRequests * 100
+	return slowCallPercentage >= float64(m.Options.SlowCallRateThreshold)
+}
+
+// badCallRateBreached reports whether the percentage of calls that failed or
+// were slow reaches BadCallRateThreshold. A slow failure is counted once. The
+// caller must hold m.Mutex for reading.
+func (m *CircuitImplementation) badCallRateBreached() bool {
+	if m.Options.BadCallRateThreshold <= 0 {
+		return false
+	}
+	failureCount := atomic.LoadInt64(&m.FailureCount)
+	totalRequests := float64(atomic.LoadInt64(&m.SuccessCount)) + float64(failureCount)
+	if totalRequests == 0 {
+		return false
+	}
+	badCallPercentage := (float64(failureCount) + float64(m.SlowSuccessCount)) / totalRequests * 100
+	return badCallPercentage >= float64(m.Options.BadCallRateThreshold)
+}
+
+// record adds a latency to the histogram. Events without a latency, e.g. from
+// UpdateStatus, are ignored.
+func (h *latencyHistogram) record(latency time.Duration) {
+	if latency <= 0 {
+		return
+	}
+	bucket := len(latencyBuckets)
+	for i, bound := range latencyBuckets {
+		if latency <= bound {
+			bucket = i
+			break
+		}
+	}
+	atomic.AddInt64(&h.buckets[bucket], 1)
+	for {
+		min := atomic.LoadInt64(&h.min)
+		if (min != 0 && min <= int64(latency)) || atomic.CompareAndSwapInt64(&h.min, min, int64(latency)) {
+			break
+		}
+	}
+	for {
+		max := atomic.LoadInt64(&h.max)
+		if max >= int64(latency) || atomic.CompareAndSwapInt64(&h.max, max, int64(latency)) {
+			break
+		}
+	}
+}
+
+// summary returns the count, extremes and percentiles of the histogram.
+func (h *latencyHistogram) summary() LatencySummary {
+	var buckets [len(latencyBuckets) + 1]int64
+	var count int64
+	for i := range buckets {
+		buckets[i] = atomic.LoadInt64(&h.buckets[i])
+		count += buckets[i]
+	}
+	if count == 0 {
+		return LatencySummary{}
+	}
+	max := time.Duration(atomic.LoadInt64(&h.max))
+	percentile := func(p float64) time.Duration {
+		rank := int64(math.Ceil(p / 100 * float64(count)))
+		var seen int64
+		for i, bound := range latencyBuckets {
+			if seen += buckets[i]; seen >= rank {
+				if bound < max {
+					return bound
+				}
+				return max
+			}
+		}
+		return max
+	}
+	return LatencySummary{
+		Count: count,
+		Min:   time.Duration(atomic.LoadInt64(&h.min)),
+		Max:   max,
+		P50:   percentile(50),
+		P95:   percentile(95),
+		P99:   percentile(99),
+	}
+}
@@ -0,0 +1,196 @@
+package tripper
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUpdateStatusWithLatency(t *testing.T) {
+	monitorOptions := CircuitOptions{
+		Name:                  "TEST_SlowCalls",
+		Threshold:             50,
+		ThresholdType:         ThresholdPercentage,
+		MinimumCount:          4,
+		IntervalInSeconds:     60,
+		SlowCallThreshold:     100 * time.Millisecond,
+		SlowCallRateThreshold: 50,
+	}
+	m, err := ConfigureCircuit(monitorOptions)
+	assert.NoError(t, err)
+
+	// Test case 1: Fast successful calls are not slow
+	// Expected output: No slow calls and circuit closed
+	m.UpdateStatusWithLatency(true, 10*time.Millisecond)
+	m.UpdateStatusWithLatency(true, 100*time.Millisecond)
+	assert.Equal(t, int64(0), m.Data().SlowCallCount)
+
+	// Test case 2: Successful but slow calls count as slow
+	// Expected output: Circuit open once half the calls are slow
+	m.UpdateStatusWithLatency(true, 150*time.Millisecond)
+	assert.False(t, m.IsCircuitOpen())
+	m.UpdateStatusWithLatency(true, time.Second)
+	assert.Equal(t, int64(2), m.Data().SlowCallCount)
+	assert.Equal(t, int64(0), m.Data().FailureCount)
+	assert.True(t, m.IsCircuitOpen())
+
+	// Test case 3: Fast calls bring the slow rate back below the threshold
+	// Expected output: Circuit closed
+	m.UpdateStatus(true)
+	assert.False(t, m.IsCircuitOpen())
+}
+
+func TestSlowCallValidation(t *testing.T) {
+	monitorOptions := CircuitOptions{
+		Name:              "TEST_SlowCallValidation",
+		Threshold:         50,
+		ThresholdType:     ThresholdPercentage,
+		MinimumCount:      4,
+		IntervalInSeconds: 60,
+		SlowCallThreshold: time.Second,
+	}
+
+	// Test case 1: A slow call threshold without a rate is rejected
+	// Expected output: An error
+	_, err := ConfigureCircuit(monitorOptions)
+	assert.EqualError(t, err, "invalid slow call rate threshold 0.000000")
+
+	// Test case 2: A negative slow call threshold is rejected
+	// Expected output: An error
+	monitorOptions.SlowCallThreshold = -time.Second
+	_, err = ConfigureCircuit(monitorOptions)
+	assert.EqualError(t, err, "invalid slow call threshold -1s")
+}
+
+func TestBadCallRate(t *testing.T) {
+	m, err := ConfigureCircuit(CircuitOptions{
+		Name:                 "TEST_BadCallRate",
+		Threshold:            90,
+		ThresholdType:        ThresholdPercentage,
+		MinimumCount:         10,
+		IntervalInSeconds:    60,
+		SlowCallThreshold:    100 * time.Millisecond,
+		BadCallRateThreshold: 50,
+		Clock:                NewFakeClock(time.Unix(1700000000, 0)),
+	})
+	assert.NoError(t, err)
+	defer m.Close()
+
+	// Test case 1: Mix fast failures, slow successes and fast successes for a 40% bad rate
+	// Expected output: The circuit stays closed
+	for i := 0; i < 2; i++ {
+		m.UpdateStatusWithLatency(false, 10*time.Millisecond)
+		m.UpdateStatusWithLatency(true, time.Second)
+	}
+	for i := 0; i < 6; i++ {
+		m.UpdateStatusWithLatency(true, 10*time.Millisecond)
+	}
+	assert.False(t, m.IsCircuitOpen())
+
+	// Test case 2: Record a slow failure
+	// Expected output: It counts as one bad call and the circuit stays closed at 5 of 11
+	m.UpdateStatusWithLatency(false, time.Second)
+	assert.False(t, m.IsCircuitOpen())
+
+	// Test case 3: Record a slow success
+	// Expected output: The failures and slow calls together reach the bad rate although neither alone trips the circuit
+	m.UpdateStatusWithLatency(true, time.Second)
+	assert.True(t, m.IsCircuitOpen())
+	data := m.Data()
+	assert.Equal(t, int64(3), data.FailureCount)
+	assert.Equal(t, int64(4), data.SlowCallCount)
+	assert.Equal(t, int64(6), data.BadCallCount)
+
+	// Test case 4: Fast successes bring the bad rate back below the threshold
+	// Expected output: The circuit closes
+	m.UpdateStatusWithLatency(true, 10*time.Millisecond)
+	assert.False(t, m.IsCircuitOpen())
+}
+
+func TestBadCallRateValidation(t *testing.T) {
+	monitorOptions := CircuitOptions{
+		Name:                 "TEST_BadCallRateValidation",
+		Threshold:            50,
+		ThresholdType:        ThresholdPercentage,
+		MinimumCount:         4,
+		IntervalInSeconds:    60,
+		BadCallRateThreshold: 50,
+		Clock:                NewFakeClock(time.Unix(1700000000, 0)),
+	}
+
+	// Test case 1: A bad call rate without a slow call threshold is rejected
+	// Expected output: An error
+	_, err := ConfigureCircuit(monitorOptions)
+	assert.EqualError(t, err, "invalid bad call rate threshold 50.000000")
+
+	// Test case 2: A bad call rate with a slow call threshold but no slow call rate
+	// Expected output: No error
+	monitorOptions.SlowCallThreshold = time.Second
+	m, err := ConfigureCircuit(monitorOptions)
+	assert.NoError(t, err)
+	m.Close()
+
+	// Test case 3: A bad call rate above 100 is rejected
+	// Expected output: An error
+	monitorOptions.BadCallRateThreshold = 101
+	_, err = ConfigureCircuit(monitorOptions)
+	assert.EqualError(t, err, "invalid bad call rate threshold 101.000000")
+}
+
+func TestLatencySummary(t *testing.T) {
+	m, err := ConfigureCircuit(CircuitOptions{
+		Name:              "TEST_LatencySummary",
+		Threshold:         50,
+		ThresholdType:     ThresholdPercentage,
+		MinimumCount:      10,
+		IntervalInSeconds: 60,
+		Clock:             NewFakeClock(time.Unix(1700000000, 0)),
+	})
+	assert.NoError(t, err)
+	defer m.Close()
+
+	// Test case 1: Record events without a latency
+	// Expected output: The summary is empty
+	m.UpdateStatus(true)
+	assert.Equal(t, LatencySummary{}, m.Data().Latency)
+
+	// Test case 2: Record 90 fast, 8 slower and 2 very slow calls
+	// Expected output: The percentiles fall into the buckets of those calls
+	for i := 0; i < 90; i++ {
+		m.UpdateStatusWithLatency(true, 3*time.Millisecond)
+	}
+	for i := 0; i < 8; i++ {
+		m.UpdateStatusWithLatency(true, 80*time.Millisecond)
+	}
+	m.UpdateStatusWithLatency(false, 400*time.Millisecond)
+	m.UpdateStatusWithLatency(false, 12*time.Second)
+	assert.Equal(t, LatencySummary{
+		Count: 100,
+		Min:   3 * time.Millisecond,
+		Max:   12 * time.Second,
+		P50:   5 * time.Millisecond,
+		P95:   100 * time.Millisecond,
+		P99:   500 * time.Millisecond,
+	}, m.Data().Latency)
+
+	// Test case 3: Reset the window
+	// Expected output: The summary is cleared with the counts
+	m.ResetWindow()
+	m.UpdateStatusWithLatency(true, 700*time.Millisecond)
+	assert.Equal(t, LatencySummary{
+		Count: 1,
+		Min:   700 * time.Millisecond,
+		Max:   700 * time.Millisecond,
+		P50:   700 * time.Millisecond,
+		P95:   700 * time.Millisecond,
+		P99:   700 * time.Millisecond,
+	}, m.Data().Latency)
+
+	// Test case 4: Encode the data as JSON
+	// Expected output: The summary is included in milliseconds
+	encoded, err := json.Marshal(m.Data())
+	assert.NoError(t, err)
+	assert.Contains(t, string(encoded), `"latency_count":1,"latency_min_ms":700,"latency_max_ms":700,"latency_p50_ms":700,"latency_p95_ms":700,"latency_p99_ms":700`)
+}
@@ -0,0 +1,21 @@
+package tripper
+
+// Logger receives a line for every state transition of a circuit. The
+// standard library *log.Logger satisfies it.
+type Logger interface {
+	Printf(format string, args ...interface{})
+}
+
+// noopLogger is the Logger used when CircuitOptions.Logger is not set.
+type noopLogger struct{}
+
+func (noopLogger) Printf(format string, args ...interface{}) {}
+
+// logTransition logs a state transition with the circuit name and counts.
+func logTransition(logger Logger, event CallbackEvent) {
+	if logger == nil {
+		return
+	}
+	logger.Printf("tripper: circuit %s changed from %s to %s (successes: %d, failures: %d)",
+		event.Name, event.FromState, event.ToState, event.SuccessCount, event.FailureCount)
+}
@@ -0,0 +1,91 @@
+package tripper
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// capturingLogger records every line written to it.
+type capturingLogger struct {
+	mu    sync.Mutex
+	lines []string
+}
+
+func (l *capturingLogger) Printf(format string, args ...interface{}) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.lines = append(l.lines, fmt.Sprintf(format, args...))
+}
+
+func (l *capturingLogger) Lines() []string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return append([]string(nil), l.lines...)
+}
+
+func TestLogger(t *testing.T) {
+	clock := NewFakeClock(time.Unix(1700000000, 0))
+	logger := &capturingLogger{}
+	circuit, err := NewCircuit("payments",
+		WithConsecutiveThreshold(2),
+		WithMinimumCount(1),
+		WithInterval(60),
+		WithClock(clock),
+		WithLogger(logger),
+	)
+	assert.NoError(t, err)
+	defer circuit.Close()
+
+	// Test case 1: Record updates that do not change the state
+	// Expected output: Nothing is logged
+	circuit.UpdateStatus(true)
+	circuit.UpdateStatus(false)
+	assert.Empty(t, logger.Lines())
+
+	// Test case 2: Trip the circuit
+	// Expected output: The transition is logged with the name and counts
+	circuit.UpdateStatus(false)
+	assert.Equal(t, []string{
+		"tripper: circuit payments changed from CLOSED to OPEN (successes: 1, failures: 2)",
+	}, logger.Lines())
+
+	// Test case 3: Record more failures while open
+	// Expected output: Nothing else is logged
+	circuit.UpdateStatus(false)
+	assert.Len(t, logger.Lines(), 1)
+
+	// Test case 4: Close the circuit with the interval reset
+	// Expected output: The transition back to closed is logged
+	clock.Advance(60 * time.Second)
+	assert.Equal(t, "tripper: circuit payments changed from OPEN to CLOSED (successes: 0, failures: 0)", logger.Lines()[1])
+}
+
+func TestLoggerDefault(t *testing.T) {
+	// Test case 1: Trip a circuit without a Logger
+	// Expected output: No panic
+	circuit, err := NewCircuit("default",
+		WithConsecutiveThreshold(1),
+		WithMinimumCount(1),
+		WithInterval(60),
+		WithClock(NewFakeClock(time.Unix(1700000000, 0))),
+	)
+	assert.NoError(t, err)
+	defer circuit.Close()
+	circuit.UpdateStatus(false)
+	assert.True(t, circuit.IsCircuitOpen())
+
+	// Test case 2: Remove the Logger with UpdateOptions
+	// Expected output: No error and no panic on the next transition
+	assert.NoError(t, circuit.UpdateOptions(CircuitOptions{
+		Name:              "default",
+		Threshold:         1,
+		ThresholdType:     ThresholdConsecutive,
+		MinimumCount:      1,
+		IntervalInSeconds: 60,
+	}))
+	circuit.UpdateStatus(true)
+}
@@ -0,0 +1,134 @@
+package tripper
+
+import (
+	"context"
+	"time"
+)
+
+// noopCircuit is a Circuit that records nothing and never opens.
+type noopCircuit struct{}
+
+// NewNoopCircuit returns a Circuit that never opens, e.g. to disable circuit
+// breaking through configuration or in tests. Updates are ignored, Data
+// returns zeroes and Execute always calls fn.
+func NewNoopCircuit() Circuit {
+	return noopCircuit{}
+}
+
+func (noopCircuit) UpdateStatus(success bool) {}
+
+func (noopCircuit) UpdateStatusE(success bool) error {
+	return nil
+}
+
+func (noopCircuit) ResetWindow() {}
+
+func (noopCircuit) Reset() {}
+
+func (noopCircuit) UpdateStatusExt(success bool, record bool) {}
+
+func (noopCircuit) UpdateStatusErr(err error) {}
+
+func (noopCircuit) UpdateStatusWeighted(success bool, weight float64) {}
+
+func (noopCircuit) UpdateStatusWithLatency(success bool, latency time.Duration) {}
+
+func (noopCircuit) UpdateStatusBatch(successes, failures int64) {}
+
+func (noopCircuit) RecordAttempts(successes, failures int64, finalSuccess bool) {}
+
+func (noopCircuit) UpdateFromHTTPStatus(code int) {}
+
+func (noopCircuit) Execute(fn func() error) error {
+	return fn()
+}
+
+func (noopCircuit) ExecuteContext(ctx context.Context, fn func(context.Context) error) error {
+	return fn(ctx)
+}
+
+func (noopCircuit) ProbeOnce(fn func() error) (bool, error) {
+	err := fn()
+	return err == nil, err
+}
+
+func (noopCircuit) IsCircuitOpen() bool {
+	return false
+}
+
+func (noopCircuit) AllowRequest() bool {
+	return true
+}
+
+func (noopCircuit) AllowProbe() (bool, func()) {
+	return true, func() {}
+}
+
+func (noopCircuit) SetEnabled(enabled bool) {}
+
+func (noopCircuit) Data() CircuitData {
+	return CircuitData{}
+}
+
+func (noopCircuit) State() CircuitState {
+	return StateClosed
+}
+
+func (noopCircuit) OpenedAt() time.Time {
+	return time.Time{}
+}
+
+func (noopCircuit) TimeUntilReset() time.Duration {
+	return 0
+}
+
+func (noopCircuit) TimeUntilHalfOpen() time.Duration {
+	return 0
+}
+
+func (noopCircuit) History() []WindowStats {
+	return nil
+}
+
+func (noopCircuit) Transitions() []TransitionRecord {
+	return nil
+}
+
+func (noopCircuit) Snapshot() CircuitSnapshot {
+	return CircuitSnapshot{}
+}
+
+func (noopCircuit) Name() string {
+	return ""
+}
+
+func (noopCircuit) GetOptions() CircuitOptions {
+	return CircuitOptions{}
+}
+
+func (noopCircuit) Clone(name string, opts ...Option) (Circuit, error) {
+	return noopCircuit{}, nil
+}
+
+func (noopCircuit) Subscribe() <-chan CallbackEvent {
+	// no state change is ever published
+	events := make(chan CallbackEvent)
+	close(events)
+	return events
+}
+
+func (noopCircuit) Unsubscribe(events <-chan CallbackEvent) {}
+
+func (noopCircuit) UpdateOptions(monitorOptions CircuitOptions) error {
+	return nil
+}
+
+func (noopCircuit) MarshalState() ([]byte, error) {
+	return []byte("{}"), nil
+}
+
+func (noopCircuit) RestoreState(data []byte) error {
+	return nil
+}
+
+func (noopCircuit) Close() {}
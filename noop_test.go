@@ -0,0 +1,112 @@
+package tripper
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNoopCircuit(t *testing.T) {
+	circuit := NewNoopCircuit()
+
+	// Test case 1: Record any number of failures
+	// Expected output: The circuit never opens and reports zeroes
+	for i := 0; i < 1000; i++ {
+		circuit.UpdateStatus(false)
+		circuit.UpdateStatusWithLatency(false, time.Hour)
+		circuit.UpdateStatusWeighted(false, 10)
+		circuit.UpdateStatusBatch(10, 10)
+		circuit.RecordAttempts(1, 2, true)
+		circuit.UpdateStatusExt(false, true)
+		circuit.UpdateStatusErr(errors.New("failed"))
+		assert.NoError(t, circuit.UpdateStatusE(false))
+	}
+	assert.False(t, circuit.IsCircuitOpen())
+	assert.True(t, circuit.AllowRequest())
+	allowed, release := circuit.AllowProbe()
+	assert.True(t, allowed)
+	release()
+	assert.Equal(t, StateClosed, circuit.State())
+	assert.Equal(t, CircuitData{}, circuit.Data())
+	assert.Empty(t, circuit.History())
+	assert.Empty(t, circuit.Transitions())
+	assert.Equal(t, CircuitSnapshot{}, circuit.Snapshot())
+
+	// Test case 2: Execute calls through the circuit
+	// Expected output: fn is always called and its error returned
+	errService := errors.New("service failed")
+	for i := 0; i < 10; i++ {
+		assert.Equal(t, errService, circuit.Execute(func() error { return errService }))
+	}
+	called := false
+	assert.NoError(t, circuit.ExecuteContext(context.Background(), func(context.Context) error {
+		called = true
+		return nil
+	}))
+	assert.True(t, called)
+	ok, err := circuit.ProbeOnce(func() error { return errService })
+	assert.False(t, ok)
+	assert.Equal(t, errService, err)
+
+	// Test case 3: Persist, reconfigure and close the circuit
+	// Expected output: No errors
+	data, err := circuit.MarshalState()
+	assert.NoError(t, err)
+	assert.NoError(t, circuit.RestoreState(data))
+	assert.NoError(t, circuit.UpdateOptions(CircuitOptions{}))
+	assert.Empty(t, circuit.Name())
+	circuit.Close()
+	assert.False(t, circuit.IsCircuitOpen())
+}
+
+func TestNilCircuit(t *testing.T) {
+	var circuit *CircuitImplementation
+
+	// Test case 1: Record updates on a nil circuit
+	// Expected output: No panic, the circuit stays closed and reports zeroes
+	circuit.SetEnabled(false)
+	circuit.UpdateStatus(false)
+	circuit.UpdateStatusWithLatency(false, time.Hour)
+	circuit.UpdateStatusWeighted(false, 10)
+	circuit.UpdateStatusBatch(10, 10)
+	circuit.RecordAttempts(1, 2, true)
+	circuit.UpdateStatusExt(false, true)
+	circuit.UpdateStatusErr(errors.New("failed"))
+	assert.NoError(t, circuit.UpdateStatusE(false))
+	assert.False(t, circuit.IsCircuitOpen())
+	assert.True(t, circuit.AllowRequest())
+	allowed, release := circuit.AllowProbe()
+	assert.True(t, allowed)
+	release()
+	assert.Equal(t, StateClosed, circuit.State())
+	assert.Equal(t, CircuitData{}, circuit.Data())
+	assert.Empty(t, circuit.History())
+	assert.Empty(t, circuit.Transitions())
+	assert.Equal(t, CircuitSnapshot{}, circuit.Snapshot())
+
+	// Test case 2: Execute through a nil circuit
+	// Expected output: fn is always called and its error returned
+	errService := errors.New("service failed")
+	assert.Equal(t, errService, circuit.Execute(func() error { return errService }))
+
+	// Test case 3: Reserve a MaxConcurrent slot on a nil circuit, like CircuitTransport does
+	// Expected output: The slot is granted and given back without a panic
+	assert.True(t, circuit.acquireSlot())
+	circuit.releaseSlot()
+
+	// Test case 4: Persist, reconfigure and close a nil circuit
+	// Expected output: No errors
+	data, err := circuit.MarshalState()
+	assert.NoError(t, err)
+	assert.NoError(t, circuit.RestoreState(data))
+	assert.NoError(t, circuit.UpdateOptions(CircuitOptions{}))
+	assert.Equal(t, CircuitOptions{}, circuit.GetOptions())
+	assert.Empty(t, circuit.Name())
+	clone, err := circuit.Clone("clone")
+	assert.NoError(t, err)
+	assert.False(t, clone.IsCircuitOpen())
+	circuit.Close()
+}
@@ -0,0 +1,137 @@
+package tripper
+
+import (
+	"reflect"
+	"time"
+)
+
+// Name returns the Name the circuit was configured with, e.g. to log it or
+// key metrics by it.
+func (m *CircuitImplementation) Name() string {
+	if m == nil {
+		return noopCircuit{}.Name()
+	}
+
+	m.Mutex.RLock()
+	defer m.Mutex.RUnlock()
+
+	return m.Options.Name
+}
+
+// GetOptions returns a copy of the effective options of the circuit, including
+// the defaults filled in by ConfigureCircuit. Thresholds, FailureStatusCodes
+// and ThresholdStrategies are copied as well, so that changing them in the
+// copy does not change the circuit. Rand is left nil when it was
+// seeded by ConfigureCircuit, so that circuits configured from the copy each
+// seed their own instead of sharing one.
+func (m *CircuitImplementation) GetOptions() CircuitOptions {
+	if m == nil {
+		return noopCircuit{}.GetOptions()
+	}
+
+	m.Mutex.RLock()
+	defer m.Mutex.RUnlock()
+
+	options := m.Options
+	options.Thresholds = append([]ThresholdRule(nil), m.Options.Thresholds...)
+	options.FailureStatusCodes = append([]int(nil), m.Options.FailureStatusCodes...)
+	if m.Options.ThresholdStrategies != nil {
+		options.ThresholdStrategies = make(map[string]ThresholdStrategy, len(m.Options.ThresholdStrategies))
+		for name, strategy := range m.Options.ThresholdStrategies {
+			options.ThresholdStrategies[name] = strategy
+		}
+	}
+	if m.randDefaulted {
+		options.Rand = nil
+	}
+	return options
+}
+
+// thresholdFields are the fields that describe the threshold rules of a
+// circuit, which withDefaults inherits together or not at all.
+var thresholdFields = map[string]bool{
+	"Threshold":      true,
+	"ThresholdType":  true,
+	"CloseThreshold": true,
+	"Thresholds":     true,
+}
+
+// withDefaults returns the options with every field left at its zero value
+// taken from defaults, so that explicit fields win. Name is never inherited,
+// and neither is Rand, which must not be shared between circuits. Threshold,
+// ThresholdType, CloseThreshold and Thresholds are only inherited when none of
+// them is set, so that the Thresholds of defaults do not replace an explicit
+// Threshold. A bool set in defaults cannot be turned off, as false is its
+// zero value.
+func (o CircuitOptions) withDefaults(defaults CircuitOptions) CircuitOptions {
+	options := reflect.ValueOf(&o).Elem()
+	fallback := reflect.ValueOf(defaults)
+	explicitThreshold := false
+	for name := range thresholdFields {
+		if !options.FieldByName(name).IsZero() {
+			explicitThreshold = true
+		}
+	}
+	for i := 0; i < options.NumField(); i++ {
+		name := options.Type().Field(i).Name
+		if name == "Name" || name == "Rand" || (explicitThreshold && thresholdFields[name]) {
+			continue
+		}
+		if field := options.Field(i); field.IsZero() {
+			field.Set(fallback.Field(i))
+		}
+	}
+	return o
+}
+
+// UpdateOptions replaces the options of the circuit at runtime without
+// resetting the recorded counts. The new options are validated first and take
+// effect on the next UpdateStatus. Changing IntervalInSeconds restarts the
+// interval from now, even with AlignToWallClock. Name cannot be changed, and
+// Clock, AsyncCallbacks, ManualReset, AlignToWallClock and Context keep the
+// values the circuit was configured with. Rand is kept unless a new one is
+// given, while a nil Logger turns logging off. A half-open circuit whose
+// probes already reached a lowered HalfOpenMaxProbes, e.g. 0, closes right
+// away, as it could not admit the probes that close it otherwise.
+func (m *CircuitImplementation) UpdateOptions(monitorOptions CircuitOptions) error {
+	if m == nil {
+		return noopCircuit{}.UpdateOptions(monitorOptions)
+	}
+
+	if err := monitorOptions.Validate(); err != nil {
+		return err
+	}
+
+	m.Mutex.Lock()
+	if monitorOptions.Name != m.Options.Name {
+		m.Mutex.Unlock()
+		return configError(ErrNameChanged, "Name", "circuit name cannot be changed from %s to %s", m.Options.Name, monitorOptions.Name)
+	}
+	monitorOptions.Clock = m.Options.Clock
+	if monitorOptions.Rand == nil {
+		monitorOptions.Rand = m.Options.Rand
+	} else {
+		m.randDefaulted = false
+	}
+	monitorOptions.AsyncCallbacks = m.Options.AsyncCallbacks
+	monitorOptions.ManualReset = m.Options.ManualReset
+	monitorOptions.AlignToWallClock = m.Options.AlignToWallClock
+	monitorOptions.Context = m.Options.Context
+	if monitorOptions.Logger == nil {
+		monitorOptions.Logger = noopLogger{}
+	}
+
+	intervalChanged := monitorOptions.IntervalInSeconds != m.Options.IntervalInSeconds
+	m.Options = monitorOptions
+	if intervalChanged {
+		if m.Ticker != nil {
+			m.Ticker.Reset(time.Duration(monitorOptions.IntervalInSeconds) * time.Second)
+		}
+		m.WindowStartedAt = m.now()
+	}
+	events := m.settleProbes(m.now())
+	m.Mutex.Unlock()
+
+	m.dispatch(events...)
+	return nil
+}
@@ -0,0 +1,172 @@
+package tripper
+
+import (
+	"math/rand"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUpdateOptions(t *testing.T) {
+	clock := NewFakeClock(time.Unix(1700000000, 0))
+	monitorOptions := CircuitOptions{
+		Name:              "TEST_UpdateOptions",
+		Threshold:         5,
+		MinimumCount:      6,
+		IntervalInSeconds: 60,
+		ThresholdType:     ThresholdCount,
+		Clock:             clock,
+	}
+	m, err := ConfigureCircuit(monitorOptions)
+	assert.NoError(t, err)
+
+	m.UpdateStatus(false)
+	m.UpdateStatus(false)
+	m.UpdateStatus(false)
+	m.UpdateStatus(true)
+	m.UpdateStatus(true)
+	m.UpdateStatus(true)
+	assert.False(t, m.IsCircuitOpen())
+
+	// Test case 1: Lowering the threshold takes effect on the next update without resetting counts
+	// Expected output: Counts kept and circuit open
+	monitorOptions.Threshold = 3
+	monitorOptions.MinimumCount = 4
+	assert.NoError(t, m.UpdateOptions(monitorOptions))
+	assert.Equal(t, int64(3), m.Data().FailureCount)
+	m.UpdateStatus(true)
+	assert.Equal(t, int64(4), m.Data().SuccessCount)
+	assert.True(t, m.IsCircuitOpen())
+
+	// Test case 2: Invalid options are rejected and the current ones are kept
+	// Expected output: An error and the circuit unchanged
+	invalid := monitorOptions
+	invalid.MinimumCount = 0
+	assert.EqualError(t, m.UpdateOptions(invalid), "invalid minimum count 0")
+	m.UpdateStatus(true)
+	assert.True(t, m.IsCircuitOpen())
+
+	// Test case 3: The name cannot be changed
+	// Expected output: An error
+	renamed := monitorOptions
+	renamed.Name = "renamed"
+	assert.EqualError(t, m.UpdateOptions(renamed), "circuit name cannot be changed from TEST_UpdateOptions to renamed")
+
+	// Test case 4: Changing the interval restarts it from now
+	// Expected output: No reset at the old interval, a reset at the new one
+	clock.Advance(30 * time.Second)
+	monitorOptions.IntervalInSeconds = 120
+	monitorOptions.Clock = nil
+	assert.NoError(t, m.UpdateOptions(monitorOptions))
+	clock.Advance(time.Minute)
+	assert.Equal(t, int64(3), m.Data().FailureCount)
+	clock.Advance(time.Minute)
+	assert.Equal(t, int64(0), m.Data().FailureCount)
+	assert.False(t, m.IsCircuitOpen())
+}
+
+func TestDataEchoesOptions(t *testing.T) {
+	monitorOptions := CircuitOptions{
+		Name:              "TEST_DataEchoesOptions",
+		Threshold:         5,
+		MinimumCount:      6,
+		IntervalInSeconds: 60,
+		ThresholdType:     ThresholdCount,
+		Clock:             NewFakeClock(time.Unix(1700000000, 0)),
+	}
+	m, err := ConfigureCircuit(monitorOptions)
+	assert.NoError(t, err)
+	defer m.Close()
+
+	// Test case 1: Read the data of a new circuit
+	// Expected output: The configured options are included
+	data := m.Data()
+	assert.Equal(t, float32(5), data.Threshold)
+	assert.Equal(t, ThresholdCount, data.ThresholdType)
+	assert.Equal(t, int64(6), data.MinimumCount)
+	assert.Equal(t, 60, data.IntervalInSeconds)
+
+	// Test case 2: Change the options at runtime
+	// Expected output: The data includes the new options
+	monitorOptions.Threshold = 40
+	monitorOptions.ThresholdType = ThresholdPercentage
+	monitorOptions.MinimumCount = 10
+	monitorOptions.IntervalInSeconds = 120
+	assert.NoError(t, m.UpdateOptions(monitorOptions))
+	data = m.Data()
+	assert.Equal(t, float32(40), data.Threshold)
+	assert.Equal(t, ThresholdPercentage, data.ThresholdType)
+	assert.Equal(t, int64(10), data.MinimumCount)
+	assert.Equal(t, 120, data.IntervalInSeconds)
+}
+
+func TestName(t *testing.T) {
+	circuit, err := ConfigureCircuit(CircuitOptions{
+		Name:              "TEST_Name",
+		Threshold:         2,
+		ThresholdType:     ThresholdConsecutive,
+		IntervalInSeconds: 60,
+		Clock:             NewFakeClock(time.Unix(1700000000, 0)),
+	})
+	assert.NoError(t, err)
+	defer circuit.Close()
+
+	// Test case 1: Read the name through the Circuit interface
+	// Expected output: The Name of the options is returned
+	assert.Equal(t, "TEST_Name", circuit.Name())
+
+	// Test case 2: Clone the circuit under another name
+	// Expected output: The clone has the new name and the original keeps its own
+	clone, err := circuit.Clone("TEST_Name_clone")
+	assert.NoError(t, err)
+	defer clone.Close()
+	assert.Equal(t, "TEST_Name_clone", clone.Name())
+	assert.Equal(t, "TEST_Name", circuit.Name())
+}
+
+func TestGetOptionsRand(t *testing.T) {
+	base, err := ConfigureCircuit(CircuitOptions{
+		Name:                  "TEST_GetOptionsRand",
+		Threshold:             1,
+		ThresholdType:         ThresholdConsecutive,
+		IntervalInSeconds:     60,
+		OpenDurationInSeconds: 30,
+		CooldownJitter:        0.5,
+	})
+	assert.NoError(t, err)
+	defer base.Close()
+
+	// Test case 1: Build two circuits from one options snapshot and trip them concurrently
+	// Expected output: Each seeds its own Rand, so the jitter is computed without a data race
+	// (the system clock is used as a shared FakeClock would synchronize the goroutines)
+	options := base.GetOptions()
+	assert.Nil(t, options.Rand)
+	var wg sync.WaitGroup
+	for i := 0; i < 2; i++ {
+		m, err := ConfigureCircuit(options)
+		assert.NoError(t, err)
+		defer m.Close()
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				m.UpdateStatus(false)
+				m.Reset()
+			}
+		}()
+	}
+	wg.Wait()
+
+	// Test case 2: Read back the options of a circuit configured with a Rand
+	// Expected output: The given Rand is returned, also after UpdateOptions
+	r := rand.New(rand.NewSource(1))
+	options.Rand = r
+	m, err := ConfigureCircuit(options)
+	assert.NoError(t, err)
+	defer m.Close()
+	assert.True(t, m.GetOptions().Rand == r)
+	assert.NoError(t, m.UpdateOptions(m.GetOptions()))
+	assert.True(t, m.GetOptions().Rand == r)
+}
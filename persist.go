@@ -0,0 +1,132 @@
+package tripper
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// persistedState is the JSON representation of a circuit used by MarshalState and RestoreState.
+type persistedState struct {
+	SuccessCount              int64            `json:"success_count"`
+	FailureCount              int64            `json:"failure_count"`
+	SlowCallCount             int64            `json:"slow_call_count"`
+	SlowSuccessCount          int64            `json:"slow_success_count,omitempty"`
+	ConsecutiveCounter        int64            `json:"consecutive_counter"`
+	ConsecutiveSuccessCounter int64            `json:"consecutive_success_counter"`
+	CircuitOpen               bool             `json:"circuit_open"`
+	HalfOpen                  bool             `json:"half_open,omitempty"`
+	CircuitOpenedSince        int64            `json:"circuit_opened_since"`
+	CurrentOpenDuration       int64            `json:"current_open_duration,omitempty"`
+	BackoffLevel              int64            `json:"backoff_level,omitempty"`
+	LastCapturedAt            int64            `json:"last_captured_at"`
+	TripCount                 int64            `json:"trip_count"`
+	LastStateChangedAt        int64            `json:"last_state_changed_at"`
+	WeightedSuccessCount      *float64         `json:"weighted_success_count,omitempty"`
+	WeightedFailureCount      *float64         `json:"weighted_failure_count,omitempty"`
+	FailuresByClass           map[string]int64 `json:"failures_by_class,omitempty"`
+}
+
+// MarshalState serializes the counts and state of the circuit as JSON so they
+// can be restored with RestoreState, e.g. after a process restart. The
+// timestamps are saved in Unix seconds.
+func (m *CircuitImplementation) MarshalState() ([]byte, error) {
+	if m == nil {
+		return noopCircuit{}.MarshalState()
+	}
+
+	m.Mutex.Lock()
+	defer m.Mutex.Unlock()
+
+	weightedSuccessCount := weightedCount(m.weightedSuccesses)
+	weightedFailureCount := weightedCount(m.weightedFailures)
+	return json.Marshal(persistedState{
+		SuccessCount:              m.SuccessCount,
+		FailureCount:              m.FailureCount,
+		SlowCallCount:             m.SlowCallCount,
+		SlowSuccessCount:          m.SlowSuccessCount,
+		ConsecutiveCounter:        m.ConsecutiveCounter,
+		ConsecutiveSuccessCounter: m.ConsecutiveSuccessCounter,
+		CircuitOpen:               m.CircuitOpen,
+		HalfOpen:                  m.HalfOpen,
+		CircuitOpenedSince:        unixSeconds(m.CircuitOpenedSince),
+		CurrentOpenDuration:       int64(time.Duration(m.CurrentOpenDuration).Round(time.Second) / time.Second),
+		BackoffLevel:              m.BackoffLevel,
+		LastCapturedAt:            unixSeconds(m.LastCapturedAt),
+		TripCount:                 m.TripCount,
+		LastStateChangedAt:        unixSeconds(m.LastStateChangedAt),
+		WeightedSuccessCount:      &weightedSuccessCount,
+		WeightedFailureCount:      &weightedFailureCount,
+		FailuresByClass:           m.failureClasses,
+	})
+}
+
+// RestoreState replaces the counts and state of the circuit with ones
+// previously returned by MarshalState. A circuit saved as open keeps its
+// CircuitOpenedSince, but if its open duration has passed since it opened it
+// is restored as closed, or half-open with HalfOpenMaxProbes, with empty
+// counts, just as it would have recovered had it kept running. The interval
+// reset is started like on the first use of the circuit. Callbacks are not
+// invoked.
+func (m *CircuitImplementation) RestoreState(data []byte) error {
+	if m == nil {
+		return noopCircuit{}.RestoreState(data)
+	}
+
+	var state persistedState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return err
+	}
+
+	// the state is saved with second precision
+	state.CircuitOpenedSince = fromUnixSeconds(state.CircuitOpenedSince)
+	state.CurrentOpenDuration = fromUnixSeconds(state.CurrentOpenDuration)
+	state.LastCapturedAt = fromUnixSeconds(state.LastCapturedAt)
+	state.LastStateChangedAt = fromUnixSeconds(state.LastStateChangedAt)
+
+	m.Mutex.Lock()
+	if state.CurrentOpenDuration <= 0 {
+		state.CurrentOpenDuration = m.openDuration()
+	}
+	if state.CircuitOpen && m.now()-state.CircuitOpenedSince >= state.CurrentOpenDuration {
+		state = persistedState{
+			LastCapturedAt:     state.LastCapturedAt,
+			TripCount:          state.TripCount,
+			BackoffLevel:       state.BackoffLevel,
+			HalfOpen:           m.Options.HalfOpenMaxProbes > 0,
+			LastStateChangedAt: state.CircuitOpenedSince + state.CurrentOpenDuration,
+		}
+	}
+	m.SuccessCount = state.SuccessCount
+	m.FailureCount = state.FailureCount
+	m.SlowCallCount = state.SlowCallCount
+	m.SlowSuccessCount = state.SlowSuccessCount
+	m.failureClasses = state.FailuresByClass
+	m.ConsecutiveCounter = state.ConsecutiveCounter
+	m.ConsecutiveSuccessCounter = state.ConsecutiveSuccessCounter
+	m.CircuitOpen = state.CircuitOpen
+	m.HalfOpen = state.HalfOpen
+	m.HalfOpenProbes = 0
+	m.HalfOpenSuccesses = 0
+	m.CircuitOpenedSince = state.CircuitOpenedSince
+	// the episode is assumed to start with the last state change
+	m.openEpisodeAt = state.LastStateChangedAt
+	m.CurrentOpenDuration = state.CurrentOpenDuration
+	m.BackoffLevel = state.BackoffLevel
+	m.LastCapturedAt = state.LastCapturedAt
+	m.TripCount = state.TripCount
+	m.LastStateChangedAt = state.LastStateChangedAt
+	// states saved before weighted updates existed count every event as 1
+	m.weightedSuccesses = countUnits(state.SuccessCount)
+	if state.WeightedSuccessCount != nil {
+		m.weightedSuccesses = weightUnits(*state.WeightedSuccessCount)
+	}
+	m.weightedFailures = countUnits(state.FailureCount)
+	if state.WeightedFailureCount != nil {
+		m.weightedFailures = weightUnits(*state.WeightedFailureCount)
+	}
+	m.Mutex.Unlock()
+
+	// a circuit restored as open must still close with the interval reset
+	m.startTicker()
+	return nil
+}
@@ -0,0 +1,93 @@
+package tripper
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newPersistCircuit(t *testing.T, clock *FakeClock) Circuit {
+	m, err := ConfigureCircuit(CircuitOptions{
+		Name:              "TEST_Persist",
+		Threshold:         3,
+		MinimumCount:      4,
+		IntervalInSeconds: 120,
+		ThresholdType:     ThresholdCount,
+		Clock:             clock,
+	})
+	assert.NoError(t, err)
+	return m
+}
+
+func TestStateRoundTrip(t *testing.T) {
+	clock := NewFakeClock(time.Unix(1700000000, 0))
+	m := newPersistCircuit(t, clock)
+	m.UpdateStatus(true)
+	m.UpdateStatus(false)
+	m.UpdateStatus(false)
+	m.UpdateStatus(false)
+	assert.True(t, m.IsCircuitOpen())
+
+	data, err := m.MarshalState()
+	assert.NoError(t, err)
+
+	// Test case 1: Restore into a new circuit before the interval has elapsed
+	// Expected output: Same counts, still open since the original time
+	clock.Advance(30 * time.Second)
+	restored := newPersistCircuit(t, clock)
+	assert.NoError(t, restored.RestoreState(data))
+	assert.Equal(t, m.Data(), restored.Data())
+	assert.True(t, restored.IsCircuitOpen())
+	assert.Equal(t, int64(1700000000), restored.Data().CircuitOpenedSince)
+
+	// Test case 2: The restored counts keep being evaluated
+	// Expected output: Failure streak continues from the restored value
+	restored.UpdateStatus(false)
+	assert.Equal(t, int64(4), restored.Data().FailureCount)
+	assert.Equal(t, int64(4), restored.Data().ConsecutiveCounter)
+
+	// Test case 3: Restore an open circuit after a full interval has passed since it opened
+	// Expected output: Closed with empty counts
+	clock.Advance(90 * time.Second)
+	late := newPersistCircuit(t, clock)
+	assert.NoError(t, late.RestoreState(data))
+	assert.False(t, late.IsCircuitOpen())
+	assert.Equal(t, int64(0), late.Data().FailureCount)
+	assert.Zero(t, late.Data().CircuitOpenedSince)
+
+	// Test case 4: Invalid data is rejected
+	// Expected output: An error and the circuit unchanged
+	assert.Error(t, restored.RestoreState([]byte("not json")))
+	assert.Equal(t, int64(4), restored.Data().FailureCount)
+}
+
+func TestRestoreOpenStateResets(t *testing.T) {
+	clock := NewFakeClock(time.Unix(1700000000, 0))
+	m := newPersistCircuit(t, clock)
+	restored := newPersistCircuit(t, clock)
+	defer restored.Close()
+
+	// Test case 1: Read the time until the reset of a circuit that was never used
+	// Expected output: Measured from when the circuit was configured
+	clock.Advance(90 * time.Second)
+	assert.Equal(t, 30*time.Second, restored.TimeUntilReset())
+	clock.Advance(120 * time.Second)
+	assert.Equal(t, 30*time.Second, restored.TimeUntilReset())
+
+	// Test case 2: Restore an open circuit without recording anything afterwards
+	// Expected output: The circuit closes with the next interval reset
+	m.UpdateStatus(true)
+	m.UpdateStatus(false)
+	m.UpdateStatus(false)
+	m.UpdateStatus(false)
+	data, err := m.MarshalState()
+	assert.NoError(t, err)
+	assert.NoError(t, restored.RestoreState(data))
+	assert.True(t, restored.IsCircuitOpen())
+	assert.Equal(t, 30*time.Second, restored.TimeUntilReset())
+	clock.Advance(30 * time.Second)
+	assert.False(t, restored.IsCircuitOpen())
+	assert.Equal(t, int64(0), restored.Data().FailureCount)
+	assert.Equal(t, 120*time.Second, restored.TimeUntilReset())
+}
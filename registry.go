@@ -0,0 +1,189 @@
+package tripper
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+)
+
+// TripperOptions represents options for configuring a Tripper.
+type TripperOptions struct {
+	Context               context.Context // Used as the Context of the circuits added without one, so that cancelling it closes all of them (disabled when nil)
+	DefaultCircuitOptions CircuitOptions  // Options inherited by the circuits added with AddMonitor or GetOrCreate for every field they leave at its zero value, except Name and Rand (the threshold fields are inherited together)
+}
+
+// Tripper is a registry of circuits identified by their name.
+type Tripper interface {
+	AddMonitor(monitorOptions CircuitOptions) (Circuit, error)
+	GetMonitor(name string) (Circuit, error)
+	GetOrCreate(monitorOptions CircuitOptions) (Circuit, error)
+	RemoveMonitor(name string) error
+	ListMonitors() []string
+	AggregateData(prefix string) CircuitData
+	ResetAll()
+	StopAll()
+	Handler() http.Handler
+}
+
+// TripperImplementation represents the implementation of the Tripper interface.
+type TripperImplementation struct {
+	Options  TripperOptions
+	Monitors map[string]Circuit // Registered circuits keyed by name
+	Mutex    sync.RWMutex
+}
+
+// Configure creates a new Tripper with the provided options.
+func Configure(tripperOptions TripperOptions) Tripper {
+	return &TripperImplementation{
+		Options:  tripperOptions,
+		Monitors: make(map[string]Circuit),
+	}
+}
+
+// AddMonitor configures a new circuit and registers it under its name.
+func (t *TripperImplementation) AddMonitor(monitorOptions CircuitOptions) (Circuit, error) {
+	t.Mutex.Lock()
+	defer t.Mutex.Unlock()
+
+	if _, ok := t.Monitors[monitorOptions.Name]; ok {
+		return nil, fmt.Errorf("Monitor with name %s already exists", monitorOptions.Name)
+	}
+	circuit, err := t.configureCircuit(monitorOptions)
+	if err != nil {
+		return nil, err
+	}
+	t.Monitors[monitorOptions.Name] = circuit
+	return circuit, nil
+}
+
+// configureCircuit configures a circuit with the fields the options leave at
+// their zero value taken from DefaultCircuitOptions, and with the Context of
+// the Tripper unless the options or the defaults have their own.
+func (t *TripperImplementation) configureCircuit(monitorOptions CircuitOptions) (Circuit, error) {
+	monitorOptions = monitorOptions.withDefaults(t.Options.DefaultCircuitOptions)
+	if monitorOptions.Context == nil {
+		monitorOptions.Context = t.Options.Context
+	}
+	return ConfigureCircuit(monitorOptions)
+}
+
+// GetMonitor returns the circuit registered under the given name.
+func (t *TripperImplementation) GetMonitor(name string) (Circuit, error) {
+	t.Mutex.RLock()
+	defer t.Mutex.RUnlock()
+
+	circuit, ok := t.Monitors[name]
+	if !ok {
+		return nil, fmt.Errorf("Monitor with name %s does not exist", name)
+	}
+	return circuit, nil
+}
+
+// GetOrCreate returns the circuit registered under the name of the options,
+// or configures and registers a new one if there is none. The lookup and the
+// registration happen under a single lock, so concurrent calls with the same
+// name all get the same circuit. The options of an existing circuit are not
+// changed.
+func (t *TripperImplementation) GetOrCreate(monitorOptions CircuitOptions) (Circuit, error) {
+	t.Mutex.Lock()
+	defer t.Mutex.Unlock()
+
+	if circuit, ok := t.Monitors[monitorOptions.Name]; ok {
+		return circuit, nil
+	}
+	circuit, err := t.configureCircuit(monitorOptions)
+	if err != nil {
+		return nil, err
+	}
+	t.Monitors[monitorOptions.Name] = circuit
+	return circuit, nil
+}
+
+// RemoveMonitor unregisters the circuit with the given name and closes it.
+func (t *TripperImplementation) RemoveMonitor(name string) error {
+	t.Mutex.Lock()
+	defer t.Mutex.Unlock()
+
+	circuit, ok := t.Monitors[name]
+	if !ok {
+		return fmt.Errorf("Monitor with name %s does not exist", name)
+	}
+	delete(t.Monitors, name)
+	circuit.Close()
+	return nil
+}
+
+// ListMonitors returns the names of all registered circuits in sorted order.
+func (t *TripperImplementation) ListMonitors() []string {
+	t.Mutex.RLock()
+	defer t.Mutex.RUnlock()
+
+	names := make([]string, 0, len(t.Monitors))
+	for name := range t.Monitors {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ResetAll resets every registered circuit with Reset, e.g. after recovering
+// from a wide outage. Circuits added concurrently may or may not be reset.
+// The callbacks of the circuits are invoked without holding the lock of the
+// Tripper, so they may use it.
+func (t *TripperImplementation) ResetAll() {
+	t.Mutex.RLock()
+	circuits := make([]Circuit, 0, len(t.Monitors))
+	for _, circuit := range t.Monitors {
+		circuits = append(circuits, circuit)
+	}
+	t.Mutex.RUnlock()
+
+	for _, circuit := range circuits {
+		circuit.Reset()
+	}
+}
+
+// StopAll closes every registered circuit during shutdown, stopping their
+// interval resets and callback goroutines. The circuits stay registered but
+// ignore further events.
+func (t *TripperImplementation) StopAll() {
+	t.Mutex.Lock()
+	defer t.Mutex.Unlock()
+
+	for _, circuit := range t.Monitors {
+		circuit.Close()
+	}
+}
+
+var (
+	defaultTripper     Tripper
+	defaultTripperOnce sync.Once
+)
+
+// Default returns the package-level Tripper used by Register, Get and Remove,
+// creating it on first use. Like every Tripper it is safe for concurrent use,
+// so small programs can share it instead of passing a Tripper around.
+func Default() Tripper {
+	defaultTripperOnce.Do(func() {
+		defaultTripper = Configure(TripperOptions{})
+	})
+	return defaultTripper
+}
+
+// Register configures a circuit and registers it in the default Tripper.
+func Register(monitorOptions CircuitOptions) (Circuit, error) {
+	return Default().AddMonitor(monitorOptions)
+}
+
+// Get returns the circuit registered in the default Tripper under name.
+func Get(name string) (Circuit, error) {
+	return Default().GetMonitor(name)
+}
+
+// Remove unregisters the circuit with the given name from the default Tripper
+// and closes it.
+func Remove(name string) error {
+	return Default().RemoveMonitor(name)
+}
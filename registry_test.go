@@ -0,0 +1,453 @@
+package tripper
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetMonitor(t *testing.T) {
+	tripperOpts := TripperOptions{}
+	tripper := Configure(tripperOpts)
+
+	// Test case 1: Get an existing monitor
+	// Expected output: Monitor and no error
+	monitorOptions := CircuitOptions{
+		Name:              "test",
+		Threshold:         65,
+		MinimumCount:      20,
+		IntervalInSeconds: 120,
+		ThresholdType:     ThresholdPercentage,
+	}
+	_, err := tripper.AddMonitor(monitorOptions)
+	assert.NoError(t, err)
+
+	m, err := tripper.GetMonitor("test")
+	assert.NoError(t, err)
+	assert.NotNil(t, m)
+
+	// Test case 2: Get a non-existing monitor
+	// Expected output: Error
+	_, err = tripper.GetMonitor("non-existing")
+	assert.Error(t, err)
+	assert.EqualError(t, err, "Monitor with name non-existing does not exist")
+
+	// Test case 3: Add a monitor with a name that is already registered
+	// Expected output: Error
+	_, err = tripper.AddMonitor(monitorOptions)
+	assert.EqualError(t, err, "Monitor with name test already exists")
+
+	// Test case 4: Add a monitor with invalid options
+	// Expected output: The validation error and nothing registered
+	monitorOptions.Name = "invalid"
+	monitorOptions.ThresholdType = "invalid"
+	_, err = tripper.AddMonitor(monitorOptions)
+	assert.EqualError(t, err, "invalid threshold type invalid")
+	_, err = tripper.GetMonitor("invalid")
+	assert.Error(t, err)
+}
+
+func TestGetOrCreate(t *testing.T) {
+	registry := Configure(TripperOptions{})
+	monitorOptions := CircuitOptions{
+		Name:              "shared",
+		Threshold:         2,
+		ThresholdType:     ThresholdConsecutive,
+		IntervalInSeconds: 60,
+		Clock:             NewFakeClock(time.Unix(1700000000, 0)),
+	}
+
+	// Test case 1: Get or create the same name from many goroutines
+	// Expected output: Exactly one circuit is created and every caller gets it
+	const callers = 50
+	circuits := make([]Circuit, callers)
+	var wg sync.WaitGroup
+	for i := 0; i < callers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			circuit, err := registry.GetOrCreate(monitorOptions)
+			assert.NoError(t, err)
+			circuits[i] = circuit
+		}(i)
+	}
+	wg.Wait()
+	assert.Equal(t, []string{"shared"}, registry.ListMonitors())
+	registered, err := registry.GetMonitor("shared")
+	assert.NoError(t, err)
+	for _, circuit := range circuits {
+		assert.True(t, circuit == registered)
+	}
+
+	// Test case 2: Get or create an existing name with other options
+	// Expected output: The existing circuit with its options unchanged
+	other := monitorOptions
+	other.Threshold = 5
+	circuit, err := registry.GetOrCreate(other)
+	assert.NoError(t, err)
+	assert.True(t, circuit == registered)
+	assert.Equal(t, float32(2), circuit.GetOptions().Threshold)
+
+	// Test case 3: Get or create a new name with invalid options
+	// Expected output: The validation error and nothing registered
+	other.Name = "invalid"
+	other.ThresholdType = "invalid"
+	_, err = registry.GetOrCreate(other)
+	assert.EqualError(t, err, "invalid threshold type invalid")
+	assert.Equal(t, []string{"shared"}, registry.ListMonitors())
+	registry.StopAll()
+}
+
+func TestRemoveMonitor(t *testing.T) {
+	clock := NewFakeClock(time.Unix(1700000000, 0))
+	tripper := Configure(TripperOptions{})
+	for _, name := range []string{"payments", "accounts", "search"} {
+		circuit, err := tripper.AddMonitor(CircuitOptions{
+			Name:              name,
+			Threshold:         50,
+			MinimumCount:      10,
+			IntervalInSeconds: 60,
+			ThresholdType:     ThresholdPercentage,
+			Clock:             clock,
+		})
+		assert.NoError(t, err)
+		circuit.UpdateStatus(true)
+	}
+	assert.Equal(t, []string{"accounts", "payments", "search"}, tripper.ListMonitors())
+	assert.Equal(t, 3, clock.ActiveTickers())
+
+	// Test case 1: Remove an existing monitor
+	// Expected output: Its ticker is stopped and it is no longer listed
+	assert.NoError(t, tripper.RemoveMonitor("payments"))
+	assert.Equal(t, []string{"accounts", "search"}, tripper.ListMonitors())
+	assert.Equal(t, 2, clock.ActiveTickers())
+	_, err := tripper.GetMonitor("payments")
+	assert.Error(t, err)
+
+	// Test case 2: Remove a non-existing monitor
+	// Expected output: Error
+	assert.EqualError(t, tripper.RemoveMonitor("payments"), "Monitor with name payments does not exist")
+}
+
+func TestRemoveMonitorStopsGoroutine(t *testing.T) {
+	tripper := Configure(TripperOptions{})
+	before := runtime.NumGoroutine()
+	circuit, err := tripper.AddMonitor(CircuitOptions{
+		Name:              "goroutine",
+		Threshold:         50,
+		MinimumCount:      10,
+		IntervalInSeconds: 60,
+		ThresholdType:     ThresholdPercentage,
+	})
+	assert.NoError(t, err)
+	circuit.UpdateStatus(true)
+	assert.Equal(t, before+1, runtime.NumGoroutine())
+
+	assert.NoError(t, tripper.RemoveMonitor("goroutine"))
+	waitFor(t, func() bool {
+		return runtime.NumGoroutine() == before
+	})
+}
+
+func TestTripperContext(t *testing.T) {
+	before := runtime.NumGoroutine()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	tripper := Configure(TripperOptions{Context: ctx})
+	var circuits []Circuit
+	for _, name := range []string{"payments", "accounts", "search"} {
+		circuit, err := tripper.AddMonitor(CircuitOptions{
+			Name:              name,
+			Threshold:         50,
+			MinimumCount:      10,
+			IntervalInSeconds: 60,
+			ThresholdType:     ThresholdPercentage,
+			AsyncCallbacks:    true,
+		})
+		assert.NoError(t, err)
+		circuit.UpdateStatus(true)
+		circuits = append(circuits, circuit)
+	}
+	ownCtx, ownCancel := context.WithCancel(context.Background())
+	own, err := tripper.GetOrCreate(CircuitOptions{
+		Name:              "own",
+		Threshold:         50,
+		MinimumCount:      10,
+		IntervalInSeconds: 60,
+		ThresholdType:     ThresholdPercentage,
+		Context:           ownCtx,
+	})
+	assert.NoError(t, err)
+	own.UpdateStatus(true)
+
+	// Test case 1: Cancel the context of the Tripper
+	// Expected output: Its circuits are closed and their goroutines exit, the
+	// circuit with a context of its own keeps running
+	cancel()
+	waitFor(t, func() bool {
+		for _, circuit := range circuits {
+			if circuit.UpdateStatusE(true) != ErrCircuitShutdown {
+				return false
+			}
+		}
+		return true
+	})
+	assert.NoError(t, own.UpdateStatusE(true))
+	assert.Equal(t, []string{"accounts", "own", "payments", "search"}, tripper.ListMonitors())
+
+	// Test case 2: Cancel the context of the remaining circuit
+	// Expected output: Every goroutine started for the circuits exits
+	ownCancel()
+	waitFor(t, func() bool {
+		return own.UpdateStatusE(true) == ErrCircuitShutdown && runtime.NumGoroutine() <= before
+	})
+}
+
+func TestDefaultCircuitOptions(t *testing.T) {
+	clock := NewFakeClock(time.Unix(1700000000, 0))
+	var opened []string
+	tripper := Configure(TripperOptions{DefaultCircuitOptions: CircuitOptions{
+		Name:               "ignored",
+		Threshold:          50,
+		ThresholdType:      ThresholdPercentage,
+		MinimumCount:       4,
+		IntervalInSeconds:  120,
+		FailureStatusCodes: []int{429},
+		OnCircuitOpen:      func(event CallbackEvent) { opened = append(opened, event.Name) },
+		Clock:              clock,
+	}})
+	defer tripper.StopAll()
+
+	// Test case 1: Add a circuit with only a name
+	// Expected output: It inherits every other option from the defaults
+	search, err := tripper.AddMonitor(CircuitOptions{Name: "search"})
+	assert.NoError(t, err)
+	options := search.GetOptions()
+	assert.Equal(t, "search", options.Name)
+	assert.Equal(t, float32(50), options.Threshold)
+	assert.Equal(t, ThresholdPercentage, options.ThresholdType)
+	assert.Equal(t, int64(4), options.MinimumCount)
+	assert.Equal(t, 120, options.IntervalInSeconds)
+	assert.Equal(t, []int{429}, options.FailureStatusCodes)
+	for i := 0; i < 4; i++ {
+		search.UpdateStatus(i%2 == 0)
+	}
+	assert.True(t, search.IsCircuitOpen())
+	assert.Equal(t, []string{"search"}, opened)
+
+	// Test case 2: Add a circuit overriding some of the defaults
+	// Expected output: The explicit fields win and the others are inherited
+	payments, err := tripper.GetOrCreate(CircuitOptions{
+		Name:          "payments",
+		Threshold:     2,
+		ThresholdType: ThresholdConsecutive,
+	})
+	assert.NoError(t, err)
+	options = payments.GetOptions()
+	assert.Equal(t, float32(2), options.Threshold)
+	assert.Equal(t, ThresholdConsecutive, options.ThresholdType)
+	assert.Equal(t, int64(4), options.MinimumCount)
+	assert.Equal(t, 120, options.IntervalInSeconds)
+	payments.UpdateStatus(false)
+	payments.UpdateStatus(false)
+	assert.True(t, payments.IsCircuitOpen())
+	assert.Equal(t, []string{"search", "payments"}, opened)
+
+	// Test case 3: Add a circuit with an explicit threshold to a Tripper whose defaults use Thresholds
+	// Expected output: None of the threshold fields are inherited and the explicit rule applies
+	rules := Configure(TripperOptions{DefaultCircuitOptions: CircuitOptions{
+		Thresholds:        []ThresholdRule{{ThresholdType: ThresholdPercentage, Threshold: 50, CloseThreshold: 25}},
+		MinimumCount:      10,
+		IntervalInSeconds: 60,
+		Clock:             clock,
+	}})
+	defer rules.StopAll()
+	orders, err := rules.AddMonitor(CircuitOptions{Name: "orders", Threshold: 2, ThresholdType: ThresholdConsecutive})
+	assert.NoError(t, err)
+	assert.Empty(t, orders.GetOptions().Thresholds)
+	assert.Equal(t, int64(10), orders.GetOptions().MinimumCount)
+	orders.UpdateStatus(false)
+	orders.UpdateStatus(false)
+	assert.True(t, orders.IsCircuitOpen())
+	inherited, err := rules.AddMonitor(CircuitOptions{Name: "inherited"})
+	assert.NoError(t, err)
+	assert.Len(t, inherited.GetOptions().Thresholds, 1)
+
+	// Test case 4: Add a circuit whose merged options are invalid
+	// Expected output: The error of ConfigureCircuit and no circuit is registered
+	_, err = tripper.AddMonitor(CircuitOptions{Name: "invalid", IntervalInSeconds: 90})
+	assert.True(t, errors.Is(err, ErrInvalidInterval))
+	assert.Equal(t, []string{"payments", "search"}, tripper.ListMonitors())
+}
+
+// waitFor polls cond until it returns true or the test times out.
+func waitFor(t *testing.T, cond func() bool) {
+	deadline := time.Now().Add(2 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("condition not met before timeout")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestCircuitNames(t *testing.T) {
+	monitorOptions := CircuitOptions{
+		Name:              "shared",
+		Threshold:         2,
+		ThresholdType:     ThresholdConsecutive,
+		MinimumCount:      1,
+		IntervalInSeconds: 60,
+		Clock:             NewFakeClock(time.Unix(1700000000, 0)),
+	}
+
+	// Test case 1: Configure two standalone circuits with the same name
+	// Expected output: Both are created and track their counts independently
+	first, err := ConfigureCircuit(monitorOptions)
+	assert.NoError(t, err)
+	defer first.Close()
+	second, err := ConfigureCircuit(monitorOptions)
+	assert.NoError(t, err)
+	defer second.Close()
+	first.UpdateStatus(false)
+	first.UpdateStatus(false)
+	assert.True(t, first.IsCircuitOpen())
+	assert.False(t, second.IsCircuitOpen())
+	assert.Equal(t, int64(0), second.Data().FailureCount)
+
+	// Test case 2: Register two circuits with the same name in a Tripper
+	// Expected output: The second one is rejected and the first one stays registered
+	tripper := Configure(TripperOptions{})
+	registered, err := tripper.AddMonitor(monitorOptions)
+	assert.NoError(t, err)
+	_, err = tripper.AddMonitor(monitorOptions)
+	assert.EqualError(t, err, "Monitor with name shared already exists")
+	m, err := tripper.GetMonitor("shared")
+	assert.NoError(t, err)
+	assert.Equal(t, registered, m)
+	assert.Equal(t, []string{"shared"}, tripper.ListMonitors())
+
+	// Test case 3: Register the name again after removing the circuit
+	// Expected output: No error
+	assert.NoError(t, tripper.RemoveMonitor("shared"))
+	_, err = tripper.AddMonitor(monitorOptions)
+	assert.NoError(t, err)
+	assert.NoError(t, tripper.RemoveMonitor("shared"))
+}
+
+func TestDefaultTripper(t *testing.T) {
+	clock := NewFakeClock(time.Unix(1700000000, 0))
+	for _, name := range []string{"default-payments", "default-search"} {
+		_, err := Register(CircuitOptions{
+			Name:              name,
+			Threshold:         2,
+			ThresholdType:     ThresholdConsecutive,
+			IntervalInSeconds: 60,
+			Clock:             clock,
+		})
+		assert.NoError(t, err)
+		defer Remove(name)
+	}
+
+	// Test case 1: Get a circuit registered in the default Tripper
+	// Expected output: The same circuit on every call, also through Default
+	payments, err := Get("default-payments")
+	assert.NoError(t, err)
+	again, err := Default().GetMonitor("default-payments")
+	assert.NoError(t, err)
+	assert.Equal(t, payments, again)
+
+	// Test case 2: Open one of the circuits
+	// Expected output: The other named circuit is not affected
+	payments.UpdateStatus(false)
+	payments.UpdateStatus(false)
+	search, err := Get("default-search")
+	assert.NoError(t, err)
+	assert.True(t, payments.IsCircuitOpen())
+	assert.False(t, search.IsCircuitOpen())
+
+	// Test case 3: Register a name twice and get an unknown name
+	// Expected output: Errors
+	_, err = Register(CircuitOptions{Name: "default-payments", Threshold: 2, ThresholdType: ThresholdConsecutive, IntervalInSeconds: 60})
+	assert.EqualError(t, err, "Monitor with name default-payments already exists")
+	_, err = Get("default-unknown")
+	assert.EqualError(t, err, "Monitor with name default-unknown does not exist")
+
+	// Test case 4: Remove a circuit
+	// Expected output: It is no longer registered
+	assert.NoError(t, Remove("default-search"))
+	_, err = Get("default-search")
+	assert.Error(t, err)
+	_, err = Register(CircuitOptions{Name: "default-search", Threshold: 2, ThresholdType: ThresholdConsecutive, IntervalInSeconds: 60, Clock: clock})
+	assert.NoError(t, err)
+}
+
+func TestResetAll(t *testing.T) {
+	registry := Configure(TripperOptions{})
+	clock := NewFakeClock(time.Unix(1700000000, 0))
+	var closed []string
+	var mu sync.Mutex
+	onClosed := func(event CallbackEvent) {
+		mu.Lock()
+		defer mu.Unlock()
+		closed = append(closed, event.Name)
+	}
+	for _, name := range []string{"a", "b", "c"} {
+		m, err := registry.AddMonitor(CircuitOptions{
+			Name:                  name,
+			Threshold:             2,
+			ThresholdType:         ThresholdConsecutive,
+			IntervalInSeconds:     60,
+			OpenDurationInSeconds: 600,
+			OnCircuitClosed:       onClosed,
+			Clock:                 clock,
+		})
+		assert.NoError(t, err)
+		m.UpdateStatus(false)
+		m.UpdateStatus(false)
+		assert.True(t, m.IsCircuitOpen())
+	}
+
+	// Test case 1: Reset every circuit while others are added concurrently
+	// Expected output: Every open circuit closes with its counts cleared
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_, err := registry.AddMonitor(CircuitOptions{
+				Name:              fmt.Sprintf("concurrent-%d", i),
+				Threshold:         2,
+				ThresholdType:     ThresholdConsecutive,
+				IntervalInSeconds: 60,
+				Clock:             clock,
+			})
+			assert.NoError(t, err)
+		}(i)
+	}
+	registry.ResetAll()
+	wg.Wait()
+	for _, name := range []string{"a", "b", "c"} {
+		m, err := registry.GetMonitor(name)
+		assert.NoError(t, err)
+		assert.False(t, m.IsCircuitOpen())
+		assert.Equal(t, int64(0), m.Data().FailureCount)
+	}
+	mu.Lock()
+	assert.ElementsMatch(t, []string{"a", "b", "c"}, closed)
+	mu.Unlock()
+
+	// Test case 2: Stop every circuit
+	// Expected output: Their tickers are stopped and events are ignored
+	registry.StopAll()
+	assert.Equal(t, 0, clock.ActiveTickers())
+	m, err := registry.GetMonitor("concurrent-3")
+	assert.NoError(t, err)
+	assert.Equal(t, ErrCircuitShutdown, m.UpdateStatusE(false))
+}
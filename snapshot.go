@@ -0,0 +1,85 @@
+package tripper
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// CircuitSnapshot holds the cumulative counters of a circuit at a point in
+// time, returned by Snapshot. Unlike the counts in Data, they are not cleared
+// when the interval ends, so two snapshots can be diffed with DiffSnapshots.
+type CircuitSnapshot struct {
+	Time            time.Time // Time the snapshot was taken, with sub-second precision when the Clock is a PreciseClock
+	SuccessCount    int64     // Number of successes recorded since the circuit was configured
+	FailureCount    int64     // Number of failures recorded since the circuit was configured
+	SlowCallCount   int64     // Number of calls slower than SlowCallThreshold since the circuit was configured
+	TripCount       int64     // Number of times the circuit has opened since it was configured
+	WindowStartedAt int64     // Timestamp when the current interval started
+}
+
+// SnapshotDelta is the difference between two snapshots, returned by
+// DiffSnapshots.
+type SnapshotDelta struct {
+	Duration           time.Duration // Time between the two snapshots
+	SuccessCount       int64         // Number of successes recorded between the two snapshots
+	FailureCount       int64         // Number of failures recorded between the two snapshots
+	SlowCallCount      int64         // Number of slow calls recorded between the two snapshots
+	TripCount          int64         // Number of times the circuit opened between the two snapshots
+	SuccessesPerSecond float64       // SuccessCount divided by Duration (0 when Duration is not positive)
+	FailuresPerSecond  float64       // FailureCount divided by Duration (0 when Duration is not positive)
+	FailureRate        float64       // FailureCount / (SuccessCount + FailureCount) between 0 and 1 (0 when nothing was recorded)
+}
+
+// Snapshot returns the cumulative counters of the circuit, e.g. for a metrics
+// system that polls on its own schedule and computes rates between its polls
+// with DiffSnapshots. The counters add up the counts of every interval, so
+// they only grow while the interval resets, Reset, the open duration or
+// StaleAfterSeconds clear the counts in Data. RestoreState replaces the counts
+// of the current interval and can make them go down.
+func (m *CircuitImplementation) Snapshot() CircuitSnapshot {
+	if m == nil {
+		return noopCircuit{}.Snapshot()
+	}
+
+	m.Mutex.RLock()
+	defer m.Mutex.RUnlock()
+
+	return CircuitSnapshot{
+		Time:            timeOf(m.now()),
+		SuccessCount:    m.clearedSuccesses + atomic.LoadInt64(&m.SuccessCount),
+		FailureCount:    m.clearedFailures + atomic.LoadInt64(&m.FailureCount),
+		SlowCallCount:   m.clearedSlowCalls + m.SlowCallCount,
+		TripCount:       m.TripCount,
+		WindowStartedAt: unixSeconds(m.WindowStartedAt),
+	}
+}
+
+// DiffSnapshots returns the counts recorded between two snapshots of the same
+// circuit and their rates per second. A counter that went down from from to
+// to, e.g. after RestoreState, is treated like a counter reset: the delta is
+// its value in to.
+func DiffSnapshots(from, to CircuitSnapshot) SnapshotDelta {
+	delta := SnapshotDelta{
+		Duration:      to.Time.Sub(from.Time),
+		SuccessCount:  counterDelta(from.SuccessCount, to.SuccessCount),
+		FailureCount:  counterDelta(from.FailureCount, to.FailureCount),
+		SlowCallCount: counterDelta(from.SlowCallCount, to.SlowCallCount),
+		TripCount:     counterDelta(from.TripCount, to.TripCount),
+	}
+	if seconds := delta.Duration.Seconds(); seconds > 0 {
+		delta.SuccessesPerSecond = float64(delta.SuccessCount) / seconds
+		delta.FailuresPerSecond = float64(delta.FailureCount) / seconds
+	}
+	if total := delta.SuccessCount + delta.FailureCount; total > 0 {
+		delta.FailureRate = float64(delta.FailureCount) / float64(total)
+	}
+	return delta
+}
+
+// counterDelta returns how much a cumulative counter grew from from to to.
+func counterDelta(from, to int64) int64 {
+	if to < from {
+		return to
+	}
+	return to - from
+}
@@ -0,0 +1,71 @@
+package tripper
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSnapshot(t *testing.T) {
+	clock := NewFakeClock(time.Unix(1700000000, 0))
+	m, err := ConfigureCircuit(CircuitOptions{
+		Name:              "TEST_Snapshot",
+		Threshold:         3,
+		ThresholdType:     ThresholdConsecutive,
+		IntervalInSeconds: 60,
+		Clock:             clock,
+	})
+	assert.NoError(t, err)
+	defer m.Close()
+
+	// Test case 1: Diff snapshots taken around some updates
+	// Expected output: The delta has the counts recorded in between and their rates
+	first := m.Snapshot()
+	assert.Equal(t, time.Unix(1700000000, 0), first.Time)
+	m.UpdateStatus(true)
+	m.UpdateStatus(false)
+	m.UpdateStatus(true)
+	m.UpdateStatus(true)
+	clock.Advance(20 * time.Second)
+	second := m.Snapshot()
+	delta := DiffSnapshots(first, second)
+	assert.Equal(t, 20*time.Second, delta.Duration)
+	assert.Equal(t, int64(3), delta.SuccessCount)
+	assert.Equal(t, int64(1), delta.FailureCount)
+	assert.Equal(t, 0.15, delta.SuccessesPerSecond)
+	assert.Equal(t, 0.05, delta.FailuresPerSecond)
+	assert.Equal(t, 0.25, delta.FailureRate)
+
+	// Test case 2: Diff snapshots across interval resets and Reset
+	// Expected output: The cumulative counters keep growing while the counts in Data are cleared
+	m.UpdateStatus(false)
+	clock.Advance(40 * time.Second)
+	m.UpdateStatus(false)
+	m.UpdateStatus(false)
+	m.UpdateStatus(false)
+	assert.True(t, m.IsCircuitOpen())
+	m.Reset()
+	assert.Equal(t, int64(0), m.Data().TotalCount)
+	third := m.Snapshot()
+	assert.Equal(t, int64(3), third.SuccessCount)
+	assert.Equal(t, int64(5), third.FailureCount)
+	assert.Equal(t, int64(1), third.TripCount)
+	delta = DiffSnapshots(second, third)
+	assert.Equal(t, 40*time.Second, delta.Duration)
+	assert.Equal(t, int64(0), delta.SuccessCount)
+	assert.Equal(t, int64(4), delta.FailureCount)
+	assert.Equal(t, int64(1), delta.TripCount)
+	assert.Equal(t, 0.1, delta.FailuresPerSecond)
+	assert.Equal(t, 1.0, delta.FailureRate)
+
+	// Test case 3: Diff a snapshot with a later one whose counters went down
+	// Expected output: The counters are treated as reset and the later values are used
+	delta = DiffSnapshots(third, CircuitSnapshot{Time: third.Time.Add(time.Second), SuccessCount: 2, FailureCount: 5})
+	assert.Equal(t, int64(2), delta.SuccessCount)
+	assert.Equal(t, int64(0), delta.FailureCount)
+
+	// Test case 4: Diff a snapshot with itself
+	// Expected output: Zero counts and rates
+	assert.Equal(t, SnapshotDelta{}, DiffSnapshots(third, third))
+}
@@ -0,0 +1,23 @@
+package tripper
+
+// CircuitState represents the state of a circuit.
+type CircuitState int
+
+const (
+	StateClosed   CircuitState = iota // Requests flow normally
+	StateOpen                         // Requests are short-circuited
+	StateHalfOpen                     // A limited number of requests probe for recovery
+)
+
+// String returns the name of the state as used in logs and metric labels.
+func (s CircuitState) String() string {
+	switch s {
+	case StateClosed:
+		return "CLOSED"
+	case StateOpen:
+		return "OPEN"
+	case StateHalfOpen:
+		return "HALF_OPEN"
+	}
+	return "UNKNOWN"
+}
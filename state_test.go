@@ -0,0 +1,40 @@
+package tripper
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCircuitStateString(t *testing.T) {
+	assert.Equal(t, "CLOSED", StateClosed.String())
+	assert.Equal(t, "OPEN", StateOpen.String())
+	assert.Equal(t, "HALF_OPEN", StateHalfOpen.String())
+	assert.Equal(t, "UNKNOWN", CircuitState(42).String())
+	assert.Equal(t, "state=OPEN", fmt.Sprintf("state=%s", StateOpen))
+}
+
+func TestState(t *testing.T) {
+	monitorOptions := CircuitOptions{
+		Name:              "TEST_State",
+		Threshold:         2,
+		MinimumCount:      3,
+		IntervalInSeconds: 120,
+		ThresholdType:     ThresholdCount,
+	}
+	m, err := ConfigureCircuit(monitorOptions)
+	assert.NoError(t, err)
+
+	// Test case 1: A new circuit is closed
+	// Expected output: StateClosed
+	assert.Equal(t, StateClosed, m.State())
+
+	// Test case 2: The state follows IsCircuitOpen
+	// Expected output: StateOpen
+	m.UpdateStatus(false)
+	m.UpdateStatus(false)
+	m.UpdateStatus(true)
+	assert.True(t, m.IsCircuitOpen())
+	assert.Equal(t, StateOpen, m.State())
+}
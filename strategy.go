@@ -0,0 +1,129 @@
+package tripper
+
+import "math"
+
+// ThresholdStrategy decides whether a threshold rule is breached. options are
+// the options of the circuit with Threshold, ThresholdType and CloseThreshold
+// set to the ones of the rule, and data are the counts as returned by Data.
+// While the circuit is open, ShouldOpen reports whether it should stay open.
+//
+// The built-in threshold types are strategies too. Custom ones set in
+// CircuitOptions.ThresholdStrategies are not gated by MinimumCount, since
+// data.TotalCount is at hand, and are called with the circuit locked, so they
+// must not call its methods.
+type ThresholdStrategy interface {
+	ShouldOpen(data CircuitData, options CircuitOptions) bool
+}
+
+// ThresholdStrategyFunc adapts a function to a ThresholdStrategy.
+type ThresholdStrategyFunc func(data CircuitData, options CircuitOptions) bool
+
+// ShouldOpen calls f(data, options).
+func (f ThresholdStrategyFunc) ShouldOpen(data CircuitData, options CircuitOptions) bool {
+	return f(data, options)
+}
+
+// thresholdCounts are the counters the built-in threshold types are evaluated
+// on. They are read on every recorded event, so unlike the CircuitData passed
+// to custom strategies they leave out the latency summary and the other
+// fields no built-in type needs.
+type thresholdCounts struct {
+	failures          int64
+	consecutive       int64
+	weightedSuccesses float64
+	weightedFailures  float64
+	ewmaFailureRate   float64
+	open              bool
+}
+
+// builtinStrategy evaluates a built-in threshold type on the counts of a
+// circuit. options are only read, and are passed by pointer so that the
+// options of the circuit are not copied on every event.
+type builtinStrategy func(counts thresholdCounts, rule ThresholdRule, options *CircuitOptions) bool
+
+// builtinStrategies are the strategies of the built-in threshold types.
+var builtinStrategies = map[string]builtinStrategy{
+	ThresholdCount:        failureCountBreached,
+	ThresholdFailureCount: failureCountBreached,
+	ThresholdPercentage:   failurePercentageBreached,
+	ThresholdEWMA:         ewmaBreached,
+	ThresholdConsecutive:  consecutiveBreached,
+}
+
+// thresholdStrategies are the built-in strategies as ThresholdStrategy, e.g.
+// to wrap one in a custom strategy.
+var thresholdStrategies = map[string]ThresholdStrategy{
+	ThresholdCount:        strategyOf(failureCountBreached),
+	ThresholdFailureCount: strategyOf(failureCountBreached),
+	ThresholdPercentage:   strategyOf(failurePercentageBreached),
+	ThresholdEWMA:         strategyOf(ewmaBreached),
+	ThresholdConsecutive:  strategyOf(consecutiveBreached),
+}
+
+// strategyOf adapts a built-in strategy to a ThresholdStrategy.
+func strategyOf(strategy builtinStrategy) ThresholdStrategy {
+	return ThresholdStrategyFunc(func(data CircuitData, options CircuitOptions) bool {
+		counts := thresholdCounts{
+			failures:          data.FailureCount,
+			consecutive:       data.ConsecutiveCounter,
+			weightedSuccesses: data.WeightedSuccessCount,
+			weightedFailures:  data.WeightedFailureCount,
+			ewmaFailureRate:   data.EWMAFailureRate,
+			open:              data.IsCircuitOpen,
+		}
+		rule := ThresholdRule{ThresholdType: options.ThresholdType, Threshold: options.Threshold, CloseThreshold: options.CloseThreshold}
+		return strategy(counts, rule, &options)
+	})
+}
+
+// effectiveThreshold returns the threshold of a rule, or its CloseThreshold
+// while the circuit is open so that it stays open until the counts drop below
+// it.
+func effectiveThreshold(counts thresholdCounts, rule ThresholdRule) float32 {
+	if counts.open && rule.CloseThreshold > 0 {
+		return rule.CloseThreshold
+	}
+	return rule.Threshold
+}
+
+// failureCountBreached implements ThresholdCount and ThresholdFailureCount.
+func failureCountBreached(counts thresholdCounts, rule ThresholdRule, options *CircuitOptions) bool {
+	return float32(counts.weightedFailures) >= effectiveThreshold(counts, rule)
+}
+
+// failurePercentageBreached implements ThresholdPercentage.
+func failurePercentageBreached(counts thresholdCounts, rule ThresholdRule, options *CircuitOptions) bool {
+	if counts.failures < options.MinimumFailures {
+		// too few failures for the percentage to be meaningful
+		return false
+	}
+	totalRequests := counts.weightedFailures + counts.weightedSuccesses
+	if totalRequests == 0 {
+		return false
+	}
+	failurePercentage := roundPercentage(options.PercentageRounding, counts.weightedFailures/totalRequests*100)
+	return float32(failurePercentage) >= effectiveThreshold(counts, rule)
+}
+
+// ewmaBreached implements ThresholdEWMA.
+func ewmaBreached(counts thresholdCounts, rule ThresholdRule, options *CircuitOptions) bool {
+	return float32(roundPercentage(options.PercentageRounding, counts.ewmaFailureRate*100)) >= effectiveThreshold(counts, rule)
+}
+
+// consecutiveBreached implements ThresholdConsecutive.
+func consecutiveBreached(counts thresholdCounts, rule ThresholdRule, options *CircuitOptions) bool {
+	return counts.consecutive >= int64(rule.Threshold)
+}
+
+// roundPercentage rounds a failure percentage according to PercentageRounding.
+func roundPercentage(rounding string, percentage float64) float64 {
+	switch rounding {
+	case RoundingFloor:
+		return math.Floor(percentage)
+	case RoundingRound:
+		return math.Round(percentage)
+	case RoundingCeil:
+		return math.Ceil(percentage)
+	}
+	return percentage
+}
@@ -0,0 +1,81 @@
+package tripper
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestThresholdStrategy(t *testing.T) {
+	var seen CircuitOptions
+	// trips when the failures outnumber the successes by Threshold
+	margin := ThresholdStrategyFunc(func(data CircuitData, options CircuitOptions) bool {
+		seen = options
+		return float32(data.FailureCount-data.SuccessCount) >= options.Threshold
+	})
+	clock := NewFakeClock(time.Unix(1700000000, 0))
+	m, err := ConfigureCircuit(CircuitOptions{
+		Name:                "TEST_ThresholdStrategy",
+		Threshold:           3,
+		ThresholdType:       "MARGIN",
+		ThresholdStrategies: map[string]ThresholdStrategy{"MARGIN": margin},
+		IntervalInSeconds:   60,
+		Clock:               clock,
+	})
+	assert.NoError(t, err)
+	defer m.Close()
+
+	// Test case 1: Record failures that do not outnumber the successes by the margin
+	// Expected output: The circuit stays closed without MinimumCount
+	m.UpdateStatus(true)
+	m.UpdateStatus(false)
+	m.UpdateStatus(false)
+	m.UpdateStatus(false)
+	assert.False(t, m.IsCircuitOpen())
+	assert.Equal(t, "MARGIN", seen.ThresholdType)
+	assert.Equal(t, float32(3), seen.Threshold)
+
+	// Test case 2: Reach the margin
+	// Expected output: The circuit opens
+	m.UpdateStatus(false)
+	assert.True(t, m.IsCircuitOpen())
+
+	// Test case 3: Record successes while open until the rule no longer holds
+	// Expected output: The circuit closes
+	m.UpdateStatus(true)
+	assert.False(t, m.IsCircuitOpen())
+
+	// Test case 4: Combine the custom rule with a built-in one
+	// Expected output: The rules are evaluated together with their own thresholds
+	combined, err := ConfigureCircuit(CircuitOptions{
+		Name:                "TEST_ThresholdStrategy_Combined",
+		MinimumCount:        4,
+		ThresholdStrategies: map[string]ThresholdStrategy{"MARGIN": margin},
+		Thresholds: []ThresholdRule{
+			{ThresholdType: "MARGIN", Threshold: 1},
+			{ThresholdType: ThresholdCount, Threshold: 3},
+		},
+		IntervalInSeconds: 60,
+		Clock:             clock,
+	})
+	assert.NoError(t, err)
+	defer combined.Close()
+	for i := 0; i < 3; i++ {
+		combined.UpdateStatus(false)
+	}
+	assert.False(t, combined.IsCircuitOpen())
+	combined.UpdateStatus(false)
+	assert.True(t, combined.IsCircuitOpen())
+	assert.Equal(t, float32(1), seen.Threshold)
+
+	// Test case 5: Use a custom threshold type that is not registered
+	// Expected output: The options are rejected
+	_, err = ConfigureCircuit(CircuitOptions{
+		Name:              "TEST_ThresholdStrategy_Unknown",
+		Threshold:         3,
+		ThresholdType:     "MARGIN",
+		IntervalInSeconds: 60,
+	})
+	assert.EqualError(t, err, "invalid threshold type MARGIN")
+}
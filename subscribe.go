@@ -0,0 +1,131 @@
+package tripper
+
+import "sync"
+
+// subscriptionBufferSize is the number of state changes that can be queued for
+// a subscriber before events are dropped, or before the circuit waits for the
+// subscriber with BlockSlowSubscribers.
+const subscriptionBufferSize = 16
+
+// subscription is a channel returned by Subscribe.
+type subscription struct {
+	events chan CallbackEvent
+	done   chan struct{} // Closed by Unsubscribe to release a blocked send
+	once   sync.Once
+	mu     sync.RWMutex // Held for reading while sending, for writing to close events
+	closed bool
+}
+
+// Subscribe returns a channel that receives an event for every state change of
+// the circuit, as an alternative to OnStateChange. The channel is buffered;
+// when a subscriber falls behind, further events are dropped unless
+// BlockSlowSubscribers is set. The channel is closed by Unsubscribe or Close.
+//
+//	events := circuit.Subscribe()
+//	defer circuit.Unsubscribe(events)
+//	for event := range events {
+//		log.Printf("%s: %s -> %s", event.Name, event.FromState, event.ToState)
+//	}
+func (m *CircuitImplementation) Subscribe() <-chan CallbackEvent {
+	if m == nil {
+		return noopCircuit{}.Subscribe()
+	}
+
+	sub := &subscription{
+		events: make(chan CallbackEvent, subscriptionBufferSize),
+		done:   make(chan struct{}),
+	}
+
+	m.subscribersMu.Lock()
+	defer m.subscribersMu.Unlock()
+
+	// Close sets shutdown before closing the subscriptions, so a subscription
+	// added here is either seen by Close or closed right away
+	m.Mutex.RLock()
+	shutdown := m.shutdown
+	m.Mutex.RUnlock()
+	if shutdown {
+		sub.close()
+		return sub.events
+	}
+	m.subscribers = append(m.subscribers, sub)
+	return sub.events
+}
+
+// Unsubscribe stops the delivery of events to a channel returned by Subscribe
+// and closes it.
+func (m *CircuitImplementation) Unsubscribe(events <-chan CallbackEvent) {
+	if m == nil {
+		return
+	}
+
+	m.subscribersMu.Lock()
+	var found *subscription
+	for i, sub := range m.subscribers {
+		if sub.events == events {
+			found = sub
+			m.subscribers = append(m.subscribers[:i], m.subscribers[i+1:]...)
+			break
+		}
+	}
+	m.subscribersMu.Unlock()
+	if found != nil {
+		found.close()
+	}
+}
+
+// publish sends a state change to every subscriber. It must be called without
+// holding m.Mutex.
+func (m *CircuitImplementation) publish(event CallbackEvent, block bool) {
+	m.subscribersMu.Lock()
+	subscribers := append([]*subscription(nil), m.subscribers...)
+	m.subscribersMu.Unlock()
+
+	for _, sub := range subscribers {
+		sub.send(event, block)
+	}
+}
+
+// closeSubscriptions closes every channel returned by Subscribe.
+func (m *CircuitImplementation) closeSubscriptions() {
+	m.subscribersMu.Lock()
+	subscribers := m.subscribers
+	m.subscribers = nil
+	m.subscribersMu.Unlock()
+
+	for _, sub := range subscribers {
+		sub.close()
+	}
+}
+
+// send delivers an event unless the subscription was closed. Without block
+// the event is dropped when the buffer is full.
+func (s *subscription) send(event CallbackEvent, block bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.closed {
+		return
+	}
+	if !block {
+		select {
+		case s.events <- event:
+		default:
+		}
+		return
+	}
+	select {
+	case s.events <- event:
+	case <-s.done:
+	}
+}
+
+// close releases a blocked send and closes the channel.
+func (s *subscription) close() {
+	s.once.Do(func() {
+		close(s.done)
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.closed = true
+		close(s.events)
+	})
+}
@@ -0,0 +1,127 @@
+package tripper
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newSubscribeCircuit(t *testing.T, block bool) (Circuit, *FakeClock) {
+	clock := NewFakeClock(time.Unix(1700000000, 0))
+	m, err := ConfigureCircuit(CircuitOptions{
+		Name:                 "TEST_Subscribe",
+		Threshold:            2,
+		ThresholdType:        ThresholdConsecutive,
+		IntervalInSeconds:    60,
+		BlockSlowSubscribers: block,
+		Clock:                clock,
+	})
+	assert.NoError(t, err)
+	return m, clock
+}
+
+func TestSubscribe(t *testing.T) {
+	m, clock := newSubscribeCircuit(t, false)
+	defer m.Close()
+	events := m.Subscribe()
+
+	// Test case 1: Trip the circuit
+	// Expected output: The transition is read off the channel
+	m.UpdateStatus(false)
+	m.UpdateStatus(false)
+	event := <-events
+	assert.Equal(t, "TEST_Subscribe", event.Name)
+	assert.Equal(t, StateClosed, event.FromState)
+	assert.Equal(t, StateOpen, event.ToState)
+	assert.Equal(t, int64(2), event.FailureCount)
+
+	// Test case 2: Record more failures while open, then reset the interval
+	// Expected output: Only the transition back to closed is emitted
+	m.UpdateStatus(false)
+	clock.Advance(60 * time.Second)
+	event = <-events
+	assert.Equal(t, StateOpen, event.FromState)
+	assert.Equal(t, StateClosed, event.ToState)
+	assert.Len(t, events, 0)
+
+	// Test case 3: Unsubscribe
+	// Expected output: The channel is closed and no longer receives events
+	m.Unsubscribe(events)
+	m.UpdateStatus(false)
+	m.UpdateStatus(false)
+	_, ok := <-events
+	assert.False(t, ok)
+}
+
+func TestSubscribeSlowConsumer(t *testing.T) {
+	// Test case 1: Emit more state changes than the buffer holds without reading
+	// Expected output: The updates do not block and the extra events are dropped
+	m, _ := newSubscribeCircuit(t, false)
+	events := m.Subscribe()
+	for i := 0; i < subscriptionBufferSize; i++ {
+		m.UpdateStatus(false)
+		m.UpdateStatus(false)
+		m.UpdateStatus(true)
+	}
+	assert.Len(t, events, subscriptionBufferSize)
+
+	// Test case 2: Close the circuit
+	// Expected output: The buffered events can still be read before the channel is closed
+	m.Close()
+	count := 0
+	for range events {
+		count++
+	}
+	assert.Equal(t, subscriptionBufferSize, count)
+
+	// Test case 3: Emit past a full buffer with BlockSlowSubscribers
+	// Expected output: The update waits until the subscriber reads
+	m, _ = newSubscribeCircuit(t, true)
+	defer m.Close()
+	events = m.Subscribe()
+	for i := 0; i < subscriptionBufferSize/2; i++ {
+		m.UpdateStatus(false)
+		m.UpdateStatus(false)
+		m.UpdateStatus(true)
+	}
+	assert.Len(t, events, subscriptionBufferSize)
+	updated := make(chan struct{})
+	go func() {
+		m.UpdateStatus(false)
+		m.UpdateStatus(false)
+		close(updated)
+	}()
+	select {
+	case <-updated:
+		t.Fatal("update did not wait for the subscriber")
+	case <-time.After(20 * time.Millisecond):
+	}
+	<-events
+	<-updated
+
+	// Test case 4: Unsubscribe while an update waits for the subscriber
+	// Expected output: The update is released
+	updated = make(chan struct{})
+	go func() {
+		m.UpdateStatus(true)
+		close(updated)
+	}()
+	waitFor(t, func() bool { return m.State() == StateClosed })
+	m.Unsubscribe(events)
+	<-updated
+}
+
+func TestSubscribeAfterClose(t *testing.T) {
+	// Test case 1: Subscribe to a closed circuit
+	// Expected output: The channel is already closed
+	m, _ := newSubscribeCircuit(t, false)
+	m.Close()
+	_, ok := <-m.Subscribe()
+	assert.False(t, ok)
+
+	// Test case 2: Subscribe to a noop circuit
+	// Expected output: The channel is already closed
+	_, ok = <-NewNoopCircuit().Subscribe()
+	assert.False(t, ok)
+}
@@ -0,0 +1,156 @@
+package tripper
+
+import (
+	"net/http"
+	"time"
+)
+
+// CircuitTransport is an http.RoundTripper that sends requests through a
+// circuit. Requests fail with ErrCircuitOpen without being sent while the
+// circuit does not allow them, i.e. while it is open or half-open with all of
+// its probes in flight, and with ErrTooManyRequests while MaxConcurrent
+// requests are in flight.
+//
+//	client := &http.Client{Transport: tripper.NewCircuitTransport(circuit, nil)}
+type CircuitTransport struct {
+	Circuit   Circuit                                   // Circuit recording the outcome of every request (a nil one, or a nil *CircuitImplementation, lets every request through)
+	Base      http.RoundTripper                         // Transport used to send the requests (defaults to http.DefaultTransport)
+	IsFailure func(resp *http.Response, err error) bool // Decides which responses count as failures (defaults to IsServerFailure)
+}
+
+// NewCircuitTransport creates a CircuitTransport sending requests through c
+// with base. A nil base uses http.DefaultTransport.
+func NewCircuitTransport(c Circuit, base http.RoundTripper) *CircuitTransport {
+	return &CircuitTransport{
+		Circuit: c,
+		Base:    base,
+	}
+}
+
+// IsServerFailure counts transport errors and 5xx responses as failures, and
+// every other response, including 4xx, as a success.
+func IsServerFailure(resp *http.Response, err error) bool {
+	return err != nil || resp.StatusCode >= http.StatusInternalServerError
+}
+
+// UpdateFromHTTPStatus records the outcome of an HTTP call from its status
+// code: 5xx codes are recorded as failures and every other code as a success.
+// A non-empty FailureStatusCodes replaces the 5xx codes, so only the codes it
+// lists are recorded as failures.
+func (m *CircuitImplementation) UpdateFromHTTPStatus(code int) {
+	if m == nil {
+		return
+	}
+
+	m.Mutex.RLock()
+	failure := isFailureStatusCode(m.Options, code)
+	m.Mutex.RUnlock()
+
+	m.updateStatus(!failure, 0, weightScale, "")
+}
+
+// isFailureStatusCode reports whether UpdateFromHTTPStatus records code as a failure.
+func isFailureStatusCode(options CircuitOptions, code int) bool {
+	if len(options.FailureStatusCodes) == 0 {
+		return code >= http.StatusInternalServerError && code <= 599
+	}
+	for _, failureCode := range options.FailureStatusCodes {
+		if code == failureCode {
+			return true
+		}
+	}
+	return false
+}
+
+// RoundTrip implements http.RoundTripper. Errors caused by the request context
+// being cancelled or exceeding its deadline are not recorded.
+func (t *CircuitTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	circuit := t.circuit()
+	if b, ok := circuit.(bulkhead); ok {
+		if !b.acquireSlot() {
+			if req.Body != nil {
+				req.Body.Close()
+			}
+			return nil, ErrTooManyRequests
+		}
+		defer b.releaseSlot()
+	}
+	allowed, probe := allowRequest(circuit)
+	if !allowed {
+		if req.Body != nil {
+			req.Body.Close()
+		}
+		return nil, ErrCircuitOpen
+	}
+
+	start := nowTime(circuit)
+	resp, err := t.base().RoundTrip(req)
+	if err != nil && req.Context().Err() != nil {
+		if probe.held {
+			circuit.(prober).releaseProbe(probe)
+		}
+		return resp, err
+	}
+	circuit.UpdateStatusWithLatency(!t.isFailure(resp, err), nowTime(circuit).Sub(start))
+	return resp, err
+}
+
+// prober is implemented by circuits that can give back a half-open probe slot
+// whose outcome is not recorded.
+type prober interface {
+	allowRequest() (bool, probeSlot)
+	releaseProbe(probe probeSlot)
+}
+
+// bulkhead is implemented by circuits that limit the calls in flight with
+// MaxConcurrent.
+type bulkhead interface {
+	acquireSlot() bool
+	releaseSlot()
+}
+
+// timer is implemented by circuits that measure latencies with their Clock.
+type timer interface {
+	nowTime() time.Time
+}
+
+// circuit returns the circuit of the transport, or the noop circuit when it is
+// nil, including a nil *CircuitImplementation in a non-nil Circuit.
+func (t *CircuitTransport) circuit() Circuit {
+	if m, ok := t.Circuit.(*CircuitImplementation); t.Circuit == nil || ok && m == nil {
+		return noopCircuit{}
+	}
+	return t.Circuit
+}
+
+// allowRequest reports whether a request may be sent through circuit and the
+// half-open probe slot it reserved, if any.
+func allowRequest(circuit Circuit) (bool, probeSlot) {
+	if p, ok := circuit.(prober); ok {
+		return p.allowRequest()
+	}
+	return circuit.AllowRequest(), probeSlot{}
+}
+
+// nowTime returns the current time of the clock of circuit, or of the system
+// clock for circuits without one, for measuring the latency of a request.
+func nowTime(circuit Circuit) time.Time {
+	if c, ok := circuit.(timer); ok {
+		return c.nowTime()
+	}
+	return time.Now()
+}
+
+func (t *CircuitTransport) base() http.RoundTripper {
+	if t.Base != nil {
+		return t.Base
+	}
+	return http.DefaultTransport
+}
+
+func (t *CircuitTransport) isFailure(resp *http.Response, err error) bool {
+	if t.IsFailure != nil {
+		return t.IsFailure(resp, err)
+	}
+	return IsServerFailure(resp, err)
+}
@@ -0,0 +1,287 @@
+package tripper
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newTransportCircuit(t *testing.T) Circuit {
+	circuit, err := ConfigureCircuit(CircuitOptions{
+		Name:              "TEST_Transport",
+		Threshold:         3,
+		ThresholdType:     ThresholdConsecutive,
+		MinimumCount:      1,
+		IntervalInSeconds: 60,
+		Clock:             NewFakeClock(time.Unix(1700000000, 0)),
+	})
+	assert.NoError(t, err)
+	return circuit
+}
+
+func TestCircuitTransport(t *testing.T) {
+	var status int32 = http.StatusInternalServerError
+	var hits int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+		w.WriteHeader(int(atomic.LoadInt32(&status)))
+	}))
+	defer server.Close()
+
+	circuit := newTransportCircuit(t)
+	defer circuit.Close()
+	client := &http.Client{Transport: NewCircuitTransport(circuit, nil)}
+
+	// Test case 1: The server keeps failing with 500
+	// Expected output: The responses are returned and the circuit opens after 3 failures
+	for i := 0; i < 3; i++ {
+		resp, err := client.Get(server.URL)
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusInternalServerError, resp.StatusCode)
+		resp.Body.Close()
+	}
+	assert.True(t, circuit.IsCircuitOpen())
+	assert.Equal(t, int64(3), circuit.Data().FailureCount)
+
+	// Test case 2: Send a request while the circuit is open
+	// Expected output: ErrCircuitOpen without reaching the server
+	_, err := client.Get(server.URL)
+	assert.True(t, errors.Is(err, ErrCircuitOpen))
+	assert.Equal(t, int32(3), atomic.LoadInt32(&hits))
+}
+
+func TestCircuitTransportSuccess(t *testing.T) {
+	var status int32 = http.StatusOK
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(int(atomic.LoadInt32(&status)))
+	}))
+	defer server.Close()
+
+	circuit := newTransportCircuit(t)
+	defer circuit.Close()
+	client := &http.Client{Transport: NewCircuitTransport(circuit, http.DefaultTransport)}
+
+	// Test case 1: 2xx and 4xx responses
+	// Expected output: Recorded as successes
+	for _, code := range []int32{http.StatusOK, http.StatusNotFound, http.StatusBadRequest, http.StatusTooManyRequests} {
+		atomic.StoreInt32(&status, code)
+		resp, err := client.Get(server.URL)
+		assert.NoError(t, err)
+		resp.Body.Close()
+	}
+	assert.Equal(t, int64(4), circuit.Data().SuccessCount)
+	assert.Equal(t, int64(0), circuit.Data().FailureCount)
+	assert.False(t, circuit.IsCircuitOpen())
+}
+
+func TestCircuitTransportErrors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	url := server.URL
+	server.Close()
+
+	circuit := newTransportCircuit(t)
+	defer circuit.Close()
+	client := &http.Client{Transport: NewCircuitTransport(circuit, nil)}
+
+	// Test case 1: The server is unreachable
+	// Expected output: The transport error is returned and recorded as a failure
+	_, err := client.Get(url)
+	assert.Error(t, err)
+	assert.Equal(t, int64(1), circuit.Data().FailureCount)
+
+	// Test case 2: The request context is cancelled
+	// Expected output: The error is returned and nothing is recorded
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	req, _ := http.NewRequest(http.MethodGet, url, nil)
+	_, err = client.Do(req.WithContext(ctx))
+	assert.True(t, errors.Is(err, context.Canceled))
+	assert.Equal(t, int64(1), circuit.Data().FailureCount)
+	assert.Equal(t, int64(0), circuit.Data().SuccessCount)
+}
+
+func TestCircuitTransportIsFailure(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer server.Close()
+
+	circuit := newTransportCircuit(t)
+	defer circuit.Close()
+	transport := NewCircuitTransport(circuit, nil)
+	transport.IsFailure = func(resp *http.Response, err error) bool {
+		return IsServerFailure(resp, err) || resp.StatusCode == http.StatusTooManyRequests
+	}
+	client := &http.Client{Transport: transport}
+
+	// Test case 1: A custom classifier counting 429 as a failure
+	// Expected output: The 429 responses are recorded as failures
+	for i := 0; i < 2; i++ {
+		resp, err := client.Get(server.URL)
+		assert.NoError(t, err)
+		resp.Body.Close()
+	}
+	assert.Equal(t, int64(2), circuit.Data().FailureCount)
+	assert.Equal(t, int64(0), circuit.Data().SuccessCount)
+}
+
+func TestCircuitTransportHalfOpen(t *testing.T) {
+	var status int32 = http.StatusInternalServerError
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(int(atomic.LoadInt32(&status)))
+	}))
+	defer server.Close()
+
+	clock := NewFakeClock(time.Unix(1700000000, 0))
+	circuit, err := ConfigureCircuit(CircuitOptions{
+		Name:                  "TEST_TransportHalfOpen",
+		Threshold:             1,
+		ThresholdType:         ThresholdConsecutive,
+		MinimumCount:          1,
+		IntervalInSeconds:     60,
+		OpenDurationInSeconds: 30,
+		HalfOpenMaxProbes:     1,
+		Clock:                 clock,
+	})
+	assert.NoError(t, err)
+	defer circuit.Close()
+	client := &http.Client{Transport: NewCircuitTransport(circuit, nil)}
+
+	resp, err := client.Get(server.URL)
+	assert.NoError(t, err)
+	resp.Body.Close()
+	assert.True(t, circuit.IsCircuitOpen())
+
+	// Test case 1: The probe request of a half-open circuit is cancelled
+	// Expected output: The probe slot is given back and the next request is sent
+	clock.Advance(30 * time.Second)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	req, _ := http.NewRequest(http.MethodGet, server.URL, nil)
+	_, err = client.Do(req.WithContext(ctx))
+	assert.True(t, errors.Is(err, context.Canceled))
+	assert.Equal(t, StateHalfOpen, circuit.State())
+
+	// Test case 2: The probe request succeeds
+	// Expected output: The circuit closes
+	atomic.StoreInt32(&status, http.StatusOK)
+	resp, err = client.Get(server.URL)
+	assert.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, StateClosed, circuit.State())
+}
+
+func TestCircuitTransportLatencyFromClock(t *testing.T) {
+	clock := NewFakeClock(time.Unix(1700000000, 0))
+	var delay int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		clock.Advance(time.Duration(atomic.LoadInt64(&delay)))
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	circuit, err := ConfigureCircuit(CircuitOptions{
+		Name:                  "TEST_TransportLatencyFromClock",
+		Threshold:             50,
+		ThresholdType:         ThresholdPercentage,
+		MinimumCount:          10,
+		IntervalInSeconds:     60,
+		SlowCallThreshold:     100 * time.Millisecond,
+		SlowCallRateThreshold: 50,
+		Clock:                 clock,
+	})
+	assert.NoError(t, err)
+	defer circuit.Close()
+	client := &http.Client{Transport: NewCircuitTransport(circuit, nil)}
+
+	// Test case 1: Send a request during which the circuit's clock does not move
+	// Expected output: The request takes no time and is not slow
+	resp, err := client.Get(server.URL)
+	assert.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, int64(0), circuit.Data().SlowCallCount)
+	assert.Equal(t, time.Duration(0), circuit.Data().Latency.Max)
+
+	// Test case 2: Send a request during which the circuit's clock moves by a second
+	// Expected output: The latency is taken from the clock, so the request is slow
+	atomic.StoreInt64(&delay, int64(time.Second))
+	resp, err = client.Get(server.URL)
+	assert.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, int64(1), circuit.Data().SlowCallCount)
+	assert.Equal(t, time.Second, circuit.Data().Latency.Max)
+}
+
+func TestCircuitTransportNilCircuit(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	var circuit *CircuitImplementation
+	for name, c := range map[string]Circuit{"nil Circuit": nil, "nil *CircuitImplementation": circuit} {
+		t.Run(name, func(t *testing.T) {
+			// Test case 1: Send failing requests through a transport without a circuit
+			// Expected output: Every response is returned without a panic, like with the noop circuit
+			client := &http.Client{Transport: NewCircuitTransport(c, nil)}
+			for i := 0; i < 3; i++ {
+				resp, err := client.Get(server.URL)
+				assert.NoError(t, err)
+				assert.Equal(t, http.StatusInternalServerError, resp.StatusCode)
+				resp.Body.Close()
+			}
+		})
+	}
+}
+
+func TestUpdateFromHTTPStatus(t *testing.T) {
+	newCircuit := func(failureCodes ...int) Circuit {
+		m, err := ConfigureCircuit(CircuitOptions{
+			Name:               "TEST_UpdateFromHTTPStatus",
+			Threshold:          100,
+			ThresholdType:      ThresholdCount,
+			MinimumCount:       1000,
+			IntervalInSeconds:  60,
+			FailureStatusCodes: failureCodes,
+			Clock:              NewFakeClock(time.Unix(1700000000, 0)),
+		})
+		assert.NoError(t, err)
+		return m
+	}
+
+	// Test case 1: Record 200, 404, 500 and 503 with the default codes
+	// Expected output: 200 and 404 are successes, 500 and 503 are failures
+	m := newCircuit()
+	defer m.Close()
+	for _, code := range []int{200, 404, 500, 503} {
+		m.UpdateFromHTTPStatus(code)
+	}
+	assert.Equal(t, int64(2), m.Data().SuccessCount)
+	assert.Equal(t, int64(2), m.Data().FailureCount)
+
+	// Test case 2: Record 200, 404, 429, 500 and 503 with 429 as the only failure code
+	// Expected output: 429 is the only failure, 500 and 503 are successes
+	m = newCircuit(http.StatusTooManyRequests)
+	defer m.Close()
+	for _, code := range []int{200, 404, 429, 500, 503} {
+		m.UpdateFromHTTPStatus(code)
+	}
+	assert.Equal(t, int64(4), m.Data().SuccessCount)
+	assert.Equal(t, int64(1), m.Data().FailureCount)
+
+	// Test case 3: Record 200, 500 and 503 with 500 and 502 as failure codes
+	// Expected output: 500 is a failure and 503 a success
+	m = newCircuit(http.StatusInternalServerError, http.StatusBadGateway)
+	defer m.Close()
+	for _, code := range []int{200, 500, 503} {
+		m.UpdateFromHTTPStatus(code)
+	}
+	assert.Equal(t, int64(2), m.Data().SuccessCount)
+	assert.Equal(t, int64(1), m.Data().FailureCount)
+}
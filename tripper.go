@@ -1,219 +1,1561 @@
 package tripper
 
 import (
-	"fmt"
+	"context"
+	"errors"
+	"math"
+	"math/rand"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
 // threshold type can be only COUNT or PERCENTAGE
 // ThresholdCount represents a threshold type based on count.
+// ThresholdFailureCount trips on the number of failures since the last reset
+// alone, without waiting for MinimumCount events.
+// ThresholdEWMA trips on an exponentially weighted moving average of the
+// failure percentage that decays with HalfLifeSeconds.
 const (
-	ThresholdCount       = "COUNT"
-	ThresholdPercentage  = "PERCENTAGE"
-	ThresholdConsecutive = "CONSECUTIVE"
+	ThresholdCount        = "COUNT"
+	ThresholdPercentage   = "PERCENTAGE"
+	ThresholdConsecutive  = "CONSECUTIVE"
+	ThresholdFailureCount = "FAILURE_COUNT"
+	ThresholdEWMA         = "EWMA"
 )
 
-var thresholdTypes = []string{ThresholdCount, ThresholdPercentage, ThresholdConsecutive}
+// Rounding modes applied to the failure percentage before it is compared to a
+// PERCENTAGE threshold. RoundingExact is the default.
+const (
+	RoundingExact = "EXACT"
+	RoundingFloor = "FLOOR"
+	RoundingRound = "ROUND"
+	RoundingCeil  = "CEIL"
+)
+
+// OperatorAnd and OperatorOr control how multiple threshold rules are combined.
+const (
+	OperatorAnd = "AND"
+	OperatorOr  = "OR"
+)
+
+// MinIntervalInSeconds is the shortest IntervalInSeconds. Intervals are a
+// whole number of minutes, so IntervalInSeconds must be a positive multiple of
+// it.
+const MinIntervalInSeconds = 60
+
+// ErrCircuitShutdown is returned by UpdateStatusE when the circuit was closed with Close.
+var ErrCircuitShutdown = errors.New("circuit is shut down")
 
 // Circuit represents a monitoring entity that tracks the status of a circuit.
 type Circuit interface {
 	UpdateStatus(success bool)
+	UpdateStatusE(success bool) error
+	UpdateStatusExt(success bool, record bool)
+	UpdateStatusErr(err error)
+	UpdateStatusWeighted(success bool, weight float64)
+	UpdateStatusWithLatency(success bool, latency time.Duration)
+	UpdateStatusBatch(successes, failures int64)
+	RecordAttempts(successes, failures int64, finalSuccess bool)
+	ResetWindow()
+	Reset()
+	UpdateFromHTTPStatus(code int)
+	Execute(fn func() error) error
+	ExecuteContext(ctx context.Context, fn func(context.Context) error) error
+	ProbeOnce(fn func() error) (bool, error)
 	IsCircuitOpen() bool
+	AllowRequest() bool
+	AllowProbe() (bool, func())
+	SetEnabled(enabled bool)
 	Data() CircuitData
+	State() CircuitState
+	OpenedAt() time.Time
+	TimeUntilReset() time.Duration
+	TimeUntilHalfOpen() time.Duration
+	History() []WindowStats
+	Transitions() []TransitionRecord
+	Snapshot() CircuitSnapshot
+	Subscribe() <-chan CallbackEvent
+	Unsubscribe(events <-chan CallbackEvent)
+	Name() string
+	GetOptions() CircuitOptions
+	UpdateOptions(monitorOptions CircuitOptions) error
+	Clone(name string, opts ...Option) (Circuit, error)
+	MarshalState() ([]byte, error)
+	RestoreState(data []byte) error
+	Close()
 }
 
 // CircuitOptions represents options for configuring a Circuit.
 type CircuitOptions struct {
-	Name              string  // Name of the circuit
-	Threshold         float32 // Threshold value for triggering circuit open
-	ThresholdType     string  // Type of threshold (e.g., percentage, count)
-	MinimumCount      int64   // Minimum number of events required for monitoring
-	IntervalInSeconds int     // Interval in seconds for monitoring (should be non-zero and multiple of 60)
-	OnCircuitOpen     func(t CallbackEvent)
-	OnCircuitClosed   func(t CallbackEvent)
+	Name                         string               // Name of the circuit, passed to callbacks in CallbackEvent and unique within a Tripper
+	Threshold                    float32              // Threshold value for triggering circuit open
+	ThresholdType                string               // Type of threshold (e.g., percentage, count)
+	CloseThreshold               float32              // Lower threshold below which an open circuit closes, to avoid flapping around Threshold (not for CONSECUTIVE, defaults to Threshold)
+	MinimumCount                 int64                // Minimum number of events required for monitoring, inclusive: the thresholds are evaluated from the MinimumCount-th event on
+	MinimumFailures              int64                // Minimum number of failures required for a PERCENTAGE threshold to trip (disabled when zero)
+	HalfLifeSeconds              float64              // Time after which an event counts half as much in the ThresholdEWMA failure rate (required with ThresholdEWMA)
+	MinRequestsPerSecond         float64              // Minimum average request rate since the interval started required, like MinimumCount, before the thresholds are evaluated (disabled when zero)
+	IntervalInSeconds            int                  // Interval in seconds for monitoring (a positive multiple of MinIntervalInSeconds)
+	OpenDurationInSeconds        int                  // How long the circuit stays open once tripped, regardless of the counts (defaults to closing with the interval reset)
+	StickyOpen                   bool                 // Keep an open circuit open across interval resets until the counts recorded since show recovery
+	WarmupSeconds                int                  // Grace period after the circuit is configured during which it records events but cannot open
+	RequireFullWindow            bool                 // Keep the circuit from opening until a full interval has elapsed since it was configured, reset or last closed
+	StaleAfterSeconds            int                  // Time without events after which the counts are cleared and the circuit closes, as its data no longer tells anything (disabled when zero)
+	MaxOpenSeconds               int                  // Time after which a circuit that has not closed since it opened, e.g. because no probe ever succeeds, is forced closed (disabled when zero)
+	Thresholds                   []ThresholdRule      // Multiple threshold rules evaluated together (replaces Threshold and ThresholdType when set)
+	ThresholdsOperator           string               // How Thresholds are combined (OperatorAnd or OperatorOr, defaults to OperatorAnd)
+	SlowCallThreshold            time.Duration        // Calls slower than this are counted as slow (disabled when zero)
+	SlowCallRateThreshold        float32              // Percentage of slow calls that opens the circuit (optional with BadCallRateThreshold)
+	BadCallRateThreshold         float32              // Percentage of calls that failed or were slower than SlowCallThreshold that opens the circuit (disabled when zero)
+	IsFailure                    func(err error) bool // Decides which errors returned to Execute or passed to UpdateStatusErr count as failures (defaults to any non-nil error)
+	FailureStatusCodes           []int                // Status codes recorded as failures by UpdateFromHTTPStatus instead of 500-599 when non-empty
+	CountContextErrorsAsFailure  bool                 // Record errors caused by context cancellation or deadline as failures in ExecuteContext
+	RecoverPanics                bool                 // Return a panic in the function passed to Execute as an error wrapping ErrPanic instead of raising it again once it is recorded as a failure
+	ProbeOnceKeepsOpen           bool                 // Only record the outcome of a successful ProbeOnce instead of closing the circuit with Reset
+	InvertPolarity               bool                 // Record every success as a failure and every failure as a success, so the thresholds trip on a spike of successes
+	MaxConcurrent                int64                // Calls allowed in flight at once through Execute and CircuitTransport, beyond which they fail with ErrTooManyRequests (unlimited when zero)
+	CloseConsecutiveCount        int64                // Consecutive successes required to close an open circuit (ThresholdConsecutive only, defaults to 1)
+	AllowedInterleavedSuccesses  int64                // Successes in a row that do not end a streak of consecutive failures (ThresholdConsecutive only, defaults to 0)
+	RequireMinimumCountPerBucket bool                 // Evaluate MinimumCount against a sliding window that includes part of the previous interval
+	RecordHistory                int                  // Number of last state transitions kept for Transitions (disabled when zero)
+	BackoffMultiplier            float64              // Factor applied to the open duration on every trip until a success is recorded after closing (disabled when zero)
+	MaxOpenDurationInSeconds     int                  // Upper bound of the open duration with BackoffMultiplier and CooldownJitter (unbounded when zero)
+	CooldownJitter               float64              // Fraction between 0 and 1 by which each open duration is randomly lengthened or shortened
+	Rand                         *rand.Rand           // Random source for CooldownJitter, not shared with other circuits (defaults to one seeded with the current time)
+	HalfOpenMaxProbes            int64                // Calls admitted by Execute while half-open, which must all succeed to close it; when set, the circuit goes half-open instead of closing once its open duration elapses
+	PercentageRounding           string               // How the failure percentage is rounded to a whole percent before comparing (RoundingExact, RoundingFloor, RoundingRound or RoundingCeil, defaults to RoundingExact)
+	OnCircuitOpen                func(t CallbackEvent)
+	OnCircuitClosed              func(t CallbackEvent)
+	OnStateChange                func(from, to CircuitState, t CallbackEvent) // Called on every state change, before OnCircuitOpen/OnCircuitClosed
+	OnRejected                   func(t CallbackEvent)                        // Called for every call rejected by Execute, AllowRequest or CircuitTransport because the circuit is open
+	OnUpdate                     func(success bool, data CircuitData)         // Called after every single event recorded, e.g. by UpdateStatus or Execute, with the data right after it
+	OnCallbackPanic              func(t CallbackEvent, recovered interface{}) // Called with the recovered value when a callback panics (defaults to logging it)
+	ThresholdStrategies          map[string]ThresholdStrategy                 // Custom threshold types by name, usable as ThresholdType or in Thresholds
+	ErrorClassifier              func(err error) string                       // Names the class of a failure recorded by Execute or UpdateStatusErr, counted in Data().FailuresByClass (disabled when nil)
+	MinCallbackInterval          time.Duration                                // Suppresses OnCircuitOpen/OnCircuitClosed when the same one was called less than this ago (disabled when zero)
+	AsyncCallbacks               bool                                         // Deliver callbacks from a dedicated goroutine instead of the calling one
+	ManualReset                  bool                                         // Do not start a ticker; the counts are only reset when ResetWindow is called
+	AlignToWallClock             bool                                         // Align the intervals to minute boundaries, so the first reset happens at the next full minute instead of IntervalInSeconds after the circuit was configured
+	BlockSlowSubscribers         bool                                         // Wait for a subscriber whose buffer is full instead of dropping the event
+	Clock                        Clock                                        // Time source for timestamps, the interval reset and the latencies measured by Execute (defaults to the system clock)
+	Logger                       Logger                                       // Receives a line for every state transition (defaults to logging nothing)
+	Context                      context.Context                              // Closes the circuit like Close once it is done, e.g. to shut down every circuit of an application at once (disabled when nil)
+}
+
+// ThresholdRule represents a single threshold used in CircuitOptions.Thresholds.
+type ThresholdRule struct {
+	ThresholdType  string  // Type of threshold (e.g., percentage, count)
+	Threshold      float32 // Threshold value for the rule
+	CloseThreshold float32 // Lower threshold below which an open circuit closes (not for CONSECUTIVE, defaults to Threshold)
 }
+
 type CircuitData struct {
-	SuccessCount       int64
-	FailureCount       int64
-	IsCircuitOpen      bool
-	CircuitOpenedSince int64
+	SuccessCount         int64
+	FailureCount         int64
+	SlowCallCount        int64
+	BadCallCount         int64   // Calls that failed or were slow, i.e. FailureCount plus the slow successes
+	TotalCount           int64   // SuccessCount + FailureCount
+	FailureRate          float64 // FailureCount / TotalCount between 0 and 1 (0 when nothing was recorded)
+	EWMAFailureRate      float64 // Exponentially weighted failure rate between 0 and 1 with HalfLifeSeconds (0 otherwise)
+	ConsecutiveCounter   int64   // Number of failures in the current streak, which ends with a success or more than AllowedInterleavedSuccesses successes in a row
+	IsCircuitOpen        bool
+	State                CircuitState // Current state of the circuit, as returned by State
+	CircuitOpenedSince   int64
+	OpenedAt             time.Time // Time the circuit opened, with sub-second precision when the Clock is a PreciseClock (zero when not open)
+	TripCount            int64     // Number of times the circuit has opened since it was configured
+	LastStateChangedAt   int64     // Timestamp of the last state change (0 if the state never changed)
+	LastStateChangedTime time.Time // Time of the last state change, like OpenedAt (zero if the state never changed)
+	WeightedSuccessCount float64   // Sum of the weights of the successes (equals SuccessCount without UpdateStatusWeighted)
+	WeightedFailureCount float64   // Sum of the weights of the failures (equals FailureCount without UpdateStatusWeighted)
+	BackoffLevel         int64     // Number of trips since the last recovery with BackoffMultiplier (0 when recovered)
+	InFlight             int64     // Number of calls running through Execute or CircuitTransport
+	ConcurrencyRejected  int64     // Number of calls rejected with ErrTooManyRequests since the circuit was configured, not counted as failures
+	RejectedThisEpisode  int64     // Number of calls rejected because the circuit is open during the current open episode, reset when it closes
+	SkippedCount         int64     // Number of calls skipped with UpdateStatusExt, cleared with the counts and not part of TotalCount
+	AllowedCount         int64     // Number of calls admitted by AllowRequest, Execute or CircuitTransport, cleared with the counts
+	RejectedCount        int64     // Number of calls rejected by AllowRequest, Execute or CircuitTransport because the circuit is open, cleared with the counts
+	ShedRatio            float64   // RejectedCount / (AllowedCount + RejectedCount) between 0 and 1 (0 when no call was admitted or rejected)
+	Threshold            float32   // Effective Threshold of the options, which UpdateOptions may have changed
+	ThresholdType        string    // Effective ThresholdType of the options (empty with Thresholds)
+	MinimumCount         int64     // Effective MinimumCount of the options
+	IntervalInSeconds    int       // Effective IntervalInSeconds of the options
+	// Number of failures per class from ErrorClassifier, cleared with the
+	// counts like FailureCount (nil without ErrorClassifier)
+	FailuresByClass map[string]int64
+	// Summary of the latencies recorded in the current interval with
+	// UpdateStatusWithLatency or Execute, cleared with the counts
+	Latency LatencySummary
 }
 
 // CircuitImplementation represents the implementation of the Circuit interface.
+//
+// Mutex guards every field. Holding it for writing allows any field to be read
+// or written directly. Holding it for reading allows the state fields to be
+// read, and the counters at the top of the struct to be read and written with
+// sync/atomic only; this is how UpdateStatus records events that cannot change
+// the state. Any change to the state or to the other fields requires the write
+// lock. Callbacks are always dispatched after Mutex is released.
+//
+// A nil *CircuitImplementation behaves like NewNoopCircuit: it never opens and
+// ignores every update.
 type CircuitImplementation struct {
-	Options            CircuitOptions
-	FailureCount       int64 // Number of failures recorded
-	SuccessCount       int64 // Number of successes recorded
-	CircuitOpen        bool  // Indicates whether the circuit is open or closed
-	LastCapturedAt     int64 // Timestamp of the last captured event
-	CircuitOpenedSince int64 // Timestamp when the circuit was opened
-	ConsecutiveCounter int64
-	Ticker             *time.Ticker
-	Mutex              sync.Mutex
-	XMutex             sync.Mutex
+	// The counters below are updated with sync/atomic while Mutex is only
+	// read-locked, so they come first to stay 64-bit aligned on 32-bit platforms.
+	FailureCount              int64 // Number of failures recorded
+	SuccessCount              int64 // Number of successes recorded
+	LastCapturedAt            int64 // Timestamp of the last captured event in Unix nanoseconds
+	ConsecutiveCounter        int64
+	ConsecutiveSuccessCounter int64 // Number of successes recorded since the last failure
+	weightedSuccesses         int64 // Sum of the weights of the successes in 1/weightScale units
+	weightedFailures          int64 // Sum of the weights of the failures in 1/weightScale units
+	inFlight                  int64 // Number of calls running through Execute or CircuitTransport
+	concurrencyRejections     int64 // Number of calls rejected because MaxConcurrent calls were in flight
+	rejectedThisEpisode       int64 // Number of calls rejected because the circuit is open since it last closed
+	skippedCount              int64 // Number of calls skipped with UpdateStatusExt in the current interval
+	allowedCount              int64 // Number of calls admitted by AllowRequest, Execute or CircuitTransport in the current interval
+	rejectedCount             int64 // Number of calls rejected because the circuit is open in the current interval
+	// Latencies recorded in the current interval, also updated with sync/atomic
+	latencies latencyHistogram
+
+	Options             CircuitOptions
+	SlowCallCount       int64  // Number of calls slower than SlowCallThreshold
+	SlowSuccessCount    int64  // Number of successful calls slower than SlowCallThreshold
+	CircuitOpen         bool   // Indicates whether the circuit is open or closed
+	Disabled            bool   // Indicates whether breaking is turned off with SetEnabled, in which case every call is allowed
+	HalfOpen            bool   // Indicates whether the circuit is half-open and admits probe calls
+	HalfOpenProbes      int64  // Number of probe calls admitted since the circuit became half-open
+	HalfOpenSuccesses   int64  // Number of probe calls that succeeded since the circuit became half-open
+	CircuitOpenedSince  int64  // Timestamp when the circuit was opened in Unix nanoseconds
+	CurrentOpenDuration int64  // How long the circuit stays open since CircuitOpenedSince in nanoseconds, including backoff and jitter
+	BackoffLevel        int64  // Number of trips since the last success recorded while closed, with BackoffMultiplier
+	TripCount           int64  // Number of times the circuit has opened
+	LastStateChangedAt  int64  // Timestamp of the last state change in Unix nanoseconds
+	WindowStartedAt     int64  // Timestamp when the current interval started in Unix nanoseconds
+	CreatedAt           int64  // Timestamp when the circuit was configured in Unix nanoseconds, from which WarmupSeconds is measured
+	ClosedSince         int64  // Timestamp when the circuit was configured, reset or last closed in Unix nanoseconds, from which RequireFullWindow is measured
+	PreviousWindowCount int64  // Number of events recorded in the previous interval
+	Ticker              Ticker // Resets the counts every interval, nil until the first UpdateStatus or AllowRequest
+	Mutex               sync.RWMutex
+	callbacks           chan CallbackEvent // Queue of events delivered by the callback goroutine when AsyncCallbacks is set
+	done                chan struct{}      // Closed by Close to stop the callback goroutine and the one waiting for Options.Context
+	stopped             chan struct{}      // Closed by the callback goroutine once it has returned
+	closeOnce           sync.Once
+	tickerOnce          sync.Once
+	randDefaulted       bool          // Whether Options.Rand was seeded by ConfigureCircuit rather than given
+	shutdown            bool          // Set by Close, after which events are ignored
+	windowOpened        bool          // Whether the circuit opened during the current interval
+	history             []WindowStats // Stats of the last completed intervals, oldest first
+	ewmaFailures        float64       // Decayed sum of the failure weights for ThresholdEWMA
+	ewmaTotal           float64       // Decayed sum of the event weights for ThresholdEWMA
+	ewmaUpdatedAt       time.Time     // Time the EWMA sums were last decayed
+	throttleMu          sync.Mutex    // Guards the timestamps below, never held while calling a callback
+	lastOpenCallbackAt  int64         // Timestamp of the last OnCircuitOpen call with MinCallbackInterval in Unix nanoseconds (0 if none)
+	lastCloseCallbackAt int64         // Timestamp of the last OnCircuitClosed call with MinCallbackInterval in Unix nanoseconds (0 if none)
+	subscribersMu       sync.Mutex    // Guards subscribers, acquired before Mutex when both are held
+	subscribers         []*subscription
+	failureClasses      map[string]int64   // Number of failures per class from ErrorClassifier
+	transitions         []TransitionRecord // Last RecordHistory state transitions, oldest first
+	openEpisodeAt       int64              // Timestamp when the circuit last left the closed state in Unix nanoseconds, from which MaxOpenSeconds is measured
+	clearedSuccesses    int64              // Number of successes cleared from the counts, added to SuccessCount by Snapshot
+	clearedFailures     int64              // Number of failures cleared from the counts, added to FailureCount by Snapshot
+	clearedSlowCalls    int64              // Number of slow calls cleared from the counts, added to SlowCallCount by Snapshot
 }
 
 // CallbackEvent represents an event callback for the circuit.
 type CallbackEvent struct {
+	Name         string // Name of the circuit that changed state
 	Timestamp    int64
 	SuccessCount int64
 	FailureCount int64
+	FromState    CircuitState // State before the transition
+	ToState      CircuitState // State after the transition
+	Reason       string       // Why the state changed, one of the Reason constants (empty for OnRejected)
+	rejected     bool         // Whether the event reports a rejected call to OnRejected
+	at           int64        // Timestamp in Unix nanoseconds, from which MinCallbackInterval is measured
 }
 
+// The reasons of a state change passed in CallbackEvent.Reason.
+const (
+	ReasonTripped             = "TRIPPED"               // The thresholds were breached
+	ReasonRecovered           = "RECOVERED"             // The recorded events or half-open probes show recovery
+	ReasonProbeFailed         = "PROBE_FAILED"          // A half-open probe failed
+	ReasonOpenDurationElapsed = "OPEN_DURATION_ELAPSED" // The open duration elapsed
+	ReasonIntervalReset       = "INTERVAL_RESET"        // The interval ended, with the ticker or ResetWindow
+	ReasonManualReset         = "MANUAL_RESET"          // Reset was called
+	ReasonStaleData           = "STALE_DATA"            // No event was recorded for StaleAfterSeconds
+	ReasonMaxOpenElapsed      = "MAX_OPEN_ELAPSED"      // The circuit was not closed for MaxOpenSeconds
+)
+
 func (m *CircuitImplementation) Data() CircuitData {
+	if m == nil {
+		return noopCircuit{}.Data()
+	}
+
+	m.refreshState()
+
+	m.Mutex.RLock()
+	defer m.Mutex.RUnlock()
+
+	return m.data()
+}
+
+// data returns the counts and state of the circuit as returned by Data. The
+// caller must hold m.Mutex for reading.
+func (m *CircuitImplementation) data() CircuitData {
+	successCount := atomic.LoadInt64(&m.SuccessCount)
+	failureCount := atomic.LoadInt64(&m.FailureCount)
+	totalCount := successCount + failureCount
+	allowedCount := atomic.LoadInt64(&m.allowedCount)
+	rejectedCount := atomic.LoadInt64(&m.rejectedCount)
+	failureRate := 0.0
+	if successCount > 0 || failureCount > 0 {
+		failureRate = float64(failureCount) / (float64(successCount) + float64(failureCount))
+	}
 	return CircuitData{
-		SuccessCount:       m.SuccessCount,
-		FailureCount:       m.FailureCount,
-		IsCircuitOpen:      m.CircuitOpen,
-		CircuitOpenedSince: m.CircuitOpenedSince,
+		SuccessCount:         successCount,
+		FailureCount:         failureCount,
+		SlowCallCount:        m.SlowCallCount,
+		BadCallCount:         failureCount + m.SlowSuccessCount,
+		TotalCount:           totalCount,
+		FailureRate:          failureRate,
+		EWMAFailureRate:      m.ewmaFailureRate(),
+		ConsecutiveCounter:   atomic.LoadInt64(&m.ConsecutiveCounter),
+		IsCircuitOpen:        m.CircuitOpen,
+		State:                m.state(),
+		CircuitOpenedSince:   unixSeconds(m.CircuitOpenedSince),
+		OpenedAt:             m.openedTime(),
+		TripCount:            m.TripCount,
+		LastStateChangedAt:   unixSeconds(m.LastStateChangedAt),
+		LastStateChangedTime: timeOf(m.LastStateChangedAt),
+		BackoffLevel:         m.BackoffLevel,
+		WeightedSuccessCount: weightedCount(atomic.LoadInt64(&m.weightedSuccesses)),
+		WeightedFailureCount: weightedCount(atomic.LoadInt64(&m.weightedFailures)),
+		InFlight:             atomic.LoadInt64(&m.inFlight),
+		ConcurrencyRejected:  atomic.LoadInt64(&m.concurrencyRejections),
+		RejectedThisEpisode:  atomic.LoadInt64(&m.rejectedThisEpisode),
+		SkippedCount:         atomic.LoadInt64(&m.skippedCount),
+		AllowedCount:         allowedCount,
+		RejectedCount:        rejectedCount,
+		ShedRatio:            shedRatio(allowedCount, rejectedCount),
+		FailuresByClass:      m.failuresByClass(),
+		Latency:              m.latencies.summary(),
+		Threshold:            m.Options.Threshold,
+		ThresholdType:        m.Options.ThresholdType,
+		MinimumCount:         m.Options.MinimumCount,
+		IntervalInSeconds:    m.Options.IntervalInSeconds,
 	}
 }
 
 // ConfigureCircuit creates and configures a new Circuit with the provided options.
+// On error the returned Circuit is nil and must not be used.
 func ConfigureCircuit(monitorOptions CircuitOptions) (Circuit, error) {
-	validThresholdType := false
-	for _, thType := range thresholdTypes {
-		if thType == monitorOptions.ThresholdType {
-			validThresholdType = true
-			break
+	if err := monitorOptions.Validate(); err != nil {
+		return nil, err
+	}
+
+	if monitorOptions.Clock == nil {
+		monitorOptions.Clock = realClock{}
+	}
+	randDefaulted := monitorOptions.Rand == nil
+	if randDefaulted {
+		monitorOptions.Rand = rand.New(rand.NewSource(time.Now().UnixNano()))
+	}
+	if monitorOptions.Logger == nil {
+		monitorOptions.Logger = noopLogger{}
+	}
+
+	newMonitor := &CircuitImplementation{
+		Options:            monitorOptions,
+		ConsecutiveCounter: 0,
+		randDefaulted:      randDefaulted,
+	}
+	newMonitor.CreatedAt = newMonitor.now()
+	newMonitor.WindowStartedAt = newMonitor.CreatedAt
+	newMonitor.ClosedSince = newMonitor.CreatedAt
+	if monitorOptions.AlignToWallClock {
+		newMonitor.alignWindow()
+	}
+	if monitorOptions.AsyncCallbacks || monitorOptions.Context != nil {
+		newMonitor.done = make(chan struct{})
+	}
+	if monitorOptions.AsyncCallbacks {
+		newMonitor.callbacks = make(chan CallbackEvent, callbackBufferSize)
+		newMonitor.stopped = make(chan struct{})
+		go newMonitor.runCallbacks()
+	}
+	if monitorOptions.Context != nil {
+		go newMonitor.closeOnDone(monitorOptions.Context)
+	}
+	return newMonitor, nil
+
+}
+
+// alignWindow moves the start of the current interval back so that it ends at
+// the next minute boundary. The circuit then starts in the interval in
+// progress, e.g. the one from 10:00:00 to 10:01:00 for a circuit configured at
+// 10:00:20 with an IntervalInSeconds of 60, and startTicker fires the first
+// reset at its end. It is called by ConfigureCircuit before the circuit is
+// shared.
+func (m *CircuitImplementation) alignWindow() {
+	nextMinute := timeOf(m.WindowStartedAt).Truncate(time.Minute).Add(time.Minute)
+	m.WindowStartedAt = nextMinute.Add(-time.Duration(m.Options.IntervalInSeconds) * time.Second).UnixNano()
+}
+
+// startTicker starts the interval reset on the first use of the circuit, so
+// that circuits registered up front that never receive traffic do not run a
+// goroutine each. The intervals still start when the circuit was configured:
+// the intervals that passed unused are skipped, as nothing was recorded in
+// them, and the first tick is shortened to the end of the current one.
+// Nothing is started with ManualReset or once the circuit was closed.
+func (m *CircuitImplementation) startTicker() {
+	m.tickerOnce.Do(func() {
+		m.Mutex.Lock()
+		defer m.Mutex.Unlock()
+
+		if m.shutdown || m.Options.ManualReset {
+			return
+		}
+		period := time.Duration(m.Options.IntervalInSeconds) * time.Second
+		elapsed := time.Duration(m.now() - m.WindowStartedAt)
+		if elapsed < 0 {
+			elapsed = 0
+		}
+		m.WindowStartedAt += int64(elapsed / period * period)
+		first := period - elapsed%period
+		shortened := true
+		m.Ticker = m.Options.Clock.NewTicker(first, func() {
+			if shortened {
+				shortened = false
+				m.Mutex.RLock()
+				ticker, interval := m.Ticker, m.Options.IntervalInSeconds
+				m.Mutex.RUnlock()
+				ticker.Reset(time.Duration(interval) * time.Second)
+			}
+			m.resetWindow()
+		})
+	})
+}
+
+// Validate checks that the options describe a valid circuit and returns the
+// ConfigError that ConfigureCircuit would return. It has no side effects, so
+// it can be used to check options at startup before any circuit is created.
+func (o CircuitOptions) Validate() error {
+	for name, strategy := range o.ThresholdStrategies {
+		if _, ok := thresholdStrategies[name]; ok || strategy == nil {
+			return configError(ErrInvalidThresholdStrategy, "ThresholdStrategies", "invalid threshold strategy %s", name)
+		}
+	}
+	rules := o.thresholdRules()
+	for _, rule := range rules {
+		if err := validateThresholdRule(rule, o.ThresholdStrategies); err != nil {
+			return err
+		}
+	}
+	if o.ThresholdsOperator != "" && o.ThresholdsOperator != OperatorAnd && o.ThresholdsOperator != OperatorOr {
+		return configError(ErrInvalidThresholdsOperator, "ThresholdsOperator", "invalid thresholds operator %s", o.ThresholdsOperator)
+	}
+
+	// if the minimum count is less than 1, return an error, unless only consecutive or failure count rules are used which ignore it
+	if o.MinimumCount < 0 || (o.MinimumCount < 1 && o.usesMinimumCount()) {
+		return configError(ErrInvalidMinimumCount, "MinimumCount", "invalid minimum count %d", o.MinimumCount)
+	}
+
+	//if threshold is type count then minimum count should be greater than threshold
+	for _, rule := range rules {
+		if rule.ThresholdType == ThresholdCount && o.MinimumCount <= int64(rule.Threshold) {
+			return configError(ErrMinimumCountBelowThreshold, "MinimumCount", "minimum count should be greater than threshold")
 		}
 	}
-	if !validThresholdType {
-		return nil, fmt.Errorf("invalid threshold type %s", monitorOptions.ThresholdType)
+
+	for _, code := range o.FailureStatusCodes {
+		if code < 100 || code > 599 {
+			return configError(ErrInvalidFailureStatusCode, "FailureStatusCodes", "invalid failure status code %d", code)
+		}
+	}
+
+	if o.MinimumFailures < 0 {
+		return configError(ErrInvalidMinimumFailures, "MinimumFailures", "invalid minimum failures %d", o.MinimumFailures)
+	}
+
+	if o.HalfLifeSeconds < 0 || (o.HalfLifeSeconds == 0 && o.hasRule(ThresholdEWMA)) {
+		return configError(ErrInvalidHalfLife, "HalfLifeSeconds", "invalid half life %f", o.HalfLifeSeconds)
+	}
+
+	if o.MinRequestsPerSecond < 0 {
+		return configError(ErrInvalidMinRequestsPerSecond, "MinRequestsPerSecond", "invalid minimum request rate %f", o.MinRequestsPerSecond)
+	}
+
+	if o.MaxConcurrent < 0 {
+		return configError(ErrInvalidMaxConcurrent, "MaxConcurrent", "invalid max concurrent %d", o.MaxConcurrent)
+	}
+
+	if o.OpenDurationInSeconds < 0 {
+		return configError(ErrInvalidOpenDuration, "OpenDurationInSeconds", "invalid open duration %d", o.OpenDurationInSeconds)
+	}
+	if o.WarmupSeconds < 0 {
+		return configError(ErrInvalidWarmup, "WarmupSeconds", "invalid warmup %d", o.WarmupSeconds)
+	}
+	if o.StaleAfterSeconds < 0 {
+		return configError(ErrInvalidStaleAfter, "StaleAfterSeconds", "invalid stale after %d", o.StaleAfterSeconds)
+	}
+	if o.MaxOpenSeconds < 0 {
+		return configError(ErrInvalidMaxOpen, "MaxOpenSeconds", "invalid max open %d", o.MaxOpenSeconds)
+	}
+	if o.MinCallbackInterval < 0 {
+		return configError(ErrInvalidMinCallbackInterval, "MinCallbackInterval", "invalid min callback interval %s", o.MinCallbackInterval)
+	}
+
+	switch o.PercentageRounding {
+	case "", RoundingExact, RoundingFloor, RoundingRound, RoundingCeil:
+	default:
+		return configError(ErrInvalidPercentageRounding, "PercentageRounding", "invalid percentage rounding %s", o.PercentageRounding)
+	}
+
+	// slow call tracking needs a rate between 0 and 100 to trip on
+	if o.SlowCallThreshold < 0 {
+		return configError(ErrInvalidSlowCallThreshold, "SlowCallThreshold", "invalid slow call threshold %s", o.SlowCallThreshold)
+	}
+	if o.SlowCallThreshold > 0 && (o.SlowCallRateThreshold < 0 || o.SlowCallRateThreshold > 100 || (o.SlowCallRateThreshold == 0 && o.BadCallRateThreshold == 0)) {
+		return configError(ErrInvalidSlowCallRateThreshold, "SlowCallRateThreshold", "invalid slow call rate threshold %f", o.SlowCallRateThreshold)
+	}
+	// a call can only be bad because it was slow when slow calls are tracked
+	if o.BadCallRateThreshold < 0 || o.BadCallRateThreshold > 100 || (o.BadCallRateThreshold > 0 && o.SlowCallThreshold <= 0) {
+		return configError(ErrInvalidBadCallRateThreshold, "BadCallRateThreshold", "invalid bad call rate threshold %f", o.BadCallRateThreshold)
+	}
+
+	if o.BackoffMultiplier != 0 && o.BackoffMultiplier < 1 {
+		return configError(ErrInvalidBackoffMultiplier, "BackoffMultiplier", "invalid backoff multiplier %f", o.BackoffMultiplier)
+	}
+	if o.MaxOpenDurationInSeconds < 0 {
+		return configError(ErrInvalidMaxOpenDuration, "MaxOpenDurationInSeconds", "invalid max open duration %d", o.MaxOpenDurationInSeconds)
+	}
+
+	if o.CooldownJitter < 0 || o.CooldownJitter > 1 {
+		return configError(ErrInvalidCooldownJitter, "CooldownJitter", "invalid cooldown jitter %f", o.CooldownJitter)
+	}
+
+	if o.HalfOpenMaxProbes < 0 {
+		return configError(ErrInvalidHalfOpenMaxProbes, "HalfOpenMaxProbes", "invalid half open max probes %d", o.HalfOpenMaxProbes)
+	}
+
+	if o.AllowedInterleavedSuccesses < 0 {
+		return configError(ErrInvalidAllowedInterleavedSuccesses, "AllowedInterleavedSuccesses", "invalid allowed interleaved successes %d", o.AllowedInterleavedSuccesses)
+	}
+
+	if o.RecordHistory < 0 {
+		return configError(ErrInvalidRecordHistory, "RecordHistory", "invalid record history %d", o.RecordHistory)
+	}
+
+	// a negative close count can never be reached
+	if o.CloseConsecutiveCount < 0 {
+		return configError(ErrInvalidCloseConsecutiveCount, "CloseConsecutiveCount", "invalid close consecutive count %d", o.CloseConsecutiveCount)
+	}
+
+	if o.IntervalInSeconds < MinIntervalInSeconds {
+		return configError(ErrInvalidInterval, "IntervalInSeconds", "invalid interval %d, should be at least %d", o.IntervalInSeconds, MinIntervalInSeconds)
+	}
+	// the interval must be a whole number of minutes
+	if o.IntervalInSeconds%MinIntervalInSeconds != 0 {
+		return configError(ErrInvalidInterval, "IntervalInSeconds", "invalid interval %d, should be a multiple of %d", o.IntervalInSeconds, MinIntervalInSeconds)
+	}
+	return nil
+}
+
+// thresholdRules returns the rules the circuit is evaluated against, falling
+// back to the single Threshold and ThresholdType when no Thresholds are set.
+func (o CircuitOptions) thresholdRules() []ThresholdRule {
+	if len(o.Thresholds) > 0 {
+		return o.Thresholds
+	}
+	return []ThresholdRule{{ThresholdType: o.ThresholdType, Threshold: o.Threshold, CloseThreshold: o.CloseThreshold}}
+}
+
+// usesMinimumCount reports whether MinimumCount gates any of the rules. It
+// gates the COUNT and PERCENTAGE thresholds and the slow call rate, but not
+// ThresholdConsecutive or ThresholdFailureCount.
+func (o CircuitOptions) usesMinimumCount() bool {
+	if o.SlowCallThreshold > 0 {
+		return true
+	}
+	for _, rule := range o.thresholdRules() {
+		if gatedByMinimumCount(rule.ThresholdType) {
+			return true
+		}
+	}
+	return false
+}
+
+// hasUngatedRule reports whether any of the rules can trip before MinimumCount
+// events were recorded.
+func (o CircuitOptions) hasUngatedRule() bool {
+	for _, rule := range o.thresholdRules() {
+		if !gatedByMinimumCount(rule.ThresholdType) {
+			return true
+		}
+	}
+	return false
+}
+
+// hasRule reports whether any of the rules has the given threshold type.
+func (o CircuitOptions) hasRule(thresholdType string) bool {
+	for _, rule := range o.thresholdRules() {
+		if rule.ThresholdType == thresholdType {
+			return true
+		}
+	}
+	return false
+}
+
+// gatedByMinimumCount reports whether a threshold type is only evaluated once
+// MinimumCount events were recorded.
+func gatedByMinimumCount(thresholdType string) bool {
+	return thresholdType == ThresholdCount || thresholdType == ThresholdPercentage || thresholdType == ThresholdEWMA
+}
+
+// validateThresholdRule checks the threshold type and that the value is valid for that type.
+func validateThresholdRule(rule ThresholdRule, strategies map[string]ThresholdStrategy) error {
+	if _, ok := thresholdStrategies[rule.ThresholdType]; !ok && strategies[rule.ThresholdType] == nil {
+		return configError(ErrInvalidThresholdType, "ThresholdType", "invalid threshold type %s", rule.ThresholdType)
 	}
 	//if the threshold type is percentage, check if the threshold is between 0 and 100
-	if monitorOptions.ThresholdType == ThresholdPercentage && (monitorOptions.Threshold < 0 || monitorOptions.Threshold > 100) {
-		return nil, fmt.Errorf("invalid threshold value %f for percentage type", monitorOptions.Threshold)
+	if rule.ThresholdType == ThresholdPercentage && (rule.Threshold < 0 || rule.Threshold > 100) {
+		return configError(ErrInvalidThreshold, "Threshold", "invalid threshold value %f for percentage type", rule.Threshold)
+	}
+	if rule.ThresholdType == ThresholdEWMA && (rule.Threshold < 0 || rule.Threshold > 100) {
+		return configError(ErrInvalidThreshold, "Threshold", "invalid threshold value %f for ewma type", rule.Threshold)
 	}
 	// if the threshold type is count, check if the threshold is greater than 0
-	if monitorOptions.ThresholdType == ThresholdCount && monitorOptions.Threshold <= 0 {
-		return nil, fmt.Errorf("invalid threshold value %f for count type", monitorOptions.Threshold)
+	if rule.ThresholdType == ThresholdCount && rule.Threshold <= 0 {
+		return configError(ErrInvalidThreshold, "Threshold", "invalid threshold value %f for count type", rule.Threshold)
+	}
+	if rule.ThresholdType == ThresholdFailureCount && rule.Threshold <= 0 {
+		return configError(ErrInvalidThreshold, "Threshold", "invalid threshold value %f for failure count type", rule.Threshold)
+	}
+	// a consecutive threshold is a whole number of failures in a row
+	if rule.ThresholdType == ThresholdConsecutive && (rule.Threshold < 1 || rule.Threshold != float32(math.Trunc(float64(rule.Threshold)))) {
+		return configError(ErrInvalidThreshold, "Threshold", "invalid threshold value %f for consecutive type", rule.Threshold)
 	}
+	// an open circuit closes below the close threshold, so it cannot be above the threshold
+	if rule.CloseThreshold < 0 || rule.CloseThreshold > rule.Threshold || (rule.CloseThreshold > 0 && rule.ThresholdType == ThresholdConsecutive) {
+		return configError(ErrInvalidCloseThreshold, "CloseThreshold", "invalid close threshold %f for %s threshold %f", rule.CloseThreshold, rule.ThresholdType, rule.Threshold)
+	}
+	return nil
+}
 
-	// if the minimum count is less than 1, return an error
-	if monitorOptions.MinimumCount < 1 {
-		return nil, fmt.Errorf("invalid minimum count %d", monitorOptions.MinimumCount)
+// resetWindow clears the counts and closes the circuit at the end of every
+// interval. A circuit with an OpenDurationInSeconds that has not elapsed yet
+// or with StickyOpen stays open, and a half-open circuit waits for the outcome
+// of its probes.
+func (m *CircuitImplementation) resetWindow() {
+	m.Mutex.Lock()
+	if m.shutdown {
+		m.Mutex.Unlock()
+		return
+	}
+	now := m.now()
+	m.recordWindow()
+	events := m.expireMaxOpen(now)
+	events = append(events, m.expireOpenDuration(now)...)
+	m.PreviousWindowCount = m.SuccessCount + m.FailureCount
+	m.WindowStartedAt = now
+	m.clearCounts()
+	if len(events) == 0 && !m.holdsOpenForDuration() && !m.HalfOpen && !(m.Options.StickyOpen && m.CircuitOpen) {
+		if m.CircuitOpen {
+			m.clearEWMA()
+		}
+		fromState := m.state()
+		m.CircuitOpenedSince = 0
+		m.CircuitOpen = false
+		if fromState != StateClosed {
+			m.recordTransition(fromState, now, ReasonIntervalReset)
+			events = append(events, m.callbackEvent(now, fromState, ReasonIntervalReset))
+		}
 	}
+	m.windowOpened = m.CircuitOpen
+	m.Mutex.Unlock()
 
-	//if threshold is type count then minimum count should be greater than threshold
-	if monitorOptions.ThresholdType == ThresholdCount && monitorOptions.MinimumCount <= int64(monitorOptions.Threshold) {
-		return nil, fmt.Errorf("minimum count should be greater than threshold")
+	m.dispatch(events...)
+}
+
+// ResetWindow ends the current interval right away, like the ticker does every
+// IntervalInSeconds: the counts are cleared and the circuit closes unless it
+// is held open. With ManualReset this is the only way the counts are reset,
+// e.g. from a scheduler shared by many circuits or after a number of requests.
+func (m *CircuitImplementation) ResetWindow() {
+	if m == nil {
+		return
 	}
+	m.resetWindow()
+}
 
-	// if the interval is less than 5, return an error
-	if monitorOptions.IntervalInSeconds < 5 {
-		return nil, fmt.Errorf("invalid interval %d", monitorOptions.IntervalInSeconds)
+// Reset closes the circuit and clears its counts, e.g. once an operator knows
+// the dependency recovered, as if it had just been configured. Unlike
+// ResetWindow it also ends an open duration, a half-open phase and the
+// backoff. OnCircuitClosed and OnStateChange are invoked if the circuit was not
+// closed. TripCount, History and Transitions are kept.
+func (m *CircuitImplementation) Reset() {
+	if m == nil {
+		return
 	}
 
-	newMonitor := &CircuitImplementation{
-		Options:            monitorOptions,
-		ConsecutiveCounter: 0,
+	m.Mutex.Lock()
+	if m.shutdown {
+		m.Mutex.Unlock()
+		return
 	}
-	newMonitor.Ticker = time.NewTicker(time.Duration(monitorOptions.IntervalInSeconds) * time.Second)
-	go func() {
-
-		for range newMonitor.Ticker.C {
-
-			newMonitor.SuccessCount = 0
-			newMonitor.FailureCount = 0
-			newMonitor.CircuitOpenedSince = 0
-			newMonitor.ConsecutiveCounter = 0
-			newMonitor.CircuitOpen = false
-			if newMonitor.Options.OnCircuitClosed != nil {
-				newMonitor.Options.OnCircuitClosed(CallbackEvent{
-					Timestamp:    getTimestamp(),
-					SuccessCount: newMonitor.SuccessCount,
-					FailureCount: newMonitor.FailureCount,
-				})
-			}
-		}
-	}()
-	return newMonitor, nil
+	now := m.now()
+	fromState := m.state()
+	m.clearCounts()
+	m.clearEWMA()
+	m.PreviousWindowCount = 0
+	m.CircuitOpen = false
+	m.HalfOpen = false
+	m.HalfOpenProbes = 0
+	m.HalfOpenSuccesses = 0
+	m.CircuitOpenedSince = 0
+	m.CurrentOpenDuration = 0
+	m.BackoffLevel = 0
+	m.ClosedSince = now
+	var events []CallbackEvent
+	if fromState != StateClosed {
+		m.recordTransition(fromState, now, ReasonManualReset)
+		events = append(events, m.callbackEvent(now, fromState, ReasonManualReset))
+	}
+	m.Mutex.Unlock()
 
+	m.dispatch(events...)
 }
 
-// UpdateStatus updates the status of the Circuit based on the success of the event.
+// clearCounts resets the counts of the current window, keeping their totals
+// for Snapshot. The caller must hold m.Mutex.
+func (m *CircuitImplementation) clearCounts() {
+	m.clearedSuccesses += m.SuccessCount
+	m.clearedFailures += m.FailureCount
+	m.clearedSlowCalls += m.SlowCallCount
+	m.SuccessCount = 0
+	m.FailureCount = 0
+	m.SlowCallCount = 0
+	m.SlowSuccessCount = 0
+	m.skippedCount = 0
+	// admissions are counted without holding m.Mutex
+	atomic.StoreInt64(&m.allowedCount, 0)
+	atomic.StoreInt64(&m.rejectedCount, 0)
+	m.ConsecutiveCounter = 0
+	m.ConsecutiveSuccessCounter = 0
+	m.weightedSuccesses = 0
+	m.weightedFailures = 0
+	m.failureClasses = nil
+	m.latencies = latencyHistogram{}
+}
+
+// UpdateStatus updates the status of the Circuit based on the success of the
+// event. Events are ignored once the circuit was closed with Close.
 func (m *CircuitImplementation) UpdateStatus(success bool) {
-	//add a lock here
+	m.updateStatus(success, 0, weightScale, "")
+}
+
+// UpdateStatusE is like UpdateStatus but returns ErrCircuitShutdown when the
+// event was ignored because the circuit was closed with Close.
+func (m *CircuitImplementation) UpdateStatusE(success bool) error {
+	return m.updateStatus(success, 0, weightScale, "")
+}
+
+// UpdateStatusExt is like UpdateStatus, but when record is false the outcome is
+// not recorded at all, e.g. for a call that failed because of bad input rather
+// than a problem of the dependency. A skipped call is only counted in
+// Data().SkippedCount, so it is in neither SuccessCount nor FailureCount and
+// the failure rate is computed over the recorded calls only. A skipped call
+// does not give back a half-open probe slot, as it may not hold one; admit
+// the call with AllowProbe to give back its slot.
+//
+//	err := callService(input)
+//	circuit.UpdateStatusExt(err == nil, !errors.Is(err, errBadInput))
+func (m *CircuitImplementation) UpdateStatusExt(success bool, record bool) {
+	if m == nil {
+		return
+	}
+	if !record {
+		m.countSkipped()
+		return
+	}
+	m.updateStatus(success, 0, weightScale, "")
+}
+
+// countSkipped counts a call skipped with UpdateStatusExt in the current
+// interval. Skipped calls are ignored once the circuit was closed.
+func (m *CircuitImplementation) countSkipped() {
+	m.startTicker()
+
+	m.Mutex.RLock()
+	defer m.Mutex.RUnlock()
+	if !m.shutdown {
+		atomic.AddInt64(&m.skippedCount, 1)
+	}
+}
+
+// updateStatus records an event and dispatches the callbacks for any state
+// change. It returns ErrCircuitShutdown without recording the event once the
+// circuit was closed. Events that cannot change the state of a COUNT or PERCENTAGE circuit
+// are recorded with atomic counters under the read lock so concurrent callers
+// do not serialize; only a state change takes the write lock. With
+// InvertPolarity the event is flipped once, before it is recorded. A failure
+// with a class from ErrorClassifier is counted per class, which needs the write
+// lock.
+func (m *CircuitImplementation) updateStatus(success bool, latency time.Duration, weight int64, class string) error {
+	if m == nil {
+		return noopCircuit{}.UpdateStatusE(success)
+	}
+	m.startTicker()
+
+	m.Mutex.RLock()
+	if m.shutdown {
+		m.Mutex.RUnlock()
+		return ErrCircuitShutdown
+	}
+	if m.Options.InvertPolarity {
+		success = !success
+	}
+	if success {
+		class = ""
+	}
+	recorded, settled := false, false
+	if class == "" {
+		recorded, settled = m.recordStatusShared(success, latency, weight)
+	}
+	if settled {
+		notice := m.updateNotice(success)
+		m.Mutex.RUnlock()
+		if notice != nil {
+			notice()
+		}
+		return nil
+	}
+	m.Mutex.RUnlock()
+
 	m.Mutex.Lock()
-	defer m.Mutex.Unlock()
+	if m.shutdown && !recorded {
+		m.Mutex.Unlock()
+		return ErrCircuitShutdown
+	}
+	var events []CallbackEvent
+	if recorded {
+		events = m.evaluateStatus()
+	} else {
+		events = m.recordStatus(success, latency, weight)
+	}
+	if class != "" {
+		m.countFailureClass(class)
+	}
+	notice := m.updateNotice(success)
+	m.Mutex.Unlock()
+
+	m.dispatch(events...)
+	if notice != nil {
+		notice()
+	}
+	return nil
+}
+
+// recordStatusShared records an event while m.Mutex is only read-locked. It
+// returns whether the event was recorded and whether the state is settled,
+// i.e. the event cannot change the state. When the event was not recorded it
+// must be recorded with recordStatus; when it was recorded but the state is
+// not settled the state must be re-evaluated with evaluateStatus.
+func (m *CircuitImplementation) recordStatusShared(success bool, latency time.Duration, weight int64) (bool, bool) {
+	if !m.usesSharedPath() {
+		return false, false
+	}
+	now := m.now()
+	if m.openDurationElapsed(now) || m.staleData(now) || m.maxOpenElapsed(now) {
+		// the open duration elapsed, the data is stale or the circuit was
+		// open for too long and the window must be cleared first
+		return false, false
+	}
+	atomic.StoreInt64(&m.LastCapturedAt, now)
+	m.latencies.record(latency)
+	if success {
+		if atomic.AddInt64(&m.ConsecutiveSuccessCounter, 1) > m.Options.AllowedInterleavedSuccesses {
+			atomic.StoreInt64(&m.ConsecutiveCounter, 0)
+		}
+		atomic.AddInt64(&m.SuccessCount, 1)
+		addWeight(&m.weightedSuccesses, weight)
+	} else {
+		atomic.AddInt64(&m.ConsecutiveCounter, 1)
+		atomic.StoreInt64(&m.ConsecutiveSuccessCounter, 0)
+		atomic.AddInt64(&m.FailureCount, 1)
+		addWeight(&m.weightedFailures, weight)
+	}
+	if !m.minimumCountReached() && !m.Options.hasUngatedRule() {
+		return true, true
+	}
+	return true, m.shouldBeOpen() == m.CircuitOpen
+}
+
+// usesSharedPath reports whether events can be recorded under the read lock.
+// The consecutive threshold depends on the exact order of events, slow call
+// tracking on the latency, a half-open circuit changes state on every event
+// and a success resets the backoff level, so those always take the write lock,
+// as do the EWMA with HalfLifeSeconds and custom ThresholdStrategies. The
+// caller must hold m.Mutex for reading.
+func (m *CircuitImplementation) usesSharedPath() bool {
+	if m.Options.SlowCallThreshold > 0 || m.Options.HalfLifeSeconds > 0 || m.HalfOpen || m.BackoffLevel > 0 {
+		return false
+	}
+	for _, rule := range m.Options.thresholdRules() {
+		if _, builtin := thresholdStrategies[rule.ThresholdType]; rule.ThresholdType == ThresholdConsecutive || !builtin {
+			return false
+		}
+	}
+	return true
+}
 
-	m.LastCapturedAt = getTimestamp()
+// recordStatus records an event and re-evaluates the state of the circuit. It
+// returns the callback events for any state changes. The caller must hold
+// m.Mutex.
+func (m *CircuitImplementation) recordStatus(success bool, latency time.Duration, weight int64) []CallbackEvent {
+	now := m.now()
+	events := m.expireStaleData(now)
+	m.LastCapturedAt = now
+	events = append(events, m.expireMaxOpen(now)...)
+	events = append(events, m.expireOpenDuration(now)...)
+	m.latencies.record(latency)
+	if m.Options.SlowCallThreshold > 0 && latency > m.Options.SlowCallThreshold {
+		m.SlowCallCount++
+		if success {
+			m.SlowSuccessCount++
+		}
+	}
 	if success {
-		m.ConsecutiveCounter = 0
+		m.ConsecutiveSuccessCounter++
+		if m.ConsecutiveSuccessCounter > m.Options.AllowedInterleavedSuccesses {
+			// the streak of failures ended
+			m.ConsecutiveCounter = 0
+		}
 		m.SuccessCount++
+		addWeight(&m.weightedSuccesses, weight)
 	} else {
 		m.ConsecutiveCounter++
+		m.ConsecutiveSuccessCounter = 0
 		m.FailureCount++
+		addWeight(&m.weightedFailures, weight)
+	}
+	if success {
+		m.recordEWMA(weightedCount(weight), 0, timeOf(m.LastCapturedAt))
+	} else {
+		m.recordEWMA(0, weightedCount(weight), timeOf(m.LastCapturedAt))
+	}
+	if m.HalfOpen {
+		return append(events, m.recordProbe(success)...)
+	}
+	if success && !m.CircuitOpen {
+		// the dependency recovered after the circuit closed
+		m.BackoffLevel = 0
+	}
+	return append(events, m.evaluateStatus()...)
+}
+
+// evaluateStatus opens or closes the circuit based on the recorded counts and
+// returns the callback event for the state change, if any. The caller must
+// hold m.Mutex.
+func (m *CircuitImplementation) evaluateStatus() []CallbackEvent {
+	if !m.minimumCountReached() && !m.Options.hasUngatedRule() {
+		return nil
+	}
+	currentStateOfCircuit := m.state()
+	open := m.shouldBeOpen()
+	if open == m.CircuitOpen {
+		return nil
+	}
+	m.CircuitOpen = open
+	reason := ReasonRecovered
+	if open {
+		m.startOpenDuration()
+		reason = ReasonTripped
+	} else {
+		m.CircuitOpenedSince = 0
+	}
+	m.recordTransition(currentStateOfCircuit, m.LastCapturedAt, reason)
+	return []CallbackEvent{m.callbackEvent(m.LastCapturedAt, currentStateOfCircuit, reason)}
+}
+
+// minimumCountReached reports whether enough events were recorded in the
+// current window to evaluate the thresholds, both in total and, with
+// MinRequestsPerSecond, as an average rate over the time the window has been
+// open. Both bounds are inclusive, so the event that reaches MinimumCount can
+// trip the circuit. The caller must hold m.Mutex for reading.
+func (m *CircuitImplementation) minimumCountReached() bool {
+	totalCount := atomic.LoadInt64(&m.SuccessCount) + atomic.LoadInt64(&m.FailureCount)
+	if m.Options.RequireMinimumCountPerBucket {
+		totalCount += m.previousWindowShare()
+	}
+	if totalCount < m.Options.MinimumCount {
+		return false
+	}
+	if m.Options.MinRequestsPerSecond > 0 {
+		rate := float64(totalCount) / m.rateWindow().Seconds()
+		return rate >= m.Options.MinRequestsPerSecond
+	}
+	return true
+}
+
+// rateWindow returns the time over which MinRequestsPerSecond averages the
+// events: the elapsed part of the current interval, at least a second so that
+// the first events do not count as an unbounded rate. With
+// RequireMinimumCountPerBucket the events of the previous interval still in the
+// sliding window are counted too, so the window is then a whole interval. The
+// caller must hold m.Mutex for reading.
+func (m *CircuitImplementation) rateWindow() time.Duration {
+	interval := seconds(m.Options.IntervalInSeconds)
+	if m.Options.RequireMinimumCountPerBucket && m.PreviousWindowCount > 0 {
+		return time.Duration(interval)
+	}
+	elapsed := m.now() - m.WindowStartedAt
+	if elapsed < int64(time.Second) {
+		elapsed = int64(time.Second)
+	}
+	if elapsed > interval {
+		elapsed = interval
+	}
+	return time.Duration(elapsed)
+}
+
+// previousWindowShare returns the part of the previous interval's events that
+// still falls within a sliding window of IntervalInSeconds ending now,
+// assuming the events were spread evenly over the previous interval. The
+// caller must hold m.Mutex for reading.
+func (m *CircuitImplementation) previousWindowShare() int64 {
+	interval := seconds(m.Options.IntervalInSeconds)
+	elapsed := m.now() - m.WindowStartedAt
+	if elapsed >= interval {
+		return 0
+	}
+	if elapsed < 0 {
+		elapsed = 0
+	}
+	return int64(float64(m.PreviousWindowCount) * float64(interval-elapsed) / float64(interval))
+}
+
+// shouldBeOpen reports whether the circuit should be open given the recorded
+// counts. The caller must hold m.Mutex for reading.
+func (m *CircuitImplementation) shouldBeOpen() bool {
+	if m.holdsOpenForDuration() {
+		// the circuit stays open until its open duration elapses
+		return true
+	}
+	if !m.CircuitOpen && (m.warmingUp() || m.inFirstWindow()) {
+		// the circuit cannot open before the warmup period or its first full
+		// interval is over
+		return false
+	}
+	if m.thresholdBreached() || (m.minimumCountReached() && (m.slowCallRateBreached() || m.badCallRateBreached())) {
+		return true
+	}
+	// an open circuit stays open until enough consecutive successes are seen
+	return m.CircuitOpen && m.holdsOpen()
+}
+
+// warmingUp reports whether the circuit is within WarmupSeconds of being
+// configured. The caller must hold m.Mutex for reading.
+func (m *CircuitImplementation) warmingUp() bool {
+	return m.Options.WarmupSeconds > 0 && m.now()-m.CreatedAt < seconds(m.Options.WarmupSeconds)
+}
+
+// inFirstWindow reports whether RequireFullWindow keeps the circuit from
+// opening because less than a full interval has elapsed since ClosedSince.
+// The caller must hold m.Mutex for reading.
+func (m *CircuitImplementation) inFirstWindow() bool {
+	return m.Options.RequireFullWindow && m.now()-m.ClosedSince < seconds(m.Options.IntervalInSeconds)
+}
+
+// openDuration returns how long the circuit stays open in nanoseconds, which
+// defaults to the interval.
+func (m *CircuitImplementation) openDuration() int64 {
+	if m.Options.OpenDurationInSeconds > 0 {
+		return seconds(m.Options.OpenDurationInSeconds)
+	}
+	return seconds(m.Options.IntervalInSeconds)
+}
+
+// startOpenDuration records that the circuit opened at LastCapturedAt and
+// picks how long it stays open, multiplied by BackoffMultiplier for every
+// previous trip since the last recovery, randomized by CooldownJitter and
+// bounded by MaxOpenDurationInSeconds. The caller must hold m.Mutex.
+func (m *CircuitImplementation) startOpenDuration() {
+	m.CircuitOpenedSince = m.LastCapturedAt
+	duration := time.Duration(m.openDuration()).Seconds()
+	if m.Options.BackoffMultiplier > 0 {
+		m.BackoffLevel++
+		duration *= math.Pow(m.Options.BackoffMultiplier, float64(m.BackoffLevel-1))
+	}
+	if m.Options.CooldownJitter > 0 {
+		duration *= 1 + m.Options.CooldownJitter*(2*m.Options.Rand.Float64()-1)
+	}
+	if maxDuration := float64(m.Options.MaxOpenDurationInSeconds); maxDuration > 0 && duration > maxDuration {
+		duration = maxDuration
+	}
+	if duration > maxOpenDuration.Seconds() {
+		duration = maxOpenDuration.Seconds()
+	}
+	m.CurrentOpenDuration = int64(duration * float64(time.Second))
+	if m.CurrentOpenDuration < int64(time.Second) {
+		m.CurrentOpenDuration = int64(time.Second)
 	}
-	if m.SuccessCount+m.FailureCount < m.Options.MinimumCount {
+}
+
+// currentOpenDuration returns how long the circuit stays open since
+// CircuitOpenedSince. The caller must hold m.Mutex for reading.
+func (m *CircuitImplementation) currentOpenDuration() int64 {
+	if m.CurrentOpenDuration > 0 {
+		return m.CurrentOpenDuration
+	}
+	return m.openDuration()
+}
+
+// holdsOpenForDuration reports whether the circuit is open with an explicit
+// OpenDurationInSeconds or HalfOpenMaxProbes, in which case it ignores the
+// counts until the open duration elapses. The caller must hold m.Mutex.
+func (m *CircuitImplementation) holdsOpenForDuration() bool {
+	return m.CircuitOpen && (m.Options.OpenDurationInSeconds > 0 || m.Options.HalfOpenMaxProbes > 0)
+}
+
+// openDurationElapsed reports whether the circuit is held open for a duration
+// that has elapsed at now. The caller must hold m.Mutex for reading.
+func (m *CircuitImplementation) openDurationElapsed(now int64) bool {
+	return m.holdsOpenForDuration() && now >= m.CircuitOpenedSince+m.currentOpenDuration()
+}
+
+// refreshState closes or half-opens a circuit whose open duration has elapsed,
+// so that reads reflect the recovery even when no events were recorded since,
+// clears data that became stale and closes a circuit open for MaxOpenSeconds.
+func (m *CircuitImplementation) refreshState() {
+	m.Mutex.RLock()
+	now := m.now()
+	elapsed := m.openDurationElapsed(now) || m.staleData(now) || m.maxOpenElapsed(now)
+	m.Mutex.RUnlock()
+	if !elapsed {
 		return
 	}
 
-	currentStateOfCircuit := m.CircuitOpen
+	m.Mutex.Lock()
+	now = m.now()
+	events := m.expireStaleData(now)
+	events = append(events, m.expireMaxOpen(now)...)
+	events = append(events, m.expireOpenDuration(now)...)
+	m.Mutex.Unlock()
 
-	if m.Options.ThresholdType == ThresholdCount {
-		if float32(m.FailureCount) >= m.Options.Threshold {
-			m.CircuitOpen = true
-			m.CircuitOpenedSince = m.LastCapturedAt
-		} else {
-			m.CircuitOpen = false
-			m.CircuitOpenedSince = 0
+	m.dispatch(events...)
+}
+
+// expireOpenDuration closes a circuit whose open duration has elapsed, or
+// makes it half-open when HalfOpenMaxProbes is set, and starts a fresh window.
+// It returns the callback event for the transition, if any. The caller must
+// hold m.Mutex.
+func (m *CircuitImplementation) expireOpenDuration(now int64) []CallbackEvent {
+	if !m.openDurationElapsed(now) {
+		return nil
+	}
+	fromState := m.state()
+	m.clearCounts()
+	m.clearEWMA()
+	m.CircuitOpen = false
+	m.CircuitOpenedSince = 0
+	if m.Options.HalfOpenMaxProbes > 0 {
+		m.HalfOpen = true
+		m.HalfOpenProbes = 0
+		m.HalfOpenSuccesses = 0
+	}
+	m.recordTransition(fromState, now, ReasonOpenDurationElapsed)
+	return []CallbackEvent{m.callbackEvent(now, fromState, ReasonOpenDurationElapsed)}
+}
+
+// staleData reports whether more than StaleAfterSeconds passed since the last
+// event while the circuit still has counts or is not closed. The caller must hold m.Mutex
+// for reading.
+func (m *CircuitImplementation) staleData(now int64) bool {
+	if m.Options.StaleAfterSeconds <= 0 {
+		return false
+	}
+	lastCapturedAt := atomic.LoadInt64(&m.LastCapturedAt)
+	if lastCapturedAt == 0 || now-lastCapturedAt <= seconds(m.Options.StaleAfterSeconds) {
+		return false
+	}
+	return m.CircuitOpen || m.HalfOpen || atomic.LoadInt64(&m.SuccessCount)+atomic.LoadInt64(&m.FailureCount) > 0
+}
+
+// expireStaleData clears the counts of a circuit whose data became stale and
+// closes it, ending any open duration or half-open phase, as stale data does
+// not tell whether the dependency is healthy. The backoff level is kept. It
+// returns the callback event for the transition, if any. The caller must hold
+// m.Mutex.
+func (m *CircuitImplementation) expireStaleData(now int64) []CallbackEvent {
+	if !m.staleData(now) {
+		return nil
+	}
+	fromState := m.state()
+	m.clearCounts()
+	m.clearEWMA()
+	m.PreviousWindowCount = 0
+	m.CircuitOpen = false
+	m.CircuitOpenedSince = 0
+	m.HalfOpen = false
+	m.HalfOpenProbes = 0
+	m.HalfOpenSuccesses = 0
+	if fromState == StateClosed {
+		return nil
+	}
+	m.recordTransition(fromState, now, ReasonStaleData)
+	return []CallbackEvent{m.callbackEvent(now, fromState, ReasonStaleData)}
+}
+
+// maxOpenElapsed reports whether the circuit has not been closed for
+// MaxOpenSeconds at now. The caller must hold m.Mutex for reading.
+func (m *CircuitImplementation) maxOpenElapsed(now int64) bool {
+	if m.Options.MaxOpenSeconds <= 0 || (!m.CircuitOpen && !m.HalfOpen) {
+		return false
+	}
+	return now >= m.openEpisodeAt+seconds(m.Options.MaxOpenSeconds)
+}
+
+// expireMaxOpen forces a circuit that has not been closed for MaxOpenSeconds
+// closed, e.g. when no probe ever succeeds or no traffic flows to show the
+// recovery, so that the dependency is not shed forever. The counts are cleared
+// like on the interval reset and the backoff level is kept. It returns the
+// callback event for the transition, if any. The caller must hold m.Mutex.
+func (m *CircuitImplementation) expireMaxOpen(now int64) []CallbackEvent {
+	if !m.maxOpenElapsed(now) {
+		return nil
+	}
+	fromState := m.state()
+	m.clearCounts()
+	m.clearEWMA()
+	m.CircuitOpen = false
+	m.CircuitOpenedSince = 0
+	m.HalfOpen = false
+	m.HalfOpenProbes = 0
+	m.HalfOpenSuccesses = 0
+	m.recordTransition(fromState, now, ReasonMaxOpenElapsed)
+	return []CallbackEvent{m.callbackEvent(now, fromState, ReasonMaxOpenElapsed)}
+}
+
+// recordTransition updates the transition statistics if the state changed
+// from the given one for the given reason. The caller must hold m.Mutex.
+func (m *CircuitImplementation) recordTransition(fromState CircuitState, timestamp int64, reason string) {
+	toState := m.state()
+	if fromState == toState {
+		return
+	}
+	m.LastStateChangedAt = timestamp
+	if fromState == StateClosed {
+		m.openEpisodeAt = timestamp
+	}
+	if toState == StateClosed {
+		m.ClosedSince = timestamp
+		atomic.StoreInt64(&m.rejectedThisEpisode, 0)
+	}
+	if toState == StateOpen {
+		m.TripCount++
+		m.windowOpened = true
+	}
+	m.recordTransitionHistory(fromState, toState, timestamp, reason)
+}
+
+// openedTime returns the time the circuit opened, or the zero time when it is
+// not open. The caller must hold m.Mutex for reading.
+func (m *CircuitImplementation) openedTime() time.Time {
+	if !m.CircuitOpen {
+		return time.Time{}
+	}
+	return timeOf(m.CircuitOpenedSince)
+}
+
+// OpenedAt returns the time the circuit opened, or the zero time when it is
+// not open. It has sub-second precision when the Clock is a PreciseClock, as
+// the default one and FakeClock are, unlike CircuitOpenedSince in Data.
+func (m *CircuitImplementation) OpenedAt() time.Time {
+	if m == nil {
+		return noopCircuit{}.OpenedAt()
+	}
+
+	m.refreshState()
+
+	m.Mutex.RLock()
+	defer m.Mutex.RUnlock()
+
+	return m.openedTime()
+}
+
+// Close stops the interval reset of the circuit and closes the channels
+// returned by Subscribe. Events recorded after Close are ignored, and
+// UpdateStatusE reports them with ErrCircuitShutdown.
+// With AsyncCallbacks it waits for the callback goroutine to return, so it
+// must not be called from a callback.
+func (m *CircuitImplementation) Close() {
+	if m == nil {
+		return
+	}
+
+	m.Mutex.Lock()
+	m.shutdown = true
+	ticker := m.Ticker
+	m.Mutex.Unlock()
+
+	if ticker != nil {
+		ticker.Stop()
+	}
+	m.closeSubscriptions()
+	m.closeOnce.Do(func() {
+		if m.done != nil {
+			close(m.done)
 		}
-	} else if m.Options.ThresholdType == ThresholdPercentage {
-		// if the threshold type is percentage, check if the percentage of failures is greater than the threshold
-		totalRequests := m.FailureCount + m.SuccessCount
-		failurePercentage := (m.FailureCount * 100) / totalRequests
-		if float32(failurePercentage) >= m.Options.Threshold {
-			m.CircuitOpen = true
-			m.CircuitOpenedSince = m.LastCapturedAt
-		} else {
-			m.CircuitOpen = false
-			m.CircuitOpenedSince = 0
+	})
+	if m.callbacks != nil {
+		<-m.stopped
+	}
+}
 
+// closeOnDone closes the circuit once ctx is done, unless it was closed first.
+func (m *CircuitImplementation) closeOnDone(ctx context.Context) {
+	select {
+	case <-ctx.Done():
+		m.Close()
+	case <-m.done:
+	}
+}
+
+// thresholdBreached combines the result of every threshold rule using the configured operator.
+func (m *CircuitImplementation) thresholdBreached() bool {
+	rules := m.Options.thresholdRules()
+	counts := m.thresholdCounts(rules)
+	if m.Options.ThresholdsOperator == OperatorOr {
+		for _, rule := range rules {
+			if m.ruleBreached(rule, counts) {
+				return true
+			}
 		}
-	} else if m.Options.ThresholdType == ThresholdConsecutive {
-		if m.ConsecutiveCounter >= int64(m.Options.Threshold) {
-			m.CircuitOpen = true
-			m.CircuitOpenedSince = m.LastCapturedAt
-		} else {
-			m.CircuitOpen = false
-			m.CircuitOpenedSince = 0
+		return false
+	}
+	for _, rule := range rules {
+		if !m.ruleBreached(rule, counts) {
+			return false
 		}
+	}
+	return true
+}
 
+// thresholdCounts reads the counters the built-in strategies of rules are
+// evaluated on. The EWMA is only computed when a rule uses it. The caller must
+// hold m.Mutex for reading.
+func (m *CircuitImplementation) thresholdCounts(rules []ThresholdRule) thresholdCounts {
+	counts := thresholdCounts{
+		failures:          atomic.LoadInt64(&m.FailureCount),
+		consecutive:       atomic.LoadInt64(&m.ConsecutiveCounter),
+		weightedSuccesses: weightedCount(atomic.LoadInt64(&m.weightedSuccesses)),
+		weightedFailures:  weightedCount(atomic.LoadInt64(&m.weightedFailures)),
+		open:              m.CircuitOpen,
 	}
-	if currentStateOfCircuit != m.CircuitOpen && m.CircuitOpen {
+	for _, rule := range rules {
+		if rule.ThresholdType == ThresholdEWMA {
+			counts.ewmaFailureRate = m.ewmaFailureRate()
+			break
+		}
+	}
+	return counts
+}
 
-		if m.Options.OnCircuitOpen != nil {
+// ruleBreached reports whether the counts reach the threshold of a single
+// rule, or its CloseThreshold while the circuit is open, using the strategy of
+// its type. The COUNT, PERCENTAGE and EWMA thresholds are only evaluated once
+// MinimumCount events were recorded, while the other types can trip from the
+// first events. Only a custom strategy is passed the full CircuitData.
+func (m *CircuitImplementation) ruleBreached(rule ThresholdRule, counts thresholdCounts) bool {
+	if gatedByMinimumCount(rule.ThresholdType) && !m.minimumCountReached() {
+		return false
+	}
+	if builtin, ok := builtinStrategies[rule.ThresholdType]; ok {
+		return builtin(counts, rule, &m.Options)
+	}
+	strategy := m.Options.ThresholdStrategies[rule.ThresholdType]
+	if strategy == nil {
+		return false
+	}
+	options := m.Options
+	options.ThresholdType = rule.ThresholdType
+	options.Threshold = rule.Threshold
+	options.CloseThreshold = rule.CloseThreshold
+	return strategy.ShouldOpen(m.data(), options)
+}
 
-			m.Options.OnCircuitOpen(CallbackEvent{
-				Timestamp:    m.LastCapturedAt,
-				SuccessCount: m.SuccessCount,
-				FailureCount: m.FailureCount,
-			})
+// holdsOpen reports whether an open consecutive circuit still needs more consecutive successes to close.
+func (m *CircuitImplementation) holdsOpen() bool {
+	for _, rule := range m.Options.thresholdRules() {
+		if rule.ThresholdType == ThresholdConsecutive {
+			return atomic.LoadInt64(&m.ConsecutiveSuccessCounter) < m.Options.CloseConsecutiveCount
 		}
+	}
+	return false
+}
 
-	} else if currentStateOfCircuit != m.CircuitOpen && !m.CircuitOpen {
-		if m.Options.OnCircuitClosed != nil {
-			m.Options.OnCircuitClosed(CallbackEvent{
-				Timestamp:    m.LastCapturedAt,
-				SuccessCount: m.SuccessCount,
-				FailureCount: m.FailureCount,
-			})
-		}
+// IsCircuitOpen returns false only when the circuit is closed. A half-open
+// circuit is still reported as open, as it only admits a limited number of
+// probe calls through AllowRequest or Execute. A circuit whose
+// OpenDurationInSeconds has elapsed is closed first. A circuit disabled with
+// SetEnabled is never reported as open.
+func (m *CircuitImplementation) IsCircuitOpen() bool {
+	if m == nil {
+		return noopCircuit{}.IsCircuitOpen()
+	}
+
+	m.refreshState()
+
+	m.Mutex.RLock()
+	defer m.Mutex.RUnlock()
+
+	return (m.CircuitOpen || m.HalfOpen) && !m.Disabled
+}
 
+// SetEnabled turns breaking on or off without resetting the circuit. While it
+// is disabled, IsCircuitOpen returns false and every call is allowed, but the
+// counts are still recorded and the state is still evaluated, so State, Data
+// and the callbacks keep reporting what the circuit would do. Enabling it
+// again applies the state reached meanwhile.
+func (m *CircuitImplementation) SetEnabled(enabled bool) {
+	if m == nil {
+		return
 	}
 
+	m.Mutex.Lock()
+	defer m.Mutex.Unlock()
+
+	m.Disabled = !enabled
 }
 
-// IsCircuitOpen returns true if the circuit is open, false otherwise.
-func (m *CircuitImplementation) IsCircuitOpen() bool {
-	return m.CircuitOpen
+// State returns the current state of the circuit. A circuit whose
+// OpenDurationInSeconds has elapsed is closed first.
+func (m *CircuitImplementation) State() CircuitState {
+	if m == nil {
+		return noopCircuit{}.State()
+	}
+
+	m.refreshState()
+
+	m.Mutex.RLock()
+	defer m.Mutex.RUnlock()
+
+	return m.state()
+}
+
+// TimeUntilReset returns how long until the next interval reset clears the
+// counts, as measured by the Clock of the circuit. It returns 0 with
+// ManualReset, as the reset happens whenever ResetWindow is called.
+func (m *CircuitImplementation) TimeUntilReset() time.Duration {
+	if m == nil {
+		return noopCircuit{}.TimeUntilReset()
+	}
+
+	m.Mutex.RLock()
+	defer m.Mutex.RUnlock()
+
+	if m.Options.ManualReset {
+		return 0
+	}
+	now, interval := m.now(), seconds(m.Options.IntervalInSeconds)
+	deadline := m.WindowStartedAt + interval
+	if elapsed := now - m.WindowStartedAt; m.Ticker == nil && elapsed > 0 {
+		// the intervals that passed before startTicker are skipped, as it does
+		deadline += elapsed / interval * interval
+	}
+	return remaining(deadline, now)
+}
+
+// TimeUntilHalfOpen returns how long until the open duration of an open
+// circuit elapses, after which it becomes half-open with HalfOpenMaxProbes or
+// closes otherwise. It returns 0 when the circuit is not open or has no open
+// duration, in which case it closes with the interval reset, see
+// TimeUntilReset, or with StickyOpen once recovery is recorded.
+func (m *CircuitImplementation) TimeUntilHalfOpen() time.Duration {
+	if m == nil {
+		return noopCircuit{}.TimeUntilHalfOpen()
+	}
+
+	m.refreshState()
+
+	m.Mutex.RLock()
+	defer m.Mutex.RUnlock()
+
+	if !m.holdsOpenForDuration() {
+		return 0
+	}
+	return remaining(m.CircuitOpenedSince+m.currentOpenDuration(), m.now())
+}
+
+// remaining returns the time from now until the deadline, or 0 if it passed.
+func remaining(deadline, now int64) time.Duration {
+	if deadline <= now {
+		return 0
+	}
+	return time.Duration(deadline - now)
+}
+
+// state returns the current state of the circuit. The caller must hold m.Mutex for reading.
+func (m *CircuitImplementation) state() CircuitState {
+	if m.HalfOpen {
+		return StateHalfOpen
+	}
+	if m.CircuitOpen {
+		return StateOpen
+	}
+	return StateClosed
+}
+
+// now returns the current timestamp of the circuit's clock in Unix
+// nanoseconds, with sub-second precision when it is a PreciseClock.
+func (m *CircuitImplementation) now() int64 {
+	if clock, ok := m.Options.Clock.(PreciseClock); ok {
+		return clock.NowTime().UnixNano()
+	}
+	return m.Options.Clock.Now() * int64(time.Second)
 }
 
-// getTimestamp returns the current timestamp in Unix format.
-func getTimestamp() int64 {
-	currentTime := time.Now()
-	return currentTime.Unix()
+// nowTime returns the current time of the circuit's clock, for measuring the
+// latency of a call. Unlike now it takes m.Mutex itself, and the times of the
+// default clock keep their monotonic reading.
+func (m *CircuitImplementation) nowTime() time.Time {
+	m.Mutex.RLock()
+	clock := m.Options.Clock
+	m.Mutex.RUnlock()
+	if precise, ok := clock.(PreciseClock); ok {
+		return precise.NowTime()
+	}
+	return time.Unix(clock.Now(), 0)
+}
+
+// maxOpenDuration bounds the open duration picked by startOpenDuration, so
+// that a large BackoffMultiplier cannot overflow the timestamps.
+const maxOpenDuration = 100 * 365 * 24 * time.Hour
+
+// seconds converts a number of seconds, e.g. from the options, to nanoseconds
+// as used by the timestamps of a circuit.
+func seconds(n int) int64 {
+	return int64(n) * int64(time.Second)
+}
+
+// unixSeconds converts a timestamp in Unix nanoseconds to the Unix seconds
+// reported in CircuitData, CallbackEvent and the persisted state.
+func unixSeconds(timestamp int64) int64 {
+	return timestamp / int64(time.Second)
+}
+
+// fromUnixSeconds converts a timestamp in Unix seconds to Unix nanoseconds.
+func fromUnixSeconds(timestamp int64) int64 {
+	return timestamp * int64(time.Second)
+}
+
+// timeOf returns the time of a timestamp in Unix nanoseconds, or the zero time
+// for a timestamp that was never set.
+func timeOf(timestamp int64) time.Time {
+	if timestamp == 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, timestamp)
 }
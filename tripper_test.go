@@ -2,6 +2,7 @@ package tripper
 
 import (
 	"math/rand"
+	"runtime"
 	"sync"
 	"testing"
 	"time"
@@ -57,9 +58,13 @@ func TestAddMonitor(t *testing.T) {
 	monitorOptions.IntervalInSeconds = 2
 	_, err = ConfigureCircuit(monitorOptions)
 	assert.Error(t, err)
-	assert.EqualError(t, err, "invalid interval 2")
+	assert.EqualError(t, err, "invalid interval 2, should be at least 60")
 	// Test case 8: Add a monitor with an interval that is not a multiple of 60
 	// Expected output: An error
+	monitorOptions.IntervalInSeconds = 90
+	_, err = ConfigureCircuit(monitorOptions)
+	assert.Error(t, err)
+	assert.EqualError(t, err, "invalid interval 90, should be a multiple of 60")
 
 	// Test case 9: Add a monitor with a valid threshold type and threshold value
 	// Expected output: No error
@@ -175,46 +180,219 @@ func TestUpdateStatus(t *testing.T) {
 	assert.True(t, callBackCalledClosed)
 }
 
-// func TestInterval(t *testing.T) {
-// 	callBackCalledClosed := false
-// 	monitorOptionsX := CircuitOptions{
-// 		Name:              generateRandomString(10),
-// 		Threshold:         2,
-// 		MinimumCount:      4,
-// 		IntervalInSeconds: 5,
-// 		ThresholdType:     ThresholdCount,
-// 		OnCircuitClosed: func(x CallbackEvent) {
-// 			callBackCalledClosed = true
-// 		},
-// 	}
-// 	mx, err := ConfigureCircuit(monitorOptionsX)
-// 	assert.NoError(t, err)
+func TestInterval(t *testing.T) {
+	callBackCalledClosed := false
+	clock := NewFakeClock(time.Unix(1700000000, 0))
+	monitorOptionsX := CircuitOptions{
+		Name:              generateRandomString(10),
+		Threshold:         2,
+		MinimumCount:      4,
+		IntervalInSeconds: 60,
+		ThresholdType:     ThresholdCount,
+		OnCircuitClosed: func(x CallbackEvent) {
+			callBackCalledClosed = true
+		},
+		Clock: clock,
+	}
+	mx, err := ConfigureCircuit(monitorOptionsX)
+	assert.NoError(t, err)
 
-// 	// Simulate failures and a success
-// 	mx.UpdateStatus(false)
-// 	mx.UpdateStatus(false)
-// 	mx.UpdateStatus(true)
-// 	mx.UpdateStatus(false)
-
-// 	// Assert initial state
-// 	assert.Equal(t, int64(1), mx.Data().SuccessCount)
-// 	assert.Equal(t, int64(3), mx.Data().FailureCount)
-// 	assert.True(t, mx.Data().IsCircuitOpen)
-// 	assert.NotZero(t, mx.Data().CircuitOpenedSince)
-
-// 	futureTime := time.Now().Unix() + int64(monitorOptionsX.IntervalInSeconds+1)
-// 	for time.Now().Unix() < futureTime {
-// 		// Simulate internal logic of the monitor on interval tick
-// 		//mx.UpdateStatus(true) // Simulate any internal housekeeping
-// 	}
+	// Simulate failures and a success
+	mx.UpdateStatus(false)
+	mx.UpdateStatus(false)
+	mx.UpdateStatus(true)
+	mx.UpdateStatus(false)
+
+	// Assert initial state
+	assert.Equal(t, int64(1), mx.Data().SuccessCount)
+	assert.Equal(t, int64(3), mx.Data().FailureCount)
+	assert.True(t, mx.Data().IsCircuitOpen)
+	assert.Equal(t, int64(1700000000), mx.Data().CircuitOpenedSince)
+
+	// Just before the interval elapses nothing is reset
+	clock.Advance(59 * time.Second)
+	assert.True(t, mx.Data().IsCircuitOpen)
+	assert.False(t, callBackCalledClosed)
+
+	clock.Advance(time.Second)
+
+	// Assert circuit closed state
+	assert.Equal(t, int64(0), mx.Data().SuccessCount)
+	assert.Equal(t, int64(0), mx.Data().FailureCount)
+	assert.False(t, mx.Data().IsCircuitOpen)
+	assert.True(t, callBackCalledClosed)
+	assert.Zero(t, mx.Data().CircuitOpenedSince)
+}
+
+func TestManualReset(t *testing.T) {
+	// Test case 1: Configure many circuits with ManualReset and the system clock
+	// Expected output: No ticker goroutine is started
+	before := runtime.NumGoroutine()
+	var circuits []Circuit
+	for i := 0; i < 100; i++ {
+		m, err := ConfigureCircuit(CircuitOptions{
+			Name:              generateRandomString(10),
+			Threshold:         2,
+			ThresholdType:     ThresholdConsecutive,
+			IntervalInSeconds: 60,
+			ManualReset:       true,
+		})
+		assert.NoError(t, err)
+		circuits = append(circuits, m)
+	}
+	assert.True(t, runtime.NumGoroutine() <= before)
+	for _, m := range circuits {
+		m.Close()
+	}
+
+	// Test case 2: Let the interval elapse on a fake clock
+	// Expected output: No ticker is registered and the counts are kept
+	clock := NewFakeClock(time.Unix(1700000000, 0))
+	m, err := ConfigureCircuit(CircuitOptions{
+		Name:              "TEST_ManualReset",
+		Threshold:         2,
+		ThresholdType:     ThresholdCount,
+		MinimumCount:      3,
+		IntervalInSeconds: 60,
+		ManualReset:       true,
+		Clock:             clock,
+	})
+	assert.NoError(t, err)
+	defer m.Close()
+	assert.Equal(t, 0, clock.ActiveTickers())
+	m.UpdateStatus(true)
+	m.UpdateStatus(false)
+	m.UpdateStatus(false)
+	assert.True(t, m.IsCircuitOpen())
+	clock.Advance(10 * time.Minute)
+	assert.True(t, m.IsCircuitOpen())
+	assert.Equal(t, int64(3), m.Data().TotalCount)
+	assert.Zero(t, m.TimeUntilReset())
+
+	// Test case 3: Reset the window manually
+	// Expected output: The counts are cleared, the circuit closes and the interval is recorded
+	m.ResetWindow()
+	assert.False(t, m.IsCircuitOpen())
+	assert.Equal(t, int64(0), m.Data().TotalCount)
+	assert.Len(t, m.History(), 1)
+
+	// Test case 4: Reset the window after Close
+	// Expected output: Nothing changes
+	m.UpdateStatus(false)
+	m.Close()
+	m.ResetWindow()
+	assert.Equal(t, int64(1), m.Data().FailureCount)
+}
+
+func TestLazyTicker(t *testing.T) {
+	// Test case 1: Configure many circuits with the system clock without using them
+	// Expected output: No ticker goroutine is started until the first UpdateStatus
+	before := runtime.NumGoroutine()
+	var circuits []Circuit
+	for i := 0; i < 100; i++ {
+		m, err := ConfigureCircuit(CircuitOptions{
+			Name:              generateRandomString(10),
+			Threshold:         2,
+			ThresholdType:     ThresholdConsecutive,
+			IntervalInSeconds: 60,
+		})
+		assert.NoError(t, err)
+		circuits = append(circuits, m)
+	}
+	assert.True(t, runtime.NumGoroutine() <= before)
+	for _, m := range circuits {
+		m.Close()
+	}
+
+	// Test case 2: Use a circuit for the first time after some intervals passed
+	// Expected output: The ticker is started and the intervals stay aligned with the configuration time
+	clock := NewFakeClock(time.Unix(1700000000, 0))
+	m, err := ConfigureCircuit(CircuitOptions{
+		Name:              "TEST_LazyTicker",
+		Threshold:         2,
+		ThresholdType:     ThresholdCount,
+		MinimumCount:      3,
+		IntervalInSeconds: 60,
+		Clock:             clock,
+	})
+	assert.NoError(t, err)
+	defer m.Close()
+	assert.Equal(t, 0, clock.ActiveTickers())
+	clock.Advance(130 * time.Second)
+	assert.True(t, m.AllowRequest())
+	assert.Equal(t, 1, clock.ActiveTickers())
+	assert.Equal(t, 50*time.Second, m.TimeUntilReset())
+	m.UpdateStatus(false)
+	clock.Advance(50 * time.Second)
+	assert.Equal(t, int64(0), m.Data().FailureCount)
+	assert.Equal(t, 60*time.Second, m.TimeUntilReset())
+
+	// Test case 3: Close a circuit that was never used
+	// Expected output: No ticker is started by a later update
+	unused, err := ConfigureCircuit(CircuitOptions{
+		Name:              "TEST_LazyTicker_Unused",
+		Threshold:         2,
+		ThresholdType:     ThresholdConsecutive,
+		IntervalInSeconds: 60,
+		Clock:             clock,
+	})
+	assert.NoError(t, err)
+	unused.Close()
+	unused.UpdateStatus(false)
+	assert.Equal(t, 1, clock.ActiveTickers())
+}
+
+func TestReset(t *testing.T) {
+	clock := NewFakeClock(time.Unix(1700000000, 0))
+	var transitions []CircuitState
+	m, err := ConfigureCircuit(CircuitOptions{
+		Name:                  "TEST_Reset",
+		Threshold:             2,
+		ThresholdType:         ThresholdConsecutive,
+		IntervalInSeconds:     60,
+		OpenDurationInSeconds: 30,
+		HalfOpenMaxProbes:     1,
+		BackoffMultiplier:     2,
+		OnStateChange: func(from, to CircuitState, event CallbackEvent) {
+			transitions = append(transitions, to)
+		},
+		Clock: clock,
+	})
+	assert.NoError(t, err)
+	defer m.Close()
+
+	// Test case 1: Reset a circuit held open with backoff
+	// Expected output: The circuit closes right away with empty counts and OnStateChange is invoked
+	m.UpdateStatus(false)
+	m.UpdateStatus(false)
+	assert.True(t, m.IsCircuitOpen())
+	m.Reset()
+	data := m.Data()
+	assert.Equal(t, StateClosed, data.State)
+	assert.Equal(t, int64(0), data.FailureCount)
+	assert.Equal(t, int64(0), data.ConsecutiveCounter)
+	assert.Equal(t, int64(0), data.BackoffLevel)
+	assert.Equal(t, int64(1), data.TripCount)
+	assert.Equal(t, []CircuitState{StateOpen, StateClosed}, transitions)
+
+	// Test case 2: Reset a half-open circuit
+	// Expected output: The circuit closes
+	m.UpdateStatus(false)
+	m.UpdateStatus(false)
+	clock.Advance(30 * time.Second)
+	assert.Equal(t, StateHalfOpen, m.State())
+	m.Reset()
+	assert.Equal(t, StateClosed, m.State())
+
+	// Test case 3: Reset a closed circuit
+	// Expected output: The counts are cleared without a state change
+	m.UpdateStatus(false)
+	transitions = nil
+	m.Reset()
+	assert.Equal(t, int64(0), m.Data().FailureCount)
+	assert.Empty(t, transitions)
+}
 
-//		// Assert circuit closed state
-//		assert.Equal(t, int64(0), mx.Data().SuccessCount)
-//		assert.Equal(t, int64(0), mx.Data().FailureCount)
-//		assert.False(t, mx.Data().IsCircuitOpen)
-//		assert.True(t, callBackCalledClosed)
-//		assert.Zero(t, mx.Data().CircuitOpenedSince)
-//	}
 func generateRandomString(n int) string {
 	var letters = []rune("abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ")
 	b := make([]rune, n)
@@ -291,32 +469,6 @@ func TestUpdateStatusRaceTwo(t *testing.T) {
 
 }
 
-// func TestGetMonitor(t *testing.T) {
-// 	tripperOpts := TripperOptions{}
-// 	tripper := Configure(tripperOpts)
-
-// 	// Test case 1: Get an existing monitor
-// 	// Expected output: Monitor and no error
-// 	monitorOptions := CircuitOptions{
-// 		Name:              "test",
-// 		Threshold:         65,
-// 		MinimumCount:      20,
-// 		IntervalInSeconds: 120,
-// 		ThresholdType:     ThresholdPercentage,
-// 	}
-// 	_, err := tripper.AddMonitor(monitorOptions)
-// 	assert.NoError(t, err)
-
-// 	m, err := tripper.GetMonitor("test")
-// 	assert.NoError(t, err)
-// 	assert.NotNil(t, m)
-
-// 	// Test case 2: Get a non-existing monitor
-// 	// Expected output: Error
-// 	_, err = tripper.GetMonitor("non-existing")
-// 	assert.Error(t, err)
-// 	assert.EqualError(t, err, "Monitor with name non-existing does not exist")
-// }
 // func TestIsCircuitOpen(t *testing.T) {
 // 	// Test case 1: Circuit is closed
 // 	// Expected output: false
@@ -393,3 +545,1934 @@ func TestUpdateStatusConsecutive(t *testing.T) {
 	assert.True(t, m.IsCircuitOpen())
 
 }
+
+func TestCloseConsecutiveCount(t *testing.T) {
+	monitorOptions := CircuitOptions{
+		Name:                  "TEST_CloseConsecutiveCount",
+		Threshold:             3,
+		MinimumCount:          1,
+		IntervalInSeconds:     120,
+		ThresholdType:         ThresholdConsecutive,
+		CloseConsecutiveCount: 3,
+	}
+	m, err := ConfigureCircuit(monitorOptions)
+	assert.NoError(t, err)
+
+	// Test case 1: Circuit opens after the consecutive failure threshold
+	// Expected output: Circuit open
+	m.UpdateStatus(false)
+	m.UpdateStatus(false)
+	m.UpdateStatus(false)
+	assert.True(t, m.IsCircuitOpen())
+
+	// Test case 2: A single success while open does not close the circuit
+	// Expected output: Circuit still open
+	m.UpdateStatus(true)
+	assert.True(t, m.IsCircuitOpen())
+	m.UpdateStatus(true)
+	assert.True(t, m.IsCircuitOpen())
+
+	// Test case 3: A failure breaks the success streak
+	// Expected output: Circuit still open after two more successes
+	m.UpdateStatus(false)
+	m.UpdateStatus(true)
+	m.UpdateStatus(true)
+	assert.True(t, m.IsCircuitOpen())
+
+	// Test case 4: The configured number of consecutive successes closes the circuit
+	// Expected output: Circuit closed
+	m.UpdateStatus(true)
+	assert.False(t, m.IsCircuitOpen())
+
+	// Test case 5: A negative close count is rejected
+	// Expected output: An error
+	monitorOptions.CloseConsecutiveCount = -1
+	_, err = ConfigureCircuit(monitorOptions)
+	assert.EqualError(t, err, "invalid close consecutive count -1")
+}
+
+func TestThresholdRulesAnd(t *testing.T) {
+	// Trip only if the failure rate is at least 50% AND there were at least 5 failures
+	monitorOptions := CircuitOptions{
+		Name:              "TEST_ThresholdRulesAnd",
+		MinimumCount:      6,
+		IntervalInSeconds: 120,
+		Thresholds: []ThresholdRule{
+			{ThresholdType: ThresholdPercentage, Threshold: 50},
+			{ThresholdType: ThresholdCount, Threshold: 5},
+		},
+		ThresholdsOperator: OperatorAnd,
+	}
+	m, err := ConfigureCircuit(monitorOptions)
+	assert.NoError(t, err)
+
+	// Test case 1: Failure rate reached but not enough failures
+	// Expected output: Circuit closed
+	for i := 0; i < 3; i++ {
+		m.UpdateStatus(true)
+		m.UpdateStatus(false)
+	}
+	assert.Equal(t, int64(3), m.Data().FailureCount)
+	assert.False(t, m.IsCircuitOpen())
+
+	// Test case 2: Enough failures and failure rate reached
+	// Expected output: Circuit open
+	m.UpdateStatus(false)
+	m.UpdateStatus(false)
+	assert.True(t, m.IsCircuitOpen())
+
+	// Test case 3: Enough failures but the failure rate drops below the threshold
+	// Expected output: Circuit closed
+	m.UpdateStatus(true)
+	m.UpdateStatus(true)
+	m.UpdateStatus(true)
+	assert.False(t, m.IsCircuitOpen())
+}
+
+func TestThresholdRulesOr(t *testing.T) {
+	// Trip if there are 3 consecutive failures OR 5 failures in total
+	monitorOptions := CircuitOptions{
+		Name:              "TEST_ThresholdRulesOr",
+		MinimumCount:      6,
+		IntervalInSeconds: 120,
+		Thresholds: []ThresholdRule{
+			{ThresholdType: ThresholdConsecutive, Threshold: 3},
+			{ThresholdType: ThresholdCount, Threshold: 5},
+		},
+		ThresholdsOperator: OperatorOr,
+	}
+	m, err := ConfigureCircuit(monitorOptions)
+	assert.NoError(t, err)
+
+	// Test case 1: Neither rule reached
+	// Expected output: Circuit closed
+	for i := 0; i < 4; i++ {
+		m.UpdateStatus(true)
+		m.UpdateStatus(false)
+	}
+	assert.False(t, m.IsCircuitOpen())
+
+	// Test case 2: The count rule is reached without consecutive failures
+	// Expected output: Circuit open
+	m.UpdateStatus(true)
+	m.UpdateStatus(false)
+	assert.Equal(t, int64(5), m.Data().FailureCount)
+	assert.True(t, m.IsCircuitOpen())
+
+	// Test case 3: Only the consecutive rule is reached
+	// Expected output: Circuit open
+	m, err = ConfigureCircuit(monitorOptions)
+	assert.NoError(t, err)
+	m.UpdateStatus(true)
+	m.UpdateStatus(true)
+	m.UpdateStatus(true)
+	m.UpdateStatus(false)
+	m.UpdateStatus(false)
+	assert.False(t, m.IsCircuitOpen())
+	m.UpdateStatus(false)
+	assert.True(t, m.IsCircuitOpen())
+}
+
+func TestThresholdRulesValidation(t *testing.T) {
+	monitorOptions := CircuitOptions{
+		Name:              "TEST_ThresholdRulesValidation",
+		MinimumCount:      10,
+		IntervalInSeconds: 120,
+		Thresholds: []ThresholdRule{
+			{ThresholdType: ThresholdPercentage, Threshold: 150},
+		},
+	}
+
+	// Test case 1: An invalid rule value is rejected
+	// Expected output: An error
+	_, err := ConfigureCircuit(monitorOptions)
+	assert.EqualError(t, err, "invalid threshold value 150.000000 for percentage type")
+
+	// Test case 2: An invalid rule type is rejected
+	// Expected output: An error
+	monitorOptions.Thresholds = []ThresholdRule{{ThresholdType: "invalid", Threshold: 1}}
+	_, err = ConfigureCircuit(monitorOptions)
+	assert.EqualError(t, err, "invalid threshold type invalid")
+
+	// Test case 3: A count rule still requires the minimum count to exceed it
+	// Expected output: An error
+	monitorOptions.Thresholds = []ThresholdRule{{ThresholdType: ThresholdCount, Threshold: 10}}
+	_, err = ConfigureCircuit(monitorOptions)
+	assert.EqualError(t, err, "minimum count should be greater than threshold")
+
+	// Test case 4: An unknown operator is rejected
+	// Expected output: An error
+	monitorOptions.Thresholds = []ThresholdRule{{ThresholdType: ThresholdCount, Threshold: 5}}
+	monitorOptions.ThresholdsOperator = "XOR"
+	_, err = ConfigureCircuit(monitorOptions)
+	assert.EqualError(t, err, "invalid thresholds operator XOR")
+}
+
+func TestCallbackEventNameAndStates(t *testing.T) {
+	clock := NewFakeClock(time.Unix(1700000000, 0))
+	var opened, closed []CallbackEvent
+	onOpen := func(x CallbackEvent) {
+		opened = append(opened, x)
+	}
+	onClosed := func(x CallbackEvent) {
+		closed = append(closed, x)
+	}
+	newCircuit := func(name string) Circuit {
+		m, err := ConfigureCircuit(CircuitOptions{
+			Name:              name,
+			Threshold:         2,
+			MinimumCount:      3,
+			IntervalInSeconds: 60,
+			ThresholdType:     ThresholdCount,
+			OnCircuitOpen:     onOpen,
+			OnCircuitClosed:   onClosed,
+			Clock:             clock,
+		})
+		assert.NoError(t, err)
+		return m
+	}
+	payments := newCircuit("payments")
+	search := newCircuit("search")
+
+	// Test case 1: A shared open callback can tell which circuit tripped
+	// Expected output: Event carries the circuit name and the closed to open transition
+	payments.UpdateStatus(false)
+	payments.UpdateStatus(false)
+	payments.UpdateStatus(false)
+	search.UpdateStatus(true)
+	assert.Equal(t, 1, len(opened))
+	assert.Equal(t, "payments", opened[0].Name)
+	assert.Equal(t, StateClosed, opened[0].FromState)
+	assert.Equal(t, StateOpen, opened[0].ToState)
+	assert.Equal(t, int64(3), opened[0].FailureCount)
+	assert.Equal(t, ReasonTripped, opened[0].Reason)
+
+	// Test case 2: The interval reset closes the open circuit
+	// Expected output: Open to closed for payments, no callback for search that was already closed
+	clock.Advance(time.Minute)
+	assert.Equal(t, 1, len(closed))
+	assert.Equal(t, "payments", closed[0].Name)
+	assert.Equal(t, StateOpen, closed[0].FromState)
+	assert.Equal(t, StateClosed, closed[0].ToState)
+	assert.Equal(t, ReasonIntervalReset, closed[0].Reason)
+
+	// Test case 3: More intervals pass with both circuits closed
+	// Expected output: OnCircuitClosed is not called again
+	clock.Advance(3 * time.Minute)
+	assert.Equal(t, 1, len(closed))
+}
+
+func TestOnStateChange(t *testing.T) {
+	clock := NewFakeClock(time.Unix(1700000000, 0))
+	var transitions [][2]CircuitState
+	var order []string
+	monitorOptions := CircuitOptions{
+		Name:              "TEST_OnStateChange",
+		Threshold:         50,
+		MinimumCount:      2,
+		IntervalInSeconds: 60,
+		ThresholdType:     ThresholdPercentage,
+		OnStateChange: func(from, to CircuitState, x CallbackEvent) {
+			transitions = append(transitions, [2]CircuitState{from, to})
+			order = append(order, "change")
+			assert.Equal(t, "TEST_OnStateChange", x.Name)
+			assert.Equal(t, from, x.FromState)
+			assert.Equal(t, to, x.ToState)
+		},
+		OnCircuitOpen: func(x CallbackEvent) {
+			order = append(order, "open")
+		},
+		OnCircuitClosed: func(x CallbackEvent) {
+			order = append(order, "closed")
+		},
+		Clock: clock,
+	}
+	m, err := ConfigureCircuit(monitorOptions)
+	assert.NoError(t, err)
+
+	// Test case 1: Open and recover through successes
+	// Expected output: Closed to open followed by open to closed
+	m.UpdateStatus(false)
+	m.UpdateStatus(false)
+	m.UpdateStatus(true)
+	m.UpdateStatus(true)
+	m.UpdateStatus(true)
+	assert.Equal(t, [][2]CircuitState{{StateClosed, StateOpen}, {StateOpen, StateClosed}}, transitions)
+	assert.Equal(t, []string{"change", "open", "change", "closed"}, order)
+
+	// Test case 2: The interval reset of a closed circuit is not a state change
+	// Expected output: No new transition, OnCircuitClosed still fires
+	clock.Advance(time.Minute)
+	assert.Equal(t, 2, len(transitions))
+	assert.Equal(t, "closed", order[len(order)-1])
+
+	// Test case 3: The interval reset of an open circuit is a state change
+	// Expected output: Closed to open followed by open to closed
+	m.UpdateStatus(false)
+	m.UpdateStatus(false)
+	clock.Advance(time.Minute)
+	assert.Equal(t, [][2]CircuitState{{StateClosed, StateOpen}, {StateOpen, StateClosed}}, transitions[2:])
+}
+
+func TestDataDerivedValues(t *testing.T) {
+	monitorOptions := CircuitOptions{
+		Name:              "TEST_DataDerivedValues",
+		Threshold:         90,
+		MinimumCount:      10,
+		IntervalInSeconds: 60,
+		ThresholdType:     ThresholdPercentage,
+	}
+	m, err := ConfigureCircuit(monitorOptions)
+	assert.NoError(t, err)
+
+	// Test case 1: Nothing recorded yet
+	// Expected output: Zero totals and a zero failure rate
+	assert.Equal(t, int64(0), m.Data().TotalCount)
+	assert.Equal(t, 0.0, m.Data().FailureRate)
+
+	// Test case 2: Mixed outcomes
+	// Expected output: Totals, rate and the current failure streak
+	m.UpdateStatus(true)
+	m.UpdateStatus(false)
+	m.UpdateStatus(false)
+	m.UpdateStatus(true)
+	m.UpdateStatus(false)
+	m.UpdateStatus(false)
+	m.UpdateStatus(false)
+	m.UpdateStatus(true)
+	data := m.Data()
+	assert.Equal(t, int64(8), data.TotalCount)
+	assert.Equal(t, 0.625, data.FailureRate)
+	assert.Equal(t, int64(0), data.ConsecutiveCounter)
+
+	m.UpdateStatus(false)
+	m.UpdateStatus(false)
+	data = m.Data()
+	assert.Equal(t, int64(10), data.TotalCount)
+	assert.Equal(t, 0.7, data.FailureRate)
+	assert.Equal(t, int64(2), data.ConsecutiveCounter)
+}
+
+func TestTripCount(t *testing.T) {
+	clock := NewFakeClock(time.Unix(1700000000, 0))
+	monitorOptions := CircuitOptions{
+		Name:              "TEST_TripCount",
+		Threshold:         3,
+		MinimumCount:      1,
+		IntervalInSeconds: 60,
+		ThresholdType:     ThresholdConsecutive,
+		Clock:             clock,
+	}
+	m, err := ConfigureCircuit(monitorOptions)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0), m.Data().TripCount)
+	assert.Zero(t, m.Data().LastStateChangedAt)
+
+	// Test case 1: Open and close through updates several times
+	// Expected output: One trip per opening, staying open does not count again
+	for i := 1; i <= 3; i++ {
+		clock.Advance(time.Second)
+		m.UpdateStatus(false)
+		m.UpdateStatus(false)
+		m.UpdateStatus(false)
+		m.UpdateStatus(false)
+		assert.True(t, m.IsCircuitOpen())
+		assert.Equal(t, int64(i), m.Data().TripCount)
+		assert.Equal(t, clock.Now(), m.Data().LastStateChangedAt)
+
+		clock.Advance(time.Second)
+		m.UpdateStatus(true)
+		assert.False(t, m.IsCircuitOpen())
+		assert.Equal(t, int64(i), m.Data().TripCount)
+		assert.Equal(t, clock.Now(), m.Data().LastStateChangedAt)
+	}
+
+	// Test case 2: The interval reset of an open circuit is a state change but not a trip
+	// Expected output: Same trip count, state change recorded at the reset
+	m.UpdateStatus(false)
+	m.UpdateStatus(false)
+	m.UpdateStatus(false)
+	assert.Equal(t, int64(4), m.Data().TripCount)
+	clock.Advance(time.Minute)
+	assert.False(t, m.IsCircuitOpen())
+	assert.Equal(t, int64(4), m.Data().TripCount)
+	assert.Equal(t, int64(1700000060), m.Data().LastStateChangedAt)
+
+	// Test case 3: The interval reset of a closed circuit is not a state change
+	// Expected output: Last state change unchanged
+	clock.Advance(time.Minute)
+	assert.Equal(t, int64(1700000060), m.Data().LastStateChangedAt)
+}
+
+func TestOpenDurationShorterThanInterval(t *testing.T) {
+	clock := NewFakeClock(time.Unix(1700000000, 0))
+	var transitions [][2]CircuitState
+	monitorOptions := CircuitOptions{
+		Name:                  "TEST_OpenDurationShort",
+		Threshold:             50,
+		MinimumCount:          2,
+		IntervalInSeconds:     120,
+		OpenDurationInSeconds: 30,
+		ThresholdType:         ThresholdPercentage,
+		OnStateChange: func(from, to CircuitState, x CallbackEvent) {
+			transitions = append(transitions, [2]CircuitState{from, to})
+		},
+		Clock: clock,
+	}
+	m, err := ConfigureCircuit(monitorOptions)
+	assert.NoError(t, err)
+
+	m.UpdateStatus(false)
+	m.UpdateStatus(false)
+	assert.True(t, m.IsCircuitOpen())
+
+	// Test case 1: Successes do not close the circuit before the open duration elapses
+	// Expected output: Circuit still open with its original open time
+	clock.Advance(29 * time.Second)
+	for i := 0; i < 10; i++ {
+		m.UpdateStatus(true)
+	}
+	assert.True(t, m.IsCircuitOpen())
+	assert.Equal(t, int64(1700000000), m.Data().CircuitOpenedSince)
+
+	// Test case 2: The first update after the open duration closes the circuit and starts a fresh window
+	// Expected output: Circuit closed with only the new update counted
+	clock.Advance(time.Second)
+	m.UpdateStatus(true)
+	assert.False(t, m.IsCircuitOpen())
+	assert.Equal(t, int64(1), m.Data().SuccessCount)
+	assert.Equal(t, int64(0), m.Data().FailureCount)
+	assert.Equal(t, [][2]CircuitState{{StateClosed, StateOpen}, {StateOpen, StateClosed}}, transitions)
+
+	// Test case 3: The circuit can trip again within the same interval
+	// Expected output: Circuit open
+	m.UpdateStatus(false)
+	m.UpdateStatus(false)
+	assert.True(t, m.IsCircuitOpen())
+	assert.Equal(t, int64(2), m.Data().TripCount)
+}
+
+func TestOpenDurationLongerThanInterval(t *testing.T) {
+	clock := NewFakeClock(time.Unix(1700000000, 0))
+	monitorOptions := CircuitOptions{
+		Name:                  "TEST_OpenDurationLong",
+		Threshold:             50,
+		MinimumCount:          2,
+		IntervalInSeconds:     60,
+		OpenDurationInSeconds: 150,
+		ThresholdType:         ThresholdPercentage,
+		Clock:                 clock,
+	}
+	m, err := ConfigureCircuit(monitorOptions)
+	assert.NoError(t, err)
+
+	m.UpdateStatus(false)
+	m.UpdateStatus(false)
+	assert.True(t, m.IsCircuitOpen())
+
+	// Test case 1: The interval reset clears the counts but keeps the circuit open
+	// Expected output: Empty counts, circuit open
+	clock.Advance(2 * time.Minute)
+	assert.Equal(t, int64(0), m.Data().FailureCount)
+	assert.True(t, m.IsCircuitOpen())
+
+	// Test case 2: The interval reset after the open duration closes the circuit
+	// Expected output: Circuit closed
+	clock.Advance(time.Minute)
+	assert.False(t, m.IsCircuitOpen())
+	assert.Equal(t, int64(1700000180), m.Data().LastStateChangedAt)
+
+	// Test case 3: A negative open duration is rejected
+	// Expected output: An error
+	monitorOptions.OpenDurationInSeconds = -1
+	_, err = ConfigureCircuit(monitorOptions)
+	assert.EqualError(t, err, "invalid open duration -1")
+}
+
+func TestConcurrentUpdateStatus(t *testing.T) {
+	clock := NewFakeClock(time.Unix(1700000000, 0))
+	var opened, closed int64
+	var mutex sync.Mutex
+	monitorOptions := CircuitOptions{
+		Name:              "TEST_ConcurrentUpdateStatus",
+		Threshold:         50,
+		MinimumCount:      10,
+		IntervalInSeconds: 60,
+		ThresholdType:     ThresholdPercentage,
+		Clock:             clock,
+		OnCircuitOpen: func(t CallbackEvent) {
+			mutex.Lock()
+			opened++
+			mutex.Unlock()
+		},
+		OnCircuitClosed: func(t CallbackEvent) {
+			mutex.Lock()
+			closed++
+			mutex.Unlock()
+		},
+	}
+	m, err := ConfigureCircuit(monitorOptions)
+	assert.NoError(t, err)
+
+	// Test case 1: Record successes from many goroutines
+	// Expected output: Every event is counted and the circuit stays closed
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 1000; j++ {
+				m.UpdateStatus(true)
+			}
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, int64(8000), m.Data().SuccessCount)
+	assert.False(t, m.IsCircuitOpen())
+
+	// Test case 2: Record failures from many goroutines until the threshold is breached
+	// Expected output: Every event is counted and the circuit opens exactly once
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 1500; j++ {
+				m.UpdateStatus(false)
+			}
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, int64(12000), m.Data().FailureCount)
+	assert.True(t, m.IsCircuitOpen())
+	assert.Equal(t, int64(1), m.Data().TripCount)
+	mutex.Lock()
+	assert.Equal(t, int64(1), opened)
+	assert.Equal(t, int64(0), closed)
+	mutex.Unlock()
+}
+
+func BenchmarkUpdateStatus(b *testing.B) {
+	benchmarks := []struct {
+		name          string
+		thresholdType string
+		threshold     float32
+	}{
+		{"Percentage", ThresholdPercentage, 50},
+		{"Consecutive", ThresholdConsecutive, 1000000},
+	}
+	for _, bm := range benchmarks {
+		b.Run(bm.name, func(b *testing.B) {
+			m, err := ConfigureCircuit(CircuitOptions{
+				Name:              "BENCH_UpdateStatus",
+				Threshold:         bm.threshold,
+				MinimumCount:      10,
+				IntervalInSeconds: 60,
+				ThresholdType:     bm.thresholdType,
+				Clock:             NewFakeClock(time.Unix(1700000000, 0)),
+			})
+			if err != nil {
+				b.Fatal(err)
+			}
+			defer m.Close()
+			b.RunParallel(func(pb *testing.PB) {
+				for pb.Next() {
+					m.UpdateStatus(true)
+				}
+			})
+		})
+	}
+}
+
+func TestConcurrentAccess(t *testing.T) {
+	// Test case 1: Record events while reading, resetting, reconfiguring and persisting the circuit
+	// Expected output: No data race and every recorded event is accounted for
+	clock := NewFakeClock(time.Unix(1700000000, 0))
+	monitorOptions := CircuitOptions{
+		Name:              "TEST_ConcurrentAccess",
+		Threshold:         50,
+		MinimumCount:      10,
+		IntervalInSeconds: 60,
+		ThresholdType:     ThresholdPercentage,
+		Clock:             clock,
+		OnCircuitOpen:     func(t CallbackEvent) {},
+		OnCircuitClosed:   func(t CallbackEvent) {},
+	}
+	m, err := ConfigureCircuit(monitorOptions)
+	assert.NoError(t, err)
+	defer m.Close()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 500; j++ {
+				m.UpdateStatus((i+j)%3 != 0)
+			}
+		}(i)
+	}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for j := 0; j < 500; j++ {
+			m.Data()
+			m.State()
+			m.IsCircuitOpen()
+			m.Execute(func() error { return nil })
+		}
+	}()
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for j := 0; j < 20; j++ {
+			clock.Advance(time.Minute)
+		}
+	}()
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for j := 0; j < 20; j++ {
+			options := monitorOptions
+			options.Threshold = float32(40 + j)
+			assert.NoError(t, m.UpdateOptions(options))
+			_, err := m.MarshalState()
+			assert.NoError(t, err)
+		}
+	}()
+	wg.Wait()
+
+	// Test case 2: Record events without any resets once the goroutines are done
+	// Expected output: The counters match the recorded events exactly
+	clock.Advance(time.Minute)
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 500; j++ {
+				m.UpdateStatus(true)
+			}
+		}()
+	}
+	wg.Wait()
+	data := m.Data()
+	assert.Equal(t, int64(2000), data.SuccessCount)
+	assert.Equal(t, int64(0), data.FailureCount)
+}
+
+func TestIsCircuitOpenAfterOpenDuration(t *testing.T) {
+	clock := NewFakeClock(time.Unix(1700000000, 0))
+	var transitions [][2]CircuitState
+	monitorOptions := CircuitOptions{
+		Name:                  "TEST_IsCircuitOpenAfterOpenDuration",
+		Threshold:             2,
+		ThresholdType:         ThresholdConsecutive,
+		MinimumCount:          1,
+		IntervalInSeconds:     120,
+		OpenDurationInSeconds: 30,
+		Clock:                 clock,
+		OnStateChange: func(from, to CircuitState, t CallbackEvent) {
+			transitions = append(transitions, [2]CircuitState{from, to})
+		},
+	}
+	m, err := ConfigureCircuit(monitorOptions)
+	assert.NoError(t, err)
+	defer m.Close()
+
+	m.UpdateStatus(false)
+	m.UpdateStatus(false)
+	assert.True(t, m.IsCircuitOpen())
+
+	// Test case 1: Read the circuit before the open duration elapsed
+	// Expected output: Still open
+	clock.Advance(29 * time.Second)
+	assert.True(t, m.IsCircuitOpen())
+	assert.Equal(t, StateOpen, m.State())
+
+	// Test case 2: Read the circuit after the open duration elapsed without new events or ticks
+	// Expected output: Closed with a fresh window and the transition reported once
+	clock.Advance(time.Second)
+	assert.False(t, m.IsCircuitOpen())
+	assert.Equal(t, StateClosed, m.State())
+	data := m.Data()
+	assert.False(t, data.IsCircuitOpen)
+	assert.Equal(t, int64(0), data.FailureCount)
+	assert.Equal(t, clock.Now(), data.LastStateChangedAt)
+	assert.Equal(t, [][2]CircuitState{{StateClosed, StateOpen}, {StateOpen, StateClosed}}, transitions)
+}
+
+func TestRequireMinimumCountPerBucket(t *testing.T) {
+	newCircuit := func(perBucket bool) (Circuit, *FakeClock) {
+		clock := NewFakeClock(time.Unix(1700000000, 0))
+		m, err := ConfigureCircuit(CircuitOptions{
+			Name:                         "TEST_RequireMinimumCountPerBucket",
+			Threshold:                    50,
+			ThresholdType:                ThresholdPercentage,
+			MinimumCount:                 20,
+			IntervalInSeconds:            60,
+			RequireMinimumCountPerBucket: perBucket,
+			Clock:                        clock,
+		})
+		assert.NoError(t, err)
+		for i := 0; i < 20; i++ {
+			m.UpdateStatus(true)
+		}
+		// start the next interval while the service starts failing
+		clock.Advance(61 * time.Second)
+		return m, clock
+	}
+
+	// Test case 1: Failures right after the interval reset without the option
+	// Expected output: The circuit stays closed until MinimumCount is reached again
+	m, _ := newCircuit(false)
+	for i := 0; i < 5; i++ {
+		m.UpdateStatus(false)
+	}
+	assert.False(t, m.IsCircuitOpen())
+	m.Close()
+
+	// Test case 2: Failures right after the interval reset with the option
+	// Expected output: The previous interval's volume counts towards MinimumCount and the circuit opens
+	m, _ = newCircuit(true)
+	for i := 0; i < 5; i++ {
+		m.UpdateStatus(false)
+	}
+	assert.True(t, m.IsCircuitOpen())
+	m.Close()
+
+	// Test case 3: Failures late in the interval with the option
+	// Expected output: The previous interval barely counts anymore and the circuit stays closed
+	m, clock := newCircuit(true)
+	clock.Advance(50 * time.Second)
+	for i := 0; i < 5; i++ {
+		m.UpdateStatus(false)
+	}
+	assert.False(t, m.IsCircuitOpen())
+	m.Close()
+}
+
+func TestFractionalPercentageThreshold(t *testing.T) {
+	newCircuit := func() Circuit {
+		m, err := ConfigureCircuit(CircuitOptions{
+			Name:              "TEST_FractionalPercentageThreshold",
+			Threshold:         0.5,
+			ThresholdType:     ThresholdPercentage,
+			MinimumCount:      10000,
+			IntervalInSeconds: 60,
+			Clock:             NewFakeClock(time.Unix(1700000000, 0)),
+		})
+		assert.NoError(t, err)
+		return m
+	}
+
+	// Test case 1: 60 failures in 10000 calls (0.6%) with a 0.5% threshold
+	// Expected output: The circuit opens
+	m := newCircuit()
+	for i := 0; i < 9940; i++ {
+		m.UpdateStatus(true)
+	}
+	for i := 0; i < 60; i++ {
+		m.UpdateStatus(false)
+	}
+	assert.True(t, m.IsCircuitOpen())
+	m.Close()
+
+	// Test case 2: 40 failures in 10000 calls (0.4%) with a 0.5% threshold
+	// Expected output: The circuit stays closed
+	m = newCircuit()
+	for i := 0; i < 9960; i++ {
+		m.UpdateStatus(true)
+	}
+	for i := 0; i < 40; i++ {
+		m.UpdateStatus(false)
+	}
+	assert.False(t, m.IsCircuitOpen())
+	m.Close()
+
+	// Test case 3: 50 failures in 10000 calls (exactly 0.5%) with a 0.5% threshold
+	// Expected output: The circuit opens
+	m = newCircuit()
+	for i := 0; i < 9950; i++ {
+		m.UpdateStatus(true)
+	}
+	for i := 0; i < 50; i++ {
+		m.UpdateStatus(false)
+	}
+	assert.True(t, m.IsCircuitOpen())
+	m.Close()
+}
+
+func TestUpdateStatusAfterClose(t *testing.T) {
+	clock := NewFakeClock(time.Unix(1700000000, 0))
+	var opened int
+	m, err := ConfigureCircuit(CircuitOptions{
+		Name:              "TEST_UpdateStatusAfterClose",
+		Threshold:         2,
+		ThresholdType:     ThresholdConsecutive,
+		MinimumCount:      1,
+		IntervalInSeconds: 60,
+		Clock:             clock,
+		OnCircuitOpen:     func(t CallbackEvent) { opened++ },
+	})
+	assert.NoError(t, err)
+
+	// Test case 1: Record events before closing the circuit
+	// Expected output: No error and the events are counted
+	assert.NoError(t, m.UpdateStatusE(true))
+	m.UpdateStatus(false)
+	assert.Equal(t, int64(1), m.Data().SuccessCount)
+	assert.Equal(t, int64(1), m.Data().FailureCount)
+
+	// Test case 2: Record events after closing the circuit
+	// Expected output: ErrCircuitShutdown, the counts don't change and no callback fires
+	m.Close()
+	assert.Equal(t, ErrCircuitShutdown, m.UpdateStatusE(false))
+	m.UpdateStatus(false)
+	m.UpdateStatusWithLatency(false, time.Second)
+	assert.NoError(t, m.Execute(func() error { return nil }))
+	data := m.Data()
+	assert.Equal(t, int64(1), data.SuccessCount)
+	assert.Equal(t, int64(1), data.FailureCount)
+	assert.False(t, data.IsCircuitOpen)
+	assert.Equal(t, 0, opened)
+
+	// Test case 3: Close the circuit twice
+	// Expected output: No panic
+	m.Close()
+}
+
+func TestUpdateStatusExt(t *testing.T) {
+	clock := NewFakeClock(time.Unix(1700000000, 0))
+	m, err := ConfigureCircuit(CircuitOptions{
+		Name:                  "TEST_UpdateStatusExt",
+		Threshold:             2,
+		ThresholdType:         ThresholdConsecutive,
+		IntervalInSeconds:     60,
+		OpenDurationInSeconds: 10,
+		HalfOpenMaxProbes:     1,
+		Clock:                 clock,
+	})
+	assert.NoError(t, err)
+	defer m.Close()
+
+	// Test case 1: Record outcomes with record set to true
+	// Expected output: They are recorded like UpdateStatus
+	m.UpdateStatusExt(true, true)
+	m.UpdateStatusExt(false, true)
+	assert.Equal(t, int64(1), m.Data().SuccessCount)
+	assert.Equal(t, int64(1), m.Data().FailureCount)
+
+	// Test case 2: Skip outcomes with record set to false
+	// Expected output: Both counts are unchanged and the failures do not trip the circuit
+	for i := 0; i < 5; i++ {
+		m.UpdateStatusExt(false, false)
+		m.UpdateStatusExt(true, false)
+	}
+	assert.Equal(t, int64(1), m.Data().SuccessCount)
+	assert.Equal(t, int64(1), m.Data().FailureCount)
+	assert.Equal(t, int64(1), m.Data().ConsecutiveCounter)
+	assert.False(t, m.IsCircuitOpen())
+
+	// Test case 3: Skip an outcome while the only probe slot of the half-open circuit is reserved
+	// Expected output: The slot is not given back, as UpdateStatusExt cannot tell whether the caller holds it
+	m.UpdateStatus(false)
+	assert.True(t, m.IsCircuitOpen())
+	clock.Advance(10 * time.Second)
+	assert.Equal(t, StateHalfOpen, m.State())
+	assert.True(t, m.AllowRequest())
+	m.UpdateStatusExt(false, false)
+	assert.Equal(t, StateHalfOpen, m.State())
+	assert.False(t, m.AllowRequest())
+
+	// Test case 4: Record the outcome of the probe
+	// Expected output: The circuit closes
+	m.UpdateStatusExt(true, true)
+	assert.Equal(t, StateClosed, m.State())
+}
+
+func TestSkippedCount(t *testing.T) {
+	clock := NewFakeClock(time.Unix(1700000000, 0))
+	m, err := ConfigureCircuit(CircuitOptions{
+		Name:              "TEST_SkippedCount",
+		Threshold:         50,
+		ThresholdType:     ThresholdPercentage,
+		MinimumCount:      4,
+		IntervalInSeconds: 60,
+		Clock:             clock,
+	})
+	assert.NoError(t, err)
+	defer m.Close()
+
+	// Test case 1: Mix recorded and skipped events
+	// Expected output: The skipped events are tallied separately and the failure rate is over the recorded ones only
+	m.UpdateStatusExt(true, true)
+	m.UpdateStatusExt(true, true)
+	m.UpdateStatusExt(false, true)
+	for i := 0; i < 6; i++ {
+		m.UpdateStatusExt(true, false)
+	}
+	m.UpdateStatusExt(false, false)
+	data := m.Data()
+	assert.Equal(t, int64(2), data.SuccessCount)
+	assert.Equal(t, int64(1), data.FailureCount)
+	assert.Equal(t, int64(3), data.TotalCount)
+	assert.Equal(t, int64(7), data.SkippedCount)
+	assert.InDelta(t, 1.0/3, data.FailureRate, 1e-9)
+
+	// Test case 2: Record a failure reaching MinimumCount with the recorded events only
+	// Expected output: The circuit opens with a 50% failure rate, which the skipped successes would have kept it below
+	m.UpdateStatusExt(false, true)
+	assert.Equal(t, 0.5, m.Data().FailureRate)
+	assert.True(t, m.IsCircuitOpen())
+
+	// Test case 3: The interval ends
+	// Expected output: SkippedCount is cleared with the counts
+	clock.Advance(60 * time.Second)
+	assert.Equal(t, int64(0), m.Data().SkippedCount)
+	m.Close()
+	m.UpdateStatusExt(false, false)
+	assert.Equal(t, int64(0), m.Data().SkippedCount)
+}
+
+func TestPercentageRounding(t *testing.T) {
+	// 496 failures in 1000 calls is a failure rate of 49.6%
+	trips := func(rounding string) bool {
+		m, err := ConfigureCircuit(CircuitOptions{
+			Name:               "TEST_PercentageRounding",
+			Threshold:          50,
+			ThresholdType:      ThresholdPercentage,
+			MinimumCount:       1000,
+			IntervalInSeconds:  60,
+			PercentageRounding: rounding,
+			Clock:              NewFakeClock(time.Unix(1700000000, 0)),
+		})
+		assert.NoError(t, err)
+		defer m.Close()
+		for i := 0; i < 504; i++ {
+			m.UpdateStatus(true)
+		}
+		for i := 0; i < 496; i++ {
+			m.UpdateStatus(false)
+		}
+		return m.IsCircuitOpen()
+	}
+
+	// Test case 1: Exact comparison, the default
+	// Expected output: 49.6% does not trip a 50% threshold
+	assert.False(t, trips(""))
+	assert.False(t, trips(RoundingExact))
+
+	// Test case 2: Ceil rounding
+	// Expected output: 49.6% is rounded up to 50% and trips the threshold
+	assert.True(t, trips(RoundingCeil))
+
+	// Test case 3: Floor rounding
+	// Expected output: 49.6% is rounded down to 49% and does not trip the threshold
+	assert.False(t, trips(RoundingFloor))
+
+	// Test case 4: Round to the nearest percent
+	// Expected output: 49.6% is rounded to 50% and trips the threshold
+	assert.True(t, trips(RoundingRound))
+}
+
+func TestCooldownJitter(t *testing.T) {
+	// openDurations trips a circuit several times and returns the open duration of each trip
+	openDurations := func(seed int64) []int64 {
+		clock := NewFakeClock(time.Unix(1700000000, 0))
+		m, err := ConfigureCircuit(CircuitOptions{
+			Name:                  "TEST_CooldownJitter",
+			Threshold:             1,
+			ThresholdType:         ThresholdConsecutive,
+			MinimumCount:          1,
+			IntervalInSeconds:     600,
+			OpenDurationInSeconds: 100,
+			CooldownJitter:        0.2,
+			Rand:                  rand.New(rand.NewSource(seed)),
+			Clock:                 clock,
+		})
+		assert.NoError(t, err)
+		defer m.Close()
+
+		var durations []int64
+		for i := 0; i < 20; i++ {
+			m.UpdateStatus(false)
+			opened := clock.Now()
+			for m.IsCircuitOpen() {
+				clock.Advance(time.Second)
+			}
+			durations = append(durations, clock.Now()-opened)
+		}
+		return durations
+	}
+
+	// Test case 1: Trip a circuit with a 20% jitter on a 100 second open duration
+	// Expected output: Every open duration is between 80 and 120 seconds and they are not all equal
+	durations := openDurations(1)
+	distinct := map[int64]bool{}
+	for _, d := range durations {
+		assert.True(t, d >= 80 && d <= 120, "open duration %d out of range", d)
+		distinct[d] = true
+	}
+	assert.True(t, len(distinct) > 1)
+
+	// Test case 2: Trip a circuit with the same seed again
+	// Expected output: The same open durations
+	assert.Equal(t, durations, openDurations(1))
+
+	// Test case 3: An invalid jitter
+	// Expected output: Error
+	_, err := ConfigureCircuit(CircuitOptions{
+		Name:              "TEST_CooldownJitter",
+		Threshold:         1,
+		ThresholdType:     ThresholdConsecutive,
+		MinimumCount:      1,
+		IntervalInSeconds: 60,
+		CooldownJitter:    1.5,
+	})
+	assert.EqualError(t, err, "invalid cooldown jitter 1.500000")
+}
+
+func TestMinimumFailures(t *testing.T) {
+	m, err := ConfigureCircuit(CircuitOptions{
+		Name:              "TEST_MinimumFailures",
+		Threshold:         50,
+		ThresholdType:     ThresholdPercentage,
+		MinimumCount:      2,
+		MinimumFailures:   5,
+		IntervalInSeconds: 60,
+		Clock:             NewFakeClock(time.Unix(1700000000, 0)),
+	})
+	assert.NoError(t, err)
+	defer m.Close()
+
+	// Test case 1: MinimumCount is reached with a 100% failure rate but too few failures
+	// Expected output: The circuit stays closed
+	m.UpdateStatus(false)
+	m.UpdateStatus(false)
+	assert.False(t, m.IsCircuitOpen())
+
+	// Test case 2: MinimumCount is reached with successes and the failure rate is above the threshold
+	// Expected output: The circuit stays closed while there are fewer than 5 failures
+	m.UpdateStatus(true)
+	m.UpdateStatus(false)
+	m.UpdateStatus(false)
+	assert.Equal(t, int64(4), m.Data().FailureCount)
+	assert.False(t, m.IsCircuitOpen())
+
+	// Test case 3: The fifth failure is recorded
+	// Expected output: The circuit opens
+	m.UpdateStatus(false)
+	assert.True(t, m.IsCircuitOpen())
+}
+
+func TestMinimumCountBoundary(t *testing.T) {
+	tests := []struct {
+		name          string
+		thresholdType string
+		threshold     float32
+		minimumCount  int64
+		opensAt       int
+	}{
+		{"count", ThresholdCount, 2, 4, 4},
+		{"percentage", ThresholdPercentage, 50, 4, 4},
+		{"ewma", ThresholdEWMA, 50, 4, 4},
+		{"more than 4 events", ThresholdPercentage, 50, 5, 5},
+		{"consecutive", ThresholdConsecutive, 2, 4, 2},
+		{"failure count", ThresholdFailureCount, 2, 4, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// the other types record events under the read lock without a half life
+			halfLife := 0.0
+			if tt.thresholdType == ThresholdEWMA {
+				halfLife = 60
+			}
+			m, err := ConfigureCircuit(CircuitOptions{
+				Name:              "TEST_MinimumCountBoundary",
+				Threshold:         tt.threshold,
+				ThresholdType:     tt.thresholdType,
+				MinimumCount:      tt.minimumCount,
+				HalfLifeSeconds:   halfLife,
+				IntervalInSeconds: 60,
+				Clock:             NewFakeClock(time.Unix(1700000000, 0)),
+			})
+			assert.NoError(t, err)
+			defer m.Close()
+
+			// Test case 1: Record one failure less than needed
+			// Expected output: The circuit stays closed
+			for i := 0; i < tt.opensAt-1; i++ {
+				m.UpdateStatus(false)
+			}
+			assert.False(t, m.IsCircuitOpen())
+
+			// Test case 2: Record the failure reaching MinimumCount, or the threshold of an ungated type
+			// Expected output: The circuit opens, as MinimumCount is inclusive
+			m.UpdateStatus(false)
+			assert.True(t, m.IsCircuitOpen())
+		})
+	}
+}
+
+func TestMinRequestsPerSecond(t *testing.T) {
+	newCircuit := func(clock *FakeClock) Circuit {
+		m, err := ConfigureCircuit(CircuitOptions{
+			Name:                 "TEST_MinRequestsPerSecond",
+			Threshold:            50,
+			ThresholdType:        ThresholdPercentage,
+			MinimumCount:         2,
+			MinRequestsPerSecond: 1,
+			IntervalInSeconds:    600,
+			Clock:                clock,
+		})
+		assert.NoError(t, err)
+		return m
+	}
+
+	// Test case 1: MinimumCount is reached with a 100% failure rate at a high request rate
+	// Expected output: The circuit opens although the events are far fewer than one per second of the interval
+	clock := NewFakeClock(time.Unix(1700000000, 0))
+	m := newCircuit(clock)
+	defer m.Close()
+	m.UpdateStatus(false)
+	clock.Advance(time.Second)
+	m.UpdateStatus(false)
+	assert.True(t, m.IsCircuitOpen())
+
+	// Test case 2: Record a failure every two seconds for five minutes
+	// Expected output: The circuit stays closed at 0.5 requests per second
+	clock = NewFakeClock(time.Unix(1700000000, 0))
+	m = newCircuit(clock)
+	defer m.Close()
+	for i := 0; i < 150; i++ {
+		clock.Advance(2 * time.Second)
+		m.UpdateStatus(false)
+	}
+	assert.Equal(t, int64(150), m.Data().FailureCount)
+	assert.False(t, m.IsCircuitOpen())
+
+	// Test case 3: A burst of failures brings the rate up to one request per second
+	// Expected output: The circuit opens on the 300th event in 300 seconds
+	for i := 0; i < 149; i++ {
+		m.UpdateStatus(false)
+	}
+	assert.False(t, m.IsCircuitOpen())
+	m.UpdateStatus(false)
+	assert.True(t, m.IsCircuitOpen())
+}
+
+func TestCloseThreshold(t *testing.T) {
+	m, err := ConfigureCircuit(CircuitOptions{
+		Name:              "TEST_CloseThreshold",
+		Threshold:         50,
+		CloseThreshold:    20,
+		ThresholdType:     ThresholdPercentage,
+		MinimumCount:      10,
+		IntervalInSeconds: 60,
+		Clock:             NewFakeClock(time.Unix(1700000000, 0)),
+	})
+	assert.NoError(t, err)
+	defer m.Close()
+
+	// Test case 1: The failure rate reaches the threshold
+	// Expected output: The circuit opens
+	for i := 0; i < 5; i++ {
+		m.UpdateStatus(true)
+		m.UpdateStatus(false)
+	}
+	assert.True(t, m.IsCircuitOpen())
+
+	// Test case 2: The failure rate moves between the close threshold and the threshold
+	// Expected output: The circuit stays open
+	for i := 0; i < 5; i++ {
+		m.UpdateStatus(true)
+		assert.True(t, m.IsCircuitOpen())
+	}
+	m.UpdateStatus(false)
+	for i := 0; i < 14; i++ {
+		m.UpdateStatus(true)
+		assert.True(t, m.IsCircuitOpen())
+	}
+	assert.Equal(t, 0.2, m.Data().FailureRate)
+
+	// Test case 3: The failure rate drops below the close threshold
+	// Expected output: The circuit closes
+	m.UpdateStatus(true)
+	assert.False(t, m.IsCircuitOpen())
+
+	// Test case 4: The failure rate rises between the close threshold and the threshold
+	// Expected output: The circuit stays closed
+	for i := 0; i < 4; i++ {
+		m.UpdateStatus(false)
+		assert.False(t, m.IsCircuitOpen())
+	}
+}
+
+func TestCloseThresholdCount(t *testing.T) {
+	clock := NewFakeClock(time.Unix(1700000000, 0))
+	m, err := ConfigureCircuit(CircuitOptions{
+		Name:              "TEST_CloseThresholdCount",
+		Threshold:         5,
+		CloseThreshold:    2,
+		ThresholdType:     ThresholdCount,
+		MinimumCount:      6,
+		IntervalInSeconds: 60,
+		StickyOpen:        true,
+		Clock:             clock,
+	})
+	assert.NoError(t, err)
+	defer m.Close()
+
+	// Test case 1: The failures reach the threshold
+	// Expected output: The circuit opens
+	for i := 0; i < 6; i++ {
+		m.UpdateStatus(false)
+	}
+	assert.True(t, m.IsCircuitOpen())
+
+	// Test case 2: The next interval records failures between the close threshold and the threshold
+	// Expected output: The circuit stays open
+	clock.Advance(time.Minute)
+	m.UpdateStatus(false)
+	m.UpdateStatus(false)
+	for i := 0; i < 4; i++ {
+		m.UpdateStatus(true)
+	}
+	assert.True(t, m.IsCircuitOpen())
+
+	// Test case 3: The next interval records fewer failures than the close threshold
+	// Expected output: The circuit closes
+	clock.Advance(time.Minute)
+	m.UpdateStatus(false)
+	for i := 0; i < 5; i++ {
+		m.UpdateStatus(true)
+	}
+	assert.False(t, m.IsCircuitOpen())
+}
+
+func TestSetEnabled(t *testing.T) {
+	clock := NewFakeClock(time.Unix(1700000000, 0))
+	m, err := ConfigureCircuit(CircuitOptions{
+		Name:              "TEST_SetEnabled",
+		Threshold:         3,
+		ThresholdType:     ThresholdCount,
+		MinimumCount:      4,
+		IntervalInSeconds: 60,
+		Clock:             clock,
+	})
+	assert.NoError(t, err)
+	defer m.Close()
+
+	// Test case 1: Disable the circuit and record failures crossing the threshold
+	// Expected output: The counts are recorded but every call is allowed
+	m.SetEnabled(false)
+	for i := 0; i < 4; i++ {
+		m.UpdateStatus(false)
+	}
+	assert.Equal(t, int64(4), m.Data().FailureCount)
+	assert.Equal(t, StateOpen, m.State())
+	assert.False(t, m.IsCircuitOpen())
+	assert.True(t, m.AllowRequest())
+	called := false
+	assert.NoError(t, m.Execute(func() error {
+		called = true
+		return nil
+	}))
+	assert.True(t, called)
+
+	// Test case 2: Enable the circuit again
+	// Expected output: The state reached from the accumulated counts applies
+	m.SetEnabled(true)
+	assert.True(t, m.IsCircuitOpen())
+	assert.False(t, m.AllowRequest())
+	assert.Equal(t, ErrCircuitOpen, m.Execute(func() error { return nil }))
+
+	// Test case 3: Disable and enable the circuit after the interval reset
+	// Expected output: The circuit is closed and evaluates new counts normally
+	clock.Advance(60 * time.Second)
+	m.SetEnabled(false)
+	m.SetEnabled(true)
+	assert.False(t, m.IsCircuitOpen())
+	for i := 0; i < 4; i++ {
+		m.UpdateStatus(false)
+	}
+	assert.True(t, m.IsCircuitOpen())
+}
+
+func TestFailureCount(t *testing.T) {
+	clock := NewFakeClock(time.Unix(1700000000, 0))
+	m, err := ConfigureCircuit(CircuitOptions{
+		Name:              "TEST_FailureCount",
+		Threshold:         3,
+		ThresholdType:     ThresholdFailureCount,
+		IntervalInSeconds: 60,
+		Clock:             clock,
+	})
+	assert.NoError(t, err)
+	defer m.Close()
+
+	// Test case 1: Record failures interleaved with successes from a cold start without MinimumCount
+	// Expected output: The circuit opens on the third failure
+	m.UpdateStatus(false)
+	m.UpdateStatus(true)
+	m.UpdateStatus(false)
+	m.UpdateStatus(true)
+	assert.False(t, m.IsCircuitOpen())
+	m.UpdateStatus(false)
+	assert.True(t, m.IsCircuitOpen())
+
+	// Test case 2: Record successes on the open circuit
+	// Expected output: The circuit stays open until the interval reset clears the failures
+	for i := 0; i < 10; i++ {
+		m.UpdateStatus(true)
+	}
+	assert.True(t, m.IsCircuitOpen())
+	clock.Advance(time.Minute)
+	assert.False(t, m.IsCircuitOpen())
+
+	// Test case 3: Configure a MinimumCount below the threshold
+	// Expected output: No error, unlike ThresholdCount, and the threshold alone trips the circuit
+	m, err = ConfigureCircuit(CircuitOptions{
+		Name:              "TEST_FailureCountWithMinimumCount",
+		Threshold:         5,
+		ThresholdType:     ThresholdFailureCount,
+		MinimumCount:      2,
+		IntervalInSeconds: 60,
+		Clock:             clock,
+	})
+	assert.NoError(t, err)
+	defer m.Close()
+	for i := 0; i < 4; i++ {
+		m.UpdateStatus(false)
+	}
+	assert.False(t, m.IsCircuitOpen())
+	m.UpdateStatus(false)
+	assert.True(t, m.IsCircuitOpen())
+}
+
+func TestConsecutiveIgnoresMinimumCount(t *testing.T) {
+	// Test case 1: Record 5 failures from a cold start with a MinimumCount of 100
+	// Expected output: The consecutive-5 circuit opens on the fifth failure
+	m, err := ConfigureCircuit(CircuitOptions{
+		Name:              "TEST_ConsecutiveIgnoresMinimumCount",
+		Threshold:         5,
+		ThresholdType:     ThresholdConsecutive,
+		MinimumCount:      100,
+		IntervalInSeconds: 60,
+		Clock:             NewFakeClock(time.Unix(1700000000, 0)),
+	})
+	assert.NoError(t, err)
+	defer m.Close()
+	for i := 0; i < 4; i++ {
+		m.UpdateStatus(false)
+	}
+	assert.False(t, m.IsCircuitOpen())
+	m.UpdateStatus(false)
+	assert.True(t, m.IsCircuitOpen())
+
+	// Test case 2: Record a success on the open circuit
+	// Expected output: The circuit closes after CloseConsecutiveCount successes
+	m.UpdateStatus(true)
+	assert.False(t, m.IsCircuitOpen())
+
+	// Test case 3: Configure a consecutive circuit without MinimumCount
+	// Expected output: No error, and the circuit opens from the first failures
+	m, err = ConfigureCircuit(CircuitOptions{
+		Name:              "TEST_ConsecutiveWithoutMinimumCount",
+		Threshold:         2,
+		ThresholdType:     ThresholdConsecutive,
+		IntervalInSeconds: 60,
+		Clock:             NewFakeClock(time.Unix(1700000000, 0)),
+	})
+	assert.NoError(t, err)
+	defer m.Close()
+	m.UpdateStatus(false)
+	m.UpdateStatus(false)
+	assert.True(t, m.IsCircuitOpen())
+
+	// Test case 4: Combine a consecutive and a percentage rule with OperatorOr
+	// Expected output: Only the consecutive rule trips the circuit before MinimumCount is reached
+	m, err = ConfigureCircuit(CircuitOptions{
+		Name: "TEST_ConsecutiveOrPercentage",
+		Thresholds: []ThresholdRule{
+			{ThresholdType: ThresholdPercentage, Threshold: 10},
+			{ThresholdType: ThresholdConsecutive, Threshold: 3},
+		},
+		ThresholdsOperator: OperatorOr,
+		MinimumCount:       100,
+		IntervalInSeconds:  60,
+		Clock:              NewFakeClock(time.Unix(1700000000, 0)),
+	})
+	assert.NoError(t, err)
+	defer m.Close()
+	m.UpdateStatus(false)
+	m.UpdateStatus(true)
+	m.UpdateStatus(false)
+	m.UpdateStatus(false)
+	assert.False(t, m.IsCircuitOpen())
+	m.UpdateStatus(false)
+	assert.True(t, m.IsCircuitOpen())
+}
+
+func TestWarmup(t *testing.T) {
+	clock := NewFakeClock(time.Unix(1700000000, 0))
+	m, err := ConfigureCircuit(CircuitOptions{
+		Name:              "TEST_Warmup",
+		Threshold:         50,
+		ThresholdType:     ThresholdPercentage,
+		MinimumCount:      10,
+		IntervalInSeconds: 120,
+		WarmupSeconds:     30,
+		Clock:             clock,
+	})
+	assert.NoError(t, err)
+	defer m.Close()
+
+	// Test case 1: Record failures during the warmup period
+	// Expected output: The counts are recorded but the circuit stays closed
+	for i := 0; i < 20; i++ {
+		m.UpdateStatus(false)
+	}
+	assert.Equal(t, int64(20), m.Data().FailureCount)
+	assert.False(t, m.IsCircuitOpen())
+	clock.Advance(29 * time.Second)
+	m.UpdateStatus(false)
+	assert.False(t, m.IsCircuitOpen())
+
+	// Test case 2: Record a failure after the warmup period
+	// Expected output: The accumulated counts open the circuit
+	clock.Advance(time.Second)
+	m.UpdateStatus(false)
+	assert.True(t, m.IsCircuitOpen())
+}
+
+func TestRequireFullWindow(t *testing.T) {
+	clock := NewFakeClock(time.Unix(1700000000, 0))
+	m, err := ConfigureCircuit(CircuitOptions{
+		Name:                  "TEST_RequireFullWindow",
+		Threshold:             2,
+		ThresholdType:         ThresholdConsecutive,
+		IntervalInSeconds:     60,
+		OpenDurationInSeconds: 10,
+		RequireFullWindow:     true,
+		Clock:                 clock,
+	})
+	assert.NoError(t, err)
+	defer m.Close()
+
+	// Test case 1: Record failures during the first interval
+	// Expected output: The counts are recorded but the circuit stays closed
+	m.UpdateStatus(false)
+	m.UpdateStatus(false)
+	m.UpdateStatus(false)
+	assert.Equal(t, int64(3), m.Data().ConsecutiveCounter)
+	assert.False(t, m.IsCircuitOpen())
+	clock.Advance(59 * time.Second)
+	m.UpdateStatus(false)
+	m.UpdateStatus(false)
+	assert.False(t, m.IsCircuitOpen())
+
+	// Test case 2: Record failures once a full interval has elapsed
+	// Expected output: The circuit opens as usual
+	clock.Advance(time.Second)
+	m.UpdateStatus(false)
+	assert.False(t, m.IsCircuitOpen())
+	m.UpdateStatus(false)
+	assert.True(t, m.IsCircuitOpen())
+
+	// Test case 3: Record failures right after the circuit closed again
+	// Expected output: The circuit cannot open until a full interval has elapsed since it closed
+	clock.Advance(60 * time.Second)
+	assert.False(t, m.IsCircuitOpen())
+	closedAt := clock.Now()
+	m.UpdateStatus(false)
+	m.UpdateStatus(false)
+	assert.False(t, m.IsCircuitOpen())
+	clock.Advance(30 * time.Second)
+	m.UpdateStatus(false)
+	m.UpdateStatus(false)
+	assert.False(t, m.IsCircuitOpen())
+	clock.Advance(30 * time.Second)
+	assert.Equal(t, int64(60), clock.Now()-closedAt)
+	m.UpdateStatus(false)
+	m.UpdateStatus(false)
+	assert.True(t, m.IsCircuitOpen())
+
+	// Test case 4: Record failures right after Reset
+	// Expected output: The circuit stays closed for another full interval
+	m.Reset()
+	m.UpdateStatus(false)
+	m.UpdateStatus(false)
+	assert.False(t, m.IsCircuitOpen())
+	clock.Advance(60 * time.Second)
+	m.UpdateStatus(false)
+	m.UpdateStatus(false)
+	assert.True(t, m.IsCircuitOpen())
+}
+
+func TestStaleAfter(t *testing.T) {
+	clock := NewFakeClock(time.Unix(1700000000, 0))
+	var closed []CallbackEvent
+	m, err := ConfigureCircuit(CircuitOptions{
+		Name:                  "TEST_StaleAfter",
+		Threshold:             50,
+		ThresholdType:         ThresholdPercentage,
+		MinimumCount:          4,
+		IntervalInSeconds:     600,
+		OpenDurationInSeconds: 300,
+		StaleAfterSeconds:     30,
+		OnCircuitClosed:       func(event CallbackEvent) { closed = append(closed, event) },
+		Clock:                 clock,
+	})
+	assert.NoError(t, err)
+	defer m.Close()
+
+	// Test case 1: Stop recording events for up to StaleAfterSeconds
+	// Expected output: The counts are kept
+	m.UpdateStatus(true)
+	m.UpdateStatus(false)
+	clock.Advance(30 * time.Second)
+	assert.Equal(t, int64(2), m.Data().TotalCount)
+
+	// Test case 2: Stop recording events for longer
+	// Expected output: The stale counts are cleared and do not add to new events
+	clock.Advance(time.Second)
+	assert.Equal(t, int64(0), m.Data().TotalCount)
+	m.UpdateStatus(false)
+	m.UpdateStatus(false)
+	m.UpdateStatus(true)
+	assert.False(t, m.IsCircuitOpen())
+	m.UpdateStatus(false)
+	assert.True(t, m.IsCircuitOpen())
+
+	// Test case 3: Leave the open circuit without events past the staleness window
+	// Expected output: It is treated as closed well before its open duration elapses
+	clock.Advance(30 * time.Second)
+	assert.True(t, m.IsCircuitOpen())
+	assert.False(t, m.AllowRequest())
+	clock.Advance(time.Second)
+	assert.False(t, m.IsCircuitOpen())
+	assert.True(t, m.AllowRequest())
+	assert.Equal(t, StateClosed, m.State())
+	assert.Equal(t, int64(0), m.Data().TotalCount)
+	if assert.Len(t, closed, 1) {
+		assert.Equal(t, StateOpen, closed[0].FromState)
+	}
+}
+
+func TestMaxOpen(t *testing.T) {
+	clock := NewFakeClock(time.Unix(1700000000, 0))
+	var closed []CallbackEvent
+	m, err := ConfigureCircuit(CircuitOptions{
+		Name:                  "TEST_MaxOpen",
+		Threshold:             2,
+		ThresholdType:         ThresholdConsecutive,
+		IntervalInSeconds:     600,
+		OpenDurationInSeconds: 30,
+		HalfOpenMaxProbes:     1,
+		MaxOpenSeconds:        100,
+		OnCircuitClosed:       func(event CallbackEvent) { closed = append(closed, event) },
+		Clock:                 clock,
+	})
+	assert.NoError(t, err)
+	defer m.Close()
+
+	// Test case 1: Trip the circuit and fail every half-open probe
+	// Expected output: The circuit keeps reopening without closing
+	m.UpdateStatus(false)
+	m.UpdateStatus(false)
+	for i := 0; i < 2; i++ {
+		clock.Advance(30 * time.Second)
+		assert.True(t, m.AllowRequest())
+		m.UpdateStatus(false)
+		assert.Equal(t, StateOpen, m.State())
+	}
+	clock.Advance(39 * time.Second)
+	assert.Equal(t, StateHalfOpen, m.State())
+	assert.Empty(t, closed)
+
+	// Test case 2: Advance the clock past MaxOpenSeconds since the circuit first opened
+	// Expected output: The circuit is forced closed with cleared counts
+	clock.Advance(time.Second)
+	assert.Equal(t, StateClosed, m.State())
+	assert.False(t, m.IsCircuitOpen())
+	assert.Equal(t, int64(0), m.Data().TotalCount)
+	if assert.Len(t, closed, 1) {
+		assert.Equal(t, StateHalfOpen, closed[0].FromState)
+		assert.Equal(t, ReasonMaxOpenElapsed, closed[0].Reason)
+	}
+
+	// Test case 3: Trip the circuit again, let it recover through a probe and trip it once more
+	// Expected output: MaxOpenSeconds is measured from the last time the circuit opened
+	m.UpdateStatus(false)
+	m.UpdateStatus(false)
+	clock.Advance(30 * time.Second)
+	assert.True(t, m.AllowRequest())
+	m.UpdateStatus(true)
+	assert.Equal(t, StateClosed, m.State())
+	m.UpdateStatus(false)
+	m.UpdateStatus(false)
+	clock.Advance(99 * time.Second)
+	assert.Equal(t, StateHalfOpen, m.State())
+	clock.Advance(time.Second)
+	assert.Equal(t, StateClosed, m.State())
+	assert.Len(t, closed, 3)
+}
+
+func TestAllowedInterleavedSuccesses(t *testing.T) {
+	m, err := ConfigureCircuit(CircuitOptions{
+		Name:                        "TEST_AllowedInterleavedSuccesses",
+		Threshold:                   3,
+		ThresholdType:               ThresholdConsecutive,
+		AllowedInterleavedSuccesses: 1,
+		IntervalInSeconds:           60,
+		Clock:                       NewFakeClock(time.Unix(1700000000, 0)),
+	})
+	assert.NoError(t, err)
+	defer m.Close()
+
+	// Test case 1: Record failures interleaved with single successes
+	// Expected output: The successes do not end the streak and the third failure opens the circuit
+	m.UpdateStatus(false)
+	m.UpdateStatus(true)
+	m.UpdateStatus(false)
+	m.UpdateStatus(true)
+	assert.Equal(t, int64(2), m.Data().ConsecutiveCounter)
+	assert.False(t, m.IsCircuitOpen())
+	m.UpdateStatus(false)
+	assert.Equal(t, int64(3), m.Data().ConsecutiveCounter)
+	assert.True(t, m.IsCircuitOpen())
+
+	// Test case 2: Record two successes in a row
+	// Expected output: The streak ends and the circuit closes
+	m.UpdateStatus(true)
+	assert.True(t, m.IsCircuitOpen())
+	m.UpdateStatus(true)
+	assert.Equal(t, int64(0), m.Data().ConsecutiveCounter)
+	assert.False(t, m.IsCircuitOpen())
+
+	// Test case 3: Record failures separated by two successes
+	// Expected output: Each pair of successes ends the streak and the circuit stays closed
+	for i := 0; i < 3; i++ {
+		m.UpdateStatus(false)
+		m.UpdateStatus(false)
+		m.UpdateStatus(true)
+		m.UpdateStatus(true)
+	}
+	assert.False(t, m.IsCircuitOpen())
+	assert.Equal(t, int64(0), m.Data().ConsecutiveCounter)
+}
+
+func TestStickyOpen(t *testing.T) {
+	clock := NewFakeClock(time.Unix(1700000000, 0))
+	closed := 0
+	m, err := ConfigureCircuit(CircuitOptions{
+		Name:              "TEST_StickyOpen",
+		Threshold:         50,
+		ThresholdType:     ThresholdPercentage,
+		MinimumCount:      4,
+		IntervalInSeconds: 60,
+		StickyOpen:        true,
+		Clock:             clock,
+		OnCircuitClosed: func(CallbackEvent) {
+			closed++
+		},
+	})
+	assert.NoError(t, err)
+	defer m.Close()
+
+	// Test case 1: Simulate a sustained outage across multiple intervals
+	// Expected output: The circuit stays open while the counts are cleared every interval
+	for i := 0; i < 4; i++ {
+		m.UpdateStatus(false)
+	}
+	assert.True(t, m.IsCircuitOpen())
+	for interval := 0; interval < 3; interval++ {
+		clock.Advance(60 * time.Second)
+		assert.True(t, m.IsCircuitOpen())
+		assert.Equal(t, int64(0), m.Data().TotalCount)
+		m.UpdateStatus(false)
+		m.UpdateStatus(false)
+		assert.True(t, m.IsCircuitOpen())
+	}
+	assert.Equal(t, 0, closed)
+	assert.Equal(t, int64(1), m.Data().TripCount)
+
+	// Test case 2: Record events showing recovery after an interval reset
+	// Expected output: The circuit closes once MinimumCount events stay below the threshold
+	clock.Advance(60 * time.Second)
+	m.UpdateStatus(true)
+	m.UpdateStatus(true)
+	m.UpdateStatus(false)
+	assert.True(t, m.IsCircuitOpen())
+	m.UpdateStatus(true)
+	assert.False(t, m.IsCircuitOpen())
+	assert.Equal(t, 1, closed)
+}
+
+func TestAlignToWallClock(t *testing.T) {
+	// 1700000000 is 20 seconds past a full minute
+	clock := NewFakeClock(time.Unix(1700000000, 0))
+	newCircuit := func(interval int) Circuit {
+		m, err := ConfigureCircuit(CircuitOptions{
+			Name:              "TEST_AlignToWallClock",
+			Threshold:         5,
+			ThresholdType:     ThresholdConsecutive,
+			IntervalInSeconds: interval,
+			AlignToWallClock:  true,
+			Clock:             clock,
+		})
+		assert.NoError(t, err)
+		return m
+	}
+	m := newCircuit(60)
+	defer m.Close()
+
+	// Test case 1: Record an event right after the circuit is configured
+	// Expected output: The first reset is due at the next full minute
+	m.UpdateStatus(false)
+	assert.Equal(t, 40*time.Second, m.TimeUntilReset())
+	clock.Advance(39 * time.Second)
+	assert.Equal(t, int64(1), m.Data().FailureCount)
+	clock.Advance(time.Second)
+	assert.Equal(t, int64(0), m.Data().FailureCount)
+	assert.Equal(t, int64(0), clock.Now()%60)
+	if history := m.History(); assert.Len(t, history, 1) {
+		assert.Equal(t, int64(1699999980), history[0].StartTime)
+	}
+
+	// Test case 2: Let the following intervals pass
+	// Expected output: Every reset lands on a minute boundary
+	m.UpdateStatus(false)
+	assert.Equal(t, 60*time.Second, m.TimeUntilReset())
+	clock.Advance(59 * time.Second)
+	assert.Equal(t, int64(1), m.Data().FailureCount)
+	clock.Advance(time.Second)
+	assert.Equal(t, int64(0), m.Data().FailureCount)
+
+	// Test case 3: Use a longer interval, starting the ticker after a while
+	// Expected output: The first reset still lands on the next full minute
+	clock.Advance(15 * time.Second)
+	long := newCircuit(120)
+	defer long.Close()
+	clock.Advance(30 * time.Second)
+	long.UpdateStatus(false)
+	assert.Equal(t, 15*time.Second, long.TimeUntilReset())
+	clock.Advance(15 * time.Second)
+	assert.Equal(t, int64(0), long.Data().FailureCount)
+	assert.Equal(t, int64(0), clock.Now()%60)
+	assert.Equal(t, 120*time.Second, long.TimeUntilReset())
+}
+
+func TestTimeUntilReset(t *testing.T) {
+	clock := NewFakeClock(time.Unix(1700000000, 0))
+	m, err := ConfigureCircuit(CircuitOptions{
+		Name:                  "TEST_TimeUntilReset",
+		Threshold:             2,
+		ThresholdType:         ThresholdConsecutive,
+		IntervalInSeconds:     60,
+		OpenDurationInSeconds: 90,
+		Clock:                 clock,
+	})
+	assert.NoError(t, err)
+	defer m.Close()
+
+	// Test case 1: Query a new circuit
+	// Expected output: The full interval remains and the closed circuit has no open duration
+	assert.Equal(t, 60*time.Second, m.TimeUntilReset())
+	assert.Equal(t, time.Duration(0), m.TimeUntilHalfOpen())
+
+	// Test case 2: Advance the clock within the interval and open the circuit
+	// Expected output: The remaining durations decrease with the clock
+	clock.Advance(20 * time.Second)
+	assert.Equal(t, 40*time.Second, m.TimeUntilReset())
+	m.UpdateStatus(false)
+	m.UpdateStatus(false)
+	assert.Equal(t, 90*time.Second, m.TimeUntilHalfOpen())
+	clock.Advance(30 * time.Second)
+	assert.Equal(t, 10*time.Second, m.TimeUntilReset())
+	assert.Equal(t, 60*time.Second, m.TimeUntilHalfOpen())
+
+	// Test case 3: Advance the clock past the interval reset
+	// Expected output: A new interval starts while the circuit stays open
+	clock.Advance(10 * time.Second)
+	assert.Equal(t, 60*time.Second, m.TimeUntilReset())
+	assert.Equal(t, 50*time.Second, m.TimeUntilHalfOpen())
+	assert.True(t, m.IsCircuitOpen())
+
+	// Test case 4: Advance the clock past the open duration
+	// Expected output: The circuit closed and no open duration remains
+	clock.Advance(50 * time.Second)
+	assert.Equal(t, time.Duration(0), m.TimeUntilHalfOpen())
+	assert.False(t, m.IsCircuitOpen())
+}
+
+// secondsClock is a Clock that only has second precision.
+type secondsClock struct {
+	clock *FakeClock
+}
+
+func (c secondsClock) Now() int64 {
+	return c.clock.Now()
+}
+
+func (c secondsClock) NewTicker(d time.Duration, fn func()) Ticker {
+	return c.clock.NewTicker(d, fn)
+}
+
+func TestOpenedAt(t *testing.T) {
+	newCircuit := func(clock Clock) Circuit {
+		m, err := ConfigureCircuit(CircuitOptions{
+			Name:              "TEST_OpenedAt",
+			Threshold:         2,
+			ThresholdType:     ThresholdConsecutive,
+			IntervalInSeconds: 60,
+			Clock:             clock,
+		})
+		assert.NoError(t, err)
+		return m
+	}
+	start := time.Unix(1700000000, 250000000)
+
+	// Test case 1: Query a closed circuit
+	// Expected output: The zero time
+	clock := NewFakeClock(start)
+	m := newCircuit(clock)
+	defer m.Close()
+	assert.True(t, m.OpenedAt().IsZero())
+	assert.True(t, m.Data().LastStateChangedTime.IsZero())
+
+	// Test case 2: Open the circuit with a precise clock
+	// Expected output: The times keep their sub-second precision, next to the Unix seconds
+	clock.Advance(1500 * time.Millisecond)
+	m.UpdateStatus(false)
+	m.UpdateStatus(false)
+	openedAt := start.Add(1500 * time.Millisecond)
+	assert.True(t, openedAt.Equal(m.OpenedAt()))
+	data := m.Data()
+	assert.True(t, openedAt.Equal(data.OpenedAt))
+	assert.True(t, openedAt.Equal(data.LastStateChangedTime))
+	assert.Equal(t, int64(1700000001), data.CircuitOpenedSince)
+	assert.Equal(t, time.Unix(data.CircuitOpenedSince, 0), data.OpenedAt.Truncate(time.Second))
+
+	// Test case 3: Close the circuit
+	// Expected output: OpenedAt is the zero time again and the state change time moves on
+	clock.Advance(60 * time.Second)
+	assert.True(t, m.OpenedAt().IsZero())
+	assert.True(t, start.Add(60*time.Second).Equal(m.Data().LastStateChangedTime))
+
+	// Test case 4: Open a circuit with a clock that only has second precision
+	// Expected output: The times are truncated to seconds
+	m = newCircuit(secondsClock{NewFakeClock(start)})
+	defer m.Close()
+	m.UpdateStatus(false)
+	m.UpdateStatus(false)
+	assert.True(t, time.Unix(1700000000, 0).Equal(m.OpenedAt()))
+}
+
+func TestSubSecondTimestamps(t *testing.T) {
+	start := time.Unix(1700000000, 0)
+	clock := NewFakeClock(start)
+	m, err := ConfigureCircuit(CircuitOptions{
+		Name:                  "TEST_SubSecondTimestamps",
+		Threshold:             2,
+		ThresholdType:         ThresholdConsecutive,
+		IntervalInSeconds:     60,
+		OpenDurationInSeconds: 30,
+		Clock:                 clock,
+	})
+	assert.NoError(t, err)
+	defer m.Close()
+
+	// Test case 1: Open the circuit half a second into a second
+	// Expected output: The open duration and the interval are measured from the precise times
+	clock.Advance(10500 * time.Millisecond)
+	m.UpdateStatus(false)
+	m.UpdateStatus(false)
+	assert.Equal(t, 30*time.Second, m.TimeUntilHalfOpen())
+	assert.Equal(t, 49500*time.Millisecond, m.TimeUntilReset())
+	assert.Equal(t, int64(1700000010), m.Data().CircuitOpenedSince)
+	clock.Advance(29800 * time.Millisecond)
+	assert.Equal(t, 200*time.Millisecond, m.TimeUntilHalfOpen())
+	assert.True(t, m.IsCircuitOpen())
+	clock.Advance(200 * time.Millisecond)
+	assert.False(t, m.IsCircuitOpen())
+	assert.True(t, start.Add(40500*time.Millisecond).Equal(m.Data().LastStateChangedTime))
+	assert.Equal(t, int64(1700000040), m.Data().LastStateChangedAt)
+
+	// Test case 2: Save and restore an open circuit
+	// Expected output: The state is saved in Unix seconds and restored from them
+	m.UpdateStatus(false)
+	m.UpdateStatus(false)
+	data, err := m.MarshalState()
+	assert.NoError(t, err)
+	assert.Contains(t, string(data), `"circuit_opened_since":1700000040,`)
+	assert.Contains(t, string(data), `"current_open_duration":30,`)
+	restored, err := ConfigureCircuit(m.GetOptions())
+	assert.NoError(t, err)
+	defer restored.Close()
+	assert.NoError(t, restored.RestoreState(data))
+	assert.True(t, restored.IsCircuitOpen())
+	assert.True(t, start.Add(40*time.Second).Equal(restored.OpenedAt()))
+	assert.Equal(t, 29500*time.Millisecond, restored.TimeUntilHalfOpen())
+}
+
+func TestInvertPolarity(t *testing.T) {
+	clock := NewFakeClock(time.Unix(1700000000, 0))
+	m, err := ConfigureCircuit(CircuitOptions{
+		Name:              "TEST_InvertPolarity",
+		Threshold:         5,
+		ThresholdType:     ThresholdCount,
+		MinimumCount:      10,
+		IntervalInSeconds: 60,
+		InvertPolarity:    true,
+		Clock:             clock,
+	})
+	assert.NoError(t, err)
+	defer m.Close()
+
+	// Test case 1: Record many unusual signals (failures) and a few usual ones (successes)
+	// Expected output: The circuit stays closed and the signals are counted as successes
+	for i := 0; i < 20; i++ {
+		m.UpdateStatus(false)
+	}
+	m.UpdateStatus(true)
+	data := m.Data()
+	assert.False(t, m.IsCircuitOpen())
+	assert.Equal(t, int64(20), data.SuccessCount)
+	assert.Equal(t, int64(1), data.FailureCount)
+
+	// Test case 2: Record a spike of successes
+	// Expected output: The circuit opens once the successes reach the threshold
+	for i := 0; i < 3; i++ {
+		m.UpdateStatus(true)
+	}
+	assert.False(t, m.IsCircuitOpen())
+	m.UpdateStatus(true)
+	assert.True(t, m.IsCircuitOpen())
+
+	// Test case 3: Record the spike as a batch after the interval reset
+	// Expected output: The batch successes are counted as failures and open the circuit
+	clock.Advance(60 * time.Second)
+	assert.False(t, m.IsCircuitOpen())
+	m.UpdateStatusBatch(5, 5)
+	assert.Equal(t, int64(5), m.Data().FailureCount)
+	assert.True(t, m.IsCircuitOpen())
+
+	// Test case 4: The same events without InvertPolarity
+	// Expected output: The default behavior is unchanged and the circuit stays closed
+	plain, err := ConfigureCircuit(CircuitOptions{
+		Name:              "TEST_InvertPolarity_Plain",
+		Threshold:         5,
+		ThresholdType:     ThresholdCount,
+		MinimumCount:      10,
+		IntervalInSeconds: 60,
+		Clock:             clock,
+	})
+	assert.NoError(t, err)
+	defer plain.Close()
+	for i := 0; i < 10; i++ {
+		plain.UpdateStatus(true)
+	}
+	assert.False(t, plain.IsCircuitOpen())
+	assert.Equal(t, int64(10), plain.Data().SuccessCount)
+}
@@ -0,0 +1,78 @@
+// Package tripperprom exposes the circuits of a tripper.Tripper as Prometheus
+// metrics. It lives in its own module so that the core tripper module does
+// not depend on the Prometheus client.
+package tripperprom
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/rajnandan1/go-tripper"
+)
+
+var (
+	openDesc = prometheus.NewDesc(
+		"tripper_circuit_open",
+		"Whether the circuit is open (1) or closed (0).",
+		[]string{"name"}, nil,
+	)
+	successDesc = prometheus.NewDesc(
+		"tripper_circuit_success_count",
+		"Number of successes recorded in the current interval.",
+		[]string{"name"}, nil,
+	)
+	failureDesc = prometheus.NewDesc(
+		"tripper_circuit_failure_count",
+		"Number of failures recorded in the current interval.",
+		[]string{"name"}, nil,
+	)
+	tripDesc = prometheus.NewDesc(
+		"tripper_circuit_trips_total",
+		"Number of times the circuit has opened.",
+		[]string{"name"}, nil,
+	)
+)
+
+// Collector is a prometheus.Collector that reports every circuit registered
+// in a Tripper each time it is scraped.
+type Collector struct {
+	tripper tripper.Tripper
+}
+
+// NewCollector creates a Collector for the circuits of t.
+func NewCollector(t tripper.Tripper) *Collector {
+	return &Collector{tripper: t}
+}
+
+// RegisterMetrics registers a Collector for the circuits of t with reg, e.g.
+// prometheus.DefaultRegisterer.
+func RegisterMetrics(reg prometheus.Registerer, t tripper.Tripper) error {
+	return reg.Register(NewCollector(t))
+}
+
+// Describe implements prometheus.Collector.
+func (c *Collector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- openDesc
+	ch <- successDesc
+	ch <- failureDesc
+	ch <- tripDesc
+}
+
+// Collect implements prometheus.Collector.
+func (c *Collector) Collect(ch chan<- prometheus.Metric) {
+	for _, name := range c.tripper.ListMonitors() {
+		circuit, err := c.tripper.GetMonitor(name)
+		if err != nil {
+			// removed between listing and lookup
+			continue
+		}
+		data := circuit.Data()
+		open := 0.0
+		if data.IsCircuitOpen {
+			open = 1
+		}
+		// the counts reset every interval, so they are reported as gauges
+		ch <- prometheus.MustNewConstMetric(openDesc, prometheus.GaugeValue, open, name)
+		ch <- prometheus.MustNewConstMetric(successDesc, prometheus.GaugeValue, float64(data.SuccessCount), name)
+		ch <- prometheus.MustNewConstMetric(failureDesc, prometheus.GaugeValue, float64(data.FailureCount), name)
+		ch <- prometheus.MustNewConstMetric(tripDesc, prometheus.CounterValue, float64(data.TripCount), name)
+	}
+}
@@ -0,0 +1,65 @@
+package tripperprom
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/rajnandan1/go-tripper"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCollector(t *testing.T) {
+	registry := tripper.Configure(tripper.TripperOptions{})
+	payments, err := registry.AddMonitor(tripper.CircuitOptions{
+		Name:              "payments",
+		Threshold:         2,
+		MinimumCount:      3,
+		IntervalInSeconds: 60,
+		ThresholdType:     tripper.ThresholdCount,
+	})
+	assert.NoError(t, err)
+	search, err := registry.AddMonitor(tripper.CircuitOptions{
+		Name:              "search",
+		Threshold:         2,
+		MinimumCount:      3,
+		IntervalInSeconds: 60,
+		ThresholdType:     tripper.ThresholdCount,
+	})
+	assert.NoError(t, err)
+	defer payments.Close()
+	defer search.Close()
+
+	payments.UpdateStatus(false)
+	payments.UpdateStatus(false)
+	payments.UpdateStatus(true)
+	search.UpdateStatus(true)
+
+	reg := prometheus.NewPedanticRegistry()
+	assert.NoError(t, RegisterMetrics(reg, registry))
+
+	expected := `
+# HELP tripper_circuit_failure_count Number of failures recorded in the current interval.
+# TYPE tripper_circuit_failure_count gauge
+tripper_circuit_failure_count{name="payments"} 2
+tripper_circuit_failure_count{name="search"} 0
+# HELP tripper_circuit_open Whether the circuit is open (1) or closed (0).
+# TYPE tripper_circuit_open gauge
+tripper_circuit_open{name="payments"} 1
+tripper_circuit_open{name="search"} 0
+# HELP tripper_circuit_success_count Number of successes recorded in the current interval.
+# TYPE tripper_circuit_success_count gauge
+tripper_circuit_success_count{name="payments"} 1
+tripper_circuit_success_count{name="search"} 1
+# HELP tripper_circuit_trips_total Number of times the circuit has opened.
+# TYPE tripper_circuit_trips_total counter
+tripper_circuit_trips_total{name="payments"} 1
+tripper_circuit_trips_total{name="search"} 0
+`
+	assert.NoError(t, testutil.GatherAndCompare(reg, strings.NewReader(expected)))
+
+	// Removed circuits are no longer reported
+	assert.NoError(t, registry.RemoveMonitor("search"))
+	assert.Equal(t, 4, testutil.CollectAndCount(NewCollector(registry)))
+}
@@ -0,0 +1,14 @@
+module github.com/rajnandan1/go-tripper/tripperprom
+
+go 1.15
+
+require (
+	github.com/prometheus/client_golang v1.11.1
+	github.com/rajnandan1/go-tripper v1.0.0
+	github.com/stretchr/testify v1.4.0
+)
+
+// Builds against the core module in this repository during development. The
+// replace only applies within this repository; modules that depend on
+// tripperprom get the tagged release required above.
+replace github.com/rajnandan1/go-tripper => ../
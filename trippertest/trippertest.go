@@ -0,0 +1,168 @@
+// Package trippertest provides helpers for tests of code that depends on the
+// state of a tripper.Circuit, so that the tests do not need to work out how
+// many events open or close a circuit with its options.
+package trippertest
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/rajnandan1/go-tripper"
+)
+
+// DriveToOpen records failures on c until it opens, at most as many as its
+// options require, and returns an error if it is still not open, e.g. during
+// its warmup period. A circuit that is already open is left as it is. With
+// InvertPolarity, successes are recorded instead.
+func DriveToOpen(c tripper.Circuit) error {
+	if c.State() == tripper.StateOpen {
+		return nil
+	}
+	options := c.GetOptions()
+	failures := failuresToOpen(options, c.Data())
+	for i := int64(0); i < failures; i++ {
+		// an inverted circuit records a success as a failure
+		c.UpdateStatus(options.InvertPolarity)
+		if c.State() == tripper.StateOpen {
+			return nil
+		}
+	}
+	return fmt.Errorf("trippertest: circuit %s did not open after %d failures", options.Name, failures)
+}
+
+// DriveToClosed closes c. A circuit that is held open for an open duration,
+// or that only closes with the interval reset, needs time to pass: when it
+// uses a *tripper.FakeClock, the clock is advanced as far as needed and an
+// error is returned otherwise. With ManualReset, ResetWindow is called instead
+// of waiting for the interval reset. Successes are then recorded until the circuit
+// closes, at most as many as its options require, or failures with
+// InvertPolarity. A circuit that is already closed is left as it is.
+func DriveToClosed(c tripper.Circuit) error {
+	if c.State() == tripper.StateClosed {
+		return nil
+	}
+	options := c.GetOptions()
+	if c.State() == tripper.StateOpen {
+		wait := c.TimeUntilHalfOpen()
+		if wait == 0 && !closesOnSuccess(options) {
+			if options.ManualReset {
+				c.ResetWindow()
+			}
+			wait = c.TimeUntilReset()
+		}
+		if wait > 0 {
+			clock, ok := options.Clock.(*tripper.FakeClock)
+			if !ok {
+				return fmt.Errorf("trippertest: circuit %s stays open for %s without a tripper.FakeClock", options.Name, wait)
+			}
+			clock.Advance(wait)
+		}
+	}
+	successes := successesToClose(options, c.Data())
+	for i := int64(0); i < successes && c.State() != tripper.StateClosed; i++ {
+		c.UpdateStatus(!options.InvertPolarity)
+	}
+	if c.State() != tripper.StateClosed {
+		return fmt.Errorf("trippertest: circuit %s did not close after %d successes", options.Name, successes)
+	}
+	return nil
+}
+
+// failuresToOpen returns how many failures trip the circuit given the counts
+// already recorded in data.
+func failuresToOpen(options tripper.CircuitOptions, data tripper.CircuitData) int64 {
+	var failures int64
+	for i, rule := range thresholdRules(options) {
+		needed := failuresToBreach(options, rule, data)
+		if i == 0 || (options.ThresholdsOperator == tripper.OperatorOr && needed < failures) || (options.ThresholdsOperator != tripper.OperatorOr && needed > failures) {
+			failures = needed
+		}
+	}
+	if failures < 1 {
+		failures = 1
+	}
+	return failures
+}
+
+// failuresToBreach returns how many failures reach the threshold of a single
+// rule given the counts already recorded in data.
+func failuresToBreach(options tripper.CircuitOptions, rule tripper.ThresholdRule, data tripper.CircuitData) int64 {
+	switch rule.ThresholdType {
+	case tripper.ThresholdConsecutive:
+		return ceil(float64(rule.Threshold))
+	case tripper.ThresholdFailureCount:
+		return ceil(float64(rule.Threshold)) - data.FailureCount
+	}
+	total := data.SuccessCount + data.FailureCount
+	needed := max(options.MinimumCount-total, ceil(options.MinRequestsPerSecond*float64(options.IntervalInSeconds))-total)
+	switch rule.ThresholdType {
+	case tripper.ThresholdCount:
+		needed = max(needed, ceil(float64(rule.Threshold))-data.FailureCount)
+	case tripper.ThresholdPercentage, tripper.ThresholdEWMA:
+		needed = max(needed, options.MinimumFailures-data.FailureCount)
+		if rule.Threshold < 100 {
+			// (failures + needed) / (total + needed) reaches the threshold
+			share := float64(rule.Threshold) / 100
+			needed = max(needed, ceil((share*float64(total)-float64(data.FailureCount))/(1-share)))
+		}
+	}
+	return needed
+}
+
+// successesToClose returns an upper bound of the successes after which the
+// circuit closes given the counts already recorded in data.
+func successesToClose(options tripper.CircuitOptions, data tripper.CircuitData) int64 {
+	successes := max(options.CloseConsecutiveCount, options.HalfOpenMaxProbes)
+	successes = max(successes, options.MinimumCount)
+	successes = max(successes, ceil(options.MinRequestsPerSecond*float64(options.IntervalInSeconds)))
+	for _, rule := range thresholdRules(options) {
+		threshold := rule.Threshold
+		if rule.CloseThreshold > 0 {
+			threshold = rule.CloseThreshold
+		}
+		if (rule.ThresholdType == tripper.ThresholdPercentage || rule.ThresholdType == tripper.ThresholdEWMA) && threshold > 0 {
+			// failures / (total + successes) drops below the close threshold
+			share := float64(threshold) / 100
+			total := float64(data.SuccessCount + data.FailureCount)
+			successes = max(successes, ceil(float64(data.FailureCount)/share-total)+1)
+		}
+	}
+	return max(successes, 1)
+}
+
+// closesOnSuccess reports whether successes can close an open circuit before
+// the interval reset. A COUNT or FAILURE_COUNT threshold stays breached until
+// the counts are cleared.
+func closesOnSuccess(options tripper.CircuitOptions) bool {
+	rules := thresholdRules(options)
+	for _, rule := range rules {
+		closes := rule.ThresholdType != tripper.ThresholdCount && rule.ThresholdType != tripper.ThresholdFailureCount
+		if options.ThresholdsOperator == tripper.OperatorOr && !closes {
+			return false
+		}
+		if options.ThresholdsOperator != tripper.OperatorOr && closes {
+			return true
+		}
+	}
+	return options.ThresholdsOperator == tripper.OperatorOr
+}
+
+// thresholdRules returns the rules of the circuit, which are Threshold and
+// ThresholdType unless Thresholds is set.
+func thresholdRules(options tripper.CircuitOptions) []tripper.ThresholdRule {
+	if len(options.Thresholds) > 0 {
+		return options.Thresholds
+	}
+	return []tripper.ThresholdRule{{ThresholdType: options.ThresholdType, Threshold: options.Threshold, CloseThreshold: options.CloseThreshold}}
+}
+
+func ceil(value float64) int64 {
+	return int64(math.Ceil(value))
+}
+
+func max(a, b int64) int64 {
+	if a > b {
+		return a
+	}
+	return b
+}
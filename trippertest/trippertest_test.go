@@ -0,0 +1,137 @@
+package trippertest
+
+import (
+	"testing"
+	"time"
+
+	"github.com/rajnandan1/go-tripper"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDriveToOpen(t *testing.T) {
+	clock := tripper.NewFakeClock(time.Unix(1700000000, 0))
+
+	// Test case 1: A COUNT circuit whose MinimumCount is above the threshold
+	// Expected output: The circuit opens after MinimumCount failures
+	count, err := tripper.ConfigureCircuit(tripper.CircuitOptions{
+		Name:              "count",
+		Threshold:         3,
+		ThresholdType:     tripper.ThresholdCount,
+		MinimumCount:      5,
+		IntervalInSeconds: 60,
+		Clock:             clock,
+	})
+	assert.NoError(t, err)
+	defer count.Close()
+	assert.NoError(t, DriveToOpen(count))
+	assert.True(t, count.IsCircuitOpen())
+	assert.Equal(t, int64(5), count.Data().FailureCount)
+
+	// Test case 2: A PERCENTAGE circuit that already recorded successes
+	// Expected output: The circuit opens once the failures reach the threshold
+	percentage, err := tripper.ConfigureCircuit(tripper.CircuitOptions{
+		Name:              "percentage",
+		Threshold:         50,
+		ThresholdType:     tripper.ThresholdPercentage,
+		MinimumCount:      2,
+		IntervalInSeconds: 60,
+		Clock:             clock,
+	})
+	assert.NoError(t, err)
+	defer percentage.Close()
+	for i := 0; i < 4; i++ {
+		percentage.UpdateStatus(true)
+	}
+	assert.NoError(t, DriveToOpen(percentage))
+	assert.True(t, percentage.IsCircuitOpen())
+	assert.Equal(t, int64(4), percentage.Data().FailureCount)
+
+	// Test case 3: A circuit that is still warming up
+	// Expected output: An error is returned and the circuit stays closed
+	warmup, err := tripper.ConfigureCircuit(tripper.CircuitOptions{
+		Name:              "warmup",
+		Threshold:         2,
+		ThresholdType:     tripper.ThresholdConsecutive,
+		IntervalInSeconds: 60,
+		WarmupSeconds:     30,
+		Clock:             clock,
+	})
+	assert.NoError(t, err)
+	defer warmup.Close()
+	assert.EqualError(t, DriveToOpen(warmup), "trippertest: circuit warmup did not open after 2 failures")
+	assert.False(t, warmup.IsCircuitOpen())
+}
+
+func TestDriveToClosed(t *testing.T) {
+	clock := tripper.NewFakeClock(time.Unix(1700000000, 0))
+
+	// Test case 1: A consecutive circuit that needs several successes to close
+	// Expected output: The circuit closes after CloseConsecutiveCount successes
+	consecutive, err := tripper.ConfigureCircuit(tripper.CircuitOptions{
+		Name:                  "consecutive",
+		Threshold:             3,
+		ThresholdType:         tripper.ThresholdConsecutive,
+		CloseConsecutiveCount: 2,
+		IntervalInSeconds:     60,
+		Clock:                 clock,
+	})
+	assert.NoError(t, err)
+	defer consecutive.Close()
+	assert.NoError(t, DriveToOpen(consecutive))
+	assert.NoError(t, DriveToClosed(consecutive))
+	assert.False(t, consecutive.IsCircuitOpen())
+	assert.Equal(t, int64(2), consecutive.Data().SuccessCount)
+
+	// Test case 2: A COUNT circuit that only closes with the interval reset
+	// Expected output: The fake clock is advanced to the reset and the circuit closes
+	count, err := tripper.ConfigureCircuit(tripper.CircuitOptions{
+		Name:              "count",
+		Threshold:         1,
+		ThresholdType:     tripper.ThresholdCount,
+		MinimumCount:      2,
+		IntervalInSeconds: 60,
+		Clock:             clock,
+	})
+	assert.NoError(t, err)
+	defer count.Close()
+	assert.NoError(t, DriveToOpen(count))
+	clock.Advance(20 * time.Second)
+	start := clock.Now()
+	assert.NoError(t, DriveToClosed(count))
+	assert.Equal(t, tripper.StateClosed, count.State())
+	assert.Equal(t, int64(40), clock.Now()-start)
+
+	// Test case 3: A circuit with half-open probes
+	// Expected output: The open duration elapses and a successful probe closes the circuit
+	probes, err := tripper.ConfigureCircuit(tripper.CircuitOptions{
+		Name:                  "probes",
+		Threshold:             50,
+		ThresholdType:         tripper.ThresholdPercentage,
+		MinimumCount:          2,
+		IntervalInSeconds:     60,
+		OpenDurationInSeconds: 10,
+		HalfOpenMaxProbes:     1,
+		Clock:                 clock,
+	})
+	assert.NoError(t, err)
+	defer probes.Close()
+	assert.NoError(t, DriveToOpen(probes))
+	assert.NoError(t, DriveToClosed(probes))
+	assert.Equal(t, tripper.StateClosed, probes.State())
+	assert.Equal(t, int64(1), probes.Data().SuccessCount)
+
+	// Test case 4: A circuit held open for an open duration with the real clock
+	// Expected output: An error is returned and the circuit stays open
+	held, err := tripper.ConfigureCircuit(tripper.CircuitOptions{
+		Name:                  "held",
+		Threshold:             1,
+		ThresholdType:         tripper.ThresholdConsecutive,
+		IntervalInSeconds:     60,
+		OpenDurationInSeconds: 30,
+	})
+	assert.NoError(t, err)
+	defer held.Close()
+	assert.NoError(t, DriveToOpen(held))
+	assert.Error(t, DriveToClosed(held))
+	assert.True(t, held.IsCircuitOpen())
+}
@@ -0,0 +1,65 @@
+package tripper
+
+import (
+	"math"
+	"sync/atomic"
+)
+
+// weightScale is the number of units a weight of 1 is recorded as. Weights
+// are summed as integers so that they can be updated atomically and add up
+// exactly, e.g. 0.3 + 0.3 + 0.4 is exactly 1. The sums are int64, so they
+// saturate at a weight of about 9.2e12 within an interval instead of
+// overflowing: further weight is not added until the counts are cleared,
+// while the event counts themselves are bounded by math.MaxInt64.
+const weightScale = 1000000
+
+// UpdateStatusWeighted updates the status of the Circuit like UpdateStatus,
+// but adds weight instead of 1 to the sums the COUNT and PERCENTAGE thresholds
+// are compared against, so that severe failures can count more than mild
+// ones. The number of events used for MinimumCount and ThresholdConsecutive
+// still increases by 1. Negative weights are treated as 0.
+func (m *CircuitImplementation) UpdateStatusWeighted(success bool, weight float64) {
+	m.updateStatus(success, 0, weightUnits(weight), "")
+}
+
+// weightUnits converts a weight to 1/weightScale units, saturating at
+// math.MaxInt64.
+func weightUnits(weight float64) int64 {
+	if weight <= 0 || math.IsNaN(weight) {
+		return 0
+	}
+	units := math.Round(weight * weightScale)
+	if units >= math.MaxInt64 {
+		return math.MaxInt64
+	}
+	return int64(units)
+}
+
+// countUnits converts a number of events of weight 1 to 1/weightScale units,
+// saturating at math.MaxInt64.
+func countUnits(count int64) int64 {
+	if count > math.MaxInt64/weightScale {
+		return math.MaxInt64
+	}
+	return count * weightScale
+}
+
+// addWeight atomically adds units to a weighted sum, saturating at
+// math.MaxInt64 so that the sum never wraps negative.
+func addWeight(sum *int64, units int64) {
+	for {
+		old := atomic.LoadInt64(sum)
+		next := old + units
+		if next < old {
+			next = math.MaxInt64
+		}
+		if atomic.CompareAndSwapInt64(sum, old, next) {
+			return
+		}
+	}
+}
+
+// weightedCount converts a sum of 1/weightScale units back to a weight.
+func weightedCount(units int64) float64 {
+	return float64(units) / weightScale
+}
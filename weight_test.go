@@ -0,0 +1,174 @@
+package tripper
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUpdateStatusWeighted(t *testing.T) {
+	newCircuit := func(thresholdType string, threshold float32) Circuit {
+		m, err := ConfigureCircuit(CircuitOptions{
+			Name:              "TEST_UpdateStatusWeighted",
+			Threshold:         threshold,
+			ThresholdType:     thresholdType,
+			MinimumCount:      4,
+			IntervalInSeconds: 60,
+			Clock:             NewFakeClock(time.Unix(1700000000, 0)),
+		})
+		assert.NoError(t, err)
+		return m
+	}
+
+	// Test case 1: Mixed weights below a COUNT threshold of 3
+	// Expected output: The circuit stays closed although 4 failures were recorded
+	m := newCircuit(ThresholdCount, 3)
+	m.UpdateStatusWeighted(false, 1)
+	m.UpdateStatusWeighted(false, 0.3)
+	m.UpdateStatusWeighted(false, 0.3)
+	m.UpdateStatusWeighted(false, 0.3)
+	assert.False(t, m.IsCircuitOpen())
+	data := m.Data()
+	assert.Equal(t, int64(4), data.FailureCount)
+	assert.Equal(t, 1.9, data.WeightedFailureCount)
+
+	// Test case 2: Reach the COUNT threshold with the weighted total
+	// Expected output: The circuit opens exactly when the weights add up to 3
+	m.UpdateStatusWeighted(false, 0.7)
+	assert.False(t, m.IsCircuitOpen())
+	m.UpdateStatusWeighted(false, 0.4)
+	assert.True(t, m.IsCircuitOpen())
+	assert.Equal(t, 3.0, m.Data().WeightedFailureCount)
+	m.Close()
+
+	// Test case 3: Plain updates count with a weight of 1
+	// Expected output: The circuit opens after 3 failures
+	m = newCircuit(ThresholdCount, 3)
+	m.UpdateStatus(true)
+	m.UpdateStatus(false)
+	m.UpdateStatus(false)
+	assert.False(t, m.IsCircuitOpen())
+	m.UpdateStatus(false)
+	assert.True(t, m.IsCircuitOpen())
+	assert.Equal(t, 1.0, m.Data().WeightedSuccessCount)
+	m.Close()
+
+	// Test case 4: A PERCENTAGE threshold compares the weighted sums
+	// Expected output: 3 light failures against 1 success stay below 50%, a severe failure trips it
+	m = newCircuit(ThresholdPercentage, 50)
+	m.UpdateStatus(true)
+	m.UpdateStatusWeighted(false, 0.2)
+	m.UpdateStatusWeighted(false, 0.2)
+	m.UpdateStatusWeighted(false, 0.2)
+	assert.False(t, m.IsCircuitOpen())
+	m.UpdateStatusWeighted(false, 1)
+	assert.True(t, m.IsCircuitOpen())
+	m.Close()
+
+	// Test case 5: Negative weights
+	// Expected output: Counted as events but not added to the weighted sums
+	m = newCircuit(ThresholdCount, 3)
+	m.UpdateStatusWeighted(false, -5)
+	data = m.Data()
+	assert.Equal(t, int64(1), data.FailureCount)
+	assert.Equal(t, 0.0, data.WeightedFailureCount)
+	m.Close()
+}
+
+func TestUpdateStatusWeightedPersisted(t *testing.T) {
+	clock := NewFakeClock(time.Unix(1700000000, 0))
+	monitorOptions := CircuitOptions{
+		Name:              "TEST_UpdateStatusWeightedPersisted",
+		Threshold:         3,
+		ThresholdType:     ThresholdCount,
+		MinimumCount:      4,
+		IntervalInSeconds: 60,
+		Clock:             clock,
+	}
+	m, err := ConfigureCircuit(monitorOptions)
+	assert.NoError(t, err)
+	defer m.Close()
+	m.UpdateStatusWeighted(false, 0.5)
+	m.UpdateStatusWeighted(true, 0.25)
+
+	// Test case 1: Restore a state with weighted sums
+	// Expected output: The weighted sums are restored
+	data, err := m.MarshalState()
+	assert.NoError(t, err)
+	restored, err := ConfigureCircuit(monitorOptions)
+	assert.NoError(t, err)
+	defer restored.Close()
+	assert.NoError(t, restored.RestoreState(data))
+	assert.Equal(t, 0.5, restored.Data().WeightedFailureCount)
+	assert.Equal(t, 0.25, restored.Data().WeightedSuccessCount)
+
+	// Test case 2: Restore a state saved without weighted sums
+	// Expected output: Every event counts with a weight of 1
+	assert.NoError(t, restored.RestoreState([]byte(`{"success_count":1,"failure_count":2}`)))
+	assert.Equal(t, 2.0, restored.Data().WeightedFailureCount)
+	assert.Equal(t, 1.0, restored.Data().WeightedSuccessCount)
+}
+
+func TestPercentageWithLargeCounts(t *testing.T) {
+	m, err := ConfigureCircuit(CircuitOptions{
+		Name:              "TEST_PercentageWithLargeCounts",
+		Threshold:         50,
+		ThresholdType:     ThresholdPercentage,
+		MinimumCount:      10,
+		IntervalInSeconds: 60,
+		Clock:             NewFakeClock(time.Unix(1700000000, 0)),
+	})
+	assert.NoError(t, err)
+	defer m.Close()
+
+	// Seed weighted sums close to the int64 bound, so that their total overflows once more failures are added
+	circuit := m.(*CircuitImplementation)
+	circuit.SuccessCount = 5000000000000
+	circuit.FailureCount = 4000000000000
+	circuit.weightedSuccesses = circuit.SuccessCount * weightScale
+	circuit.weightedFailures = circuit.FailureCount * weightScale
+
+	// Test case 1: Record a failure with a failure rate of about 44%
+	// Expected output: The circuit stays closed
+	m.UpdateStatus(false)
+	assert.False(t, m.IsCircuitOpen())
+	assert.InDelta(t, 4.0/9.0, m.Data().FailureRate, 1e-9)
+
+	// Test case 2: Record enough failures to raise the failure rate to 50%
+	// Expected output: The circuit opens
+	m.UpdateStatusWeighted(false, 999999999999)
+	assert.True(t, m.IsCircuitOpen())
+	assert.InDelta(t, 50, m.Data().WeightedFailureCount/(m.Data().WeightedFailureCount+m.Data().WeightedSuccessCount)*100, 1e-6)
+}
+
+func TestWeightedSumsSaturate(t *testing.T) {
+	m, err := ConfigureCircuit(CircuitOptions{
+		Name:              "TEST_WeightedSumsSaturate",
+		Threshold:         50,
+		ThresholdType:     ThresholdPercentage,
+		MinimumCount:      4,
+		IntervalInSeconds: 60,
+		Clock:             NewFakeClock(time.Unix(1700000000, 0)),
+	})
+	assert.NoError(t, err)
+	defer m.Close()
+	circuit := m.(*CircuitImplementation)
+
+	// Test case 1: Record failures whose weights add up past the int64 bound
+	// Expected output: The weighted sum saturates instead of wrapping negative and the circuit opens
+	m.UpdateStatus(true)
+	m.UpdateStatusWeighted(false, 9e12)
+	m.UpdateStatusWeighted(false, 9e12)
+	m.UpdateStatusWeighted(false, 9e12)
+	assert.Equal(t, int64(math.MaxInt64), circuit.weightedFailures)
+	assert.True(t, m.Data().WeightedFailureCount > 0)
+	assert.True(t, m.IsCircuitOpen())
+
+	// Test case 2: Convert weights and counts that do not fit the sums
+	// Expected output: They saturate as well
+	assert.Equal(t, int64(math.MaxInt64), weightUnits(1e300))
+	assert.Equal(t, int64(math.MaxInt64), countUnits(math.MaxInt64/weightScale+1))
+	assert.Equal(t, int64(3*weightScale), countUnits(3))
+}